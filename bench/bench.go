@@ -0,0 +1,126 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Result 一个基准在多次运行中的中位数结果
+	Result struct {
+		Name        string
+		Runs        int
+		NsPerOp     float64
+		BytesPerOp  float64
+		AllocsPerOp float64
+	}
+
+	// Delta 新旧两次运行的对比，变化率为(新-旧)/旧
+	Delta struct {
+		Name        string
+		Old, New    Result
+		NsChange    float64
+		AllocChange float64
+		Regression  bool // 耗时或分配次数的增幅超过阈值
+	}
+)
+
+// procsSuffix 基准名末尾的GOMAXPROCS后缀，例如BenchmarkParse-8中的-8
+var procsSuffix = regexp.MustCompile(`-\d+$`)
+
+// Parse 解析go test -bench的输出，同名基准多次运行（-count）时各指标取中位数
+func Parse(r io.Reader) (map[string]Result, error) {
+	samples := make(map[string][][3]float64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+
+		name := procsSuffix.ReplaceAllString(fields[0], "")
+		var sample [3]float64
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: 无效的取值: %s", name, fields[i])
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				sample[0] = value
+			case "B/op":
+				sample[1] = value
+			case "allocs/op":
+				sample[2] = value
+			}
+		}
+		samples[name] = append(samples[name], sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]Result, len(samples))
+	for name, runs := range samples {
+		results[name] = Result{
+			Name:        name,
+			Runs:        len(runs),
+			NsPerOp:     median(runs, 0),
+			BytesPerOp:  median(runs, 1),
+			AllocsPerOp: median(runs, 2),
+		}
+	}
+	return results, nil
+}
+
+func median(runs [][3]float64, metric int) float64 {
+	values := make([]float64, len(runs))
+	for i, run := range runs {
+		values[i] = run[metric]
+	}
+	sort.Float64s(values)
+	if n := len(values); n%2 == 0 {
+		return (values[n/2-1] + values[n/2]) / 2
+	}
+	return values[len(values)/2]
+}
+
+// Compare 按名称对比两次运行中都存在的基准，threshold为允许的增幅（0.1表示10%），结果按名称排序
+func Compare(before, after map[string]Result, threshold float64) []Delta {
+	deltas := make([]Delta, 0, len(after))
+	for name, newResult := range after {
+		oldResult, ok := before[name]
+		if !ok {
+			continue
+		}
+		delta := Delta{
+			Name:        name,
+			Old:         oldResult,
+			New:         newResult,
+			NsChange:    change(oldResult.NsPerOp, newResult.NsPerOp),
+			AllocChange: change(oldResult.AllocsPerOp, newResult.AllocsPerOp),
+		}
+		delta.Regression = delta.NsChange > threshold || delta.AllocChange > threshold
+		deltas = append(deltas, delta)
+	}
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i].Name < deltas[j].Name })
+	return deltas
+}
+
+func change(before, after float64) float64 {
+	if before == 0 {
+		if after == 0 {
+			return 0
+		}
+		return 1
+	}
+	return (after - before) / before
+}
@@ -0,0 +1,56 @@
+package bench
+
+import (
+	"strings"
+	"testing"
+)
+
+const baseline = `goos: linux
+goarch: amd64
+pkg: AuctionMatch/order
+BenchmarkParse/many_instruments-8         	      96	  12000000 ns/op	       0 B/op	       0 allocs/op
+BenchmarkParse/many_instruments-8         	      90	  13000000 ns/op	       0 B/op	       0 allocs/op
+BenchmarkParse/many_instruments-8         	      98	  11000000 ns/op	       0 B/op	       0 allocs/op
+BenchmarkProcess/few_instruments/cpu=4-8  	      15	  70000000 ns/op	  26.30 MB/s	29927656 B/op	  200230 allocs/op
+BenchmarkRemoved-8                        	    1000	      1000 ns/op
+PASS
+ok  	AuctionMatch/order	2.340s
+`
+
+const current = `BenchmarkParse/many_instruments-4         	      96	  12500000 ns/op	       0 B/op	       0 allocs/op
+BenchmarkProcess/few_instruments/cpu=4-4  	      15	  71000000 ns/op	  26.00 MB/s	29927656 B/op	  250000 allocs/op
+BenchmarkAdded-4                          	    1000	      1000 ns/op
+`
+
+func TestParseAndCompare(t *testing.T) {
+	before, err := Parse(strings.NewReader(baseline))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	parse := before["BenchmarkParse/many_instruments"]
+	if parse.Runs != 3 || parse.NsPerOp != 12000000 {
+		t.Errorf("多次运行应取中位数: %+v", parse)
+	}
+	if process := before["BenchmarkProcess/few_instruments/cpu=4"]; process.AllocsPerOp != 200230 || process.BytesPerOp != 29927656 {
+		t.Errorf("解析结果 = %+v", process)
+	}
+
+	after, err := Parse(strings.NewReader(current))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	deltas := Compare(before, after, 0.1)
+	if len(deltas) != 2 {
+		t.Fatalf("只对比两边都存在的基准: %+v", deltas)
+	}
+	if deltas[0].Name != "BenchmarkParse/many_instruments" || deltas[0].Regression {
+		t.Errorf("耗时增加4.2%%不应视为退化: %+v", deltas[0])
+	}
+	if deltas[1].Name != "BenchmarkProcess/few_instruments/cpu=4" || !deltas[1].Regression {
+		t.Errorf("分配次数增加24.9%%应视为退化: %+v", deltas[1])
+	}
+
+	if _, err := Parse(strings.NewReader("BenchmarkBad-8 10 abc ns/op\n")); err == nil {
+		t.Error("无效的取值应返回错误")
+	}
+}
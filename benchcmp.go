@@ -0,0 +1,58 @@
+package main
+
+import (
+	"AuctionMatch/bench"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runBenchCompare 对比两次go test -bench的输出，耗时或分配次数的增幅超过阈值时以状态码1退出
+func runBenchCompare(argv []string) {
+	flags := flag.NewFlagSet("benchcmp", flag.ExitOnError)
+	threshold := flags.Float64("threshold", 10, "允许的增幅（百分比）")
+	flags.Parse(argv)
+	if flags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "参数错误: 需要新旧两个基准结果文件！使用 -h 查看帮助信息")
+		os.Exit(2)
+	}
+
+	before := readBenchResults(flags.Arg(0))
+	after := readBenchResults(flags.Arg(1))
+	deltas := bench.Compare(before, after, *threshold/100)
+
+	var output strings.Builder
+	output.WriteString("name,old_ns/op,new_ns/op,delta,old_allocs/op,new_allocs/op,delta,regression\n")
+	regressions := 0
+	for _, d := range deltas {
+		mark := ""
+		if d.Regression {
+			mark = "REGRESSION"
+			regressions++
+		}
+		output.WriteString(fmt.Sprintf("%s,%.0f,%.0f,%+.1f%%,%.0f,%.0f,%+.1f%%,%s\n", d.Name,
+			d.Old.NsPerOp, d.New.NsPerOp, d.NsChange*100,
+			d.Old.AllocsPerOp, d.New.AllocsPerOp, d.AllocChange*100, mark))
+	}
+	fmt.Print(output.String())
+
+	if regressions > 0 {
+		fmt.Fprintf(os.Stderr, "%d个基准退化超过%.1f%%\n", regressions, *threshold)
+		os.Exit(1)
+	}
+}
+
+func readBenchResults(path string) map[string]bench.Result {
+	file, err := os.Open(path)
+	if err != nil {
+		fatal("无法打开基准结果", err)
+	}
+	defer file.Close()
+
+	results, err := bench.Parse(file)
+	if err != nil {
+		fatal("解析基准结果失败", err, "path", path)
+	}
+	return results
+}
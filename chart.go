@@ -0,0 +1,44 @@
+package main
+
+import (
+	"AuctionMatch/chart"
+	"AuctionMatch/order"
+	"os"
+	"path/filepath"
+)
+
+// runChart 为每个合约输出一张集合竞价曲线SVG图，-o 指定输出目录
+func runChart(argv []string) {
+	args := mustParseArgs(argv, argSpec{options: []string{"-o", "-scale"}})
+	stream := order.StreamOrders(args.inputFile)
+	waitErrors := logErrors(stream)
+
+	ladders := order.CollectLadders(stream, order.WithScaleMode(args.scaleMode))
+	waitErrors()
+
+	outputDir := args.outputFile
+	if outputDir == "" {
+		outputDir = "."
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fatal("创建输出目录时发生错误", err)
+	}
+
+	for _, ladder := range ladders {
+		if err := writeChart(filepath.Join(outputDir, chart.FileName(ladder.InstrumentID)), ladder); err != nil {
+			fatal("写入图表时发生错误", err)
+		}
+	}
+}
+
+func writeChart(path string, ladder order.AuctionLadder) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := chart.RenderLadder(file, ladder); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
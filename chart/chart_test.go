@@ -0,0 +1,73 @@
+package chart
+
+import (
+	"AuctionMatch/order"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRenderLadder(t *testing.T) {
+	tests := []struct {
+		name   string
+		orders []order.Order
+		want   []string
+	}{
+		{
+			name: "有成交",
+			orders: []order.Order{
+				{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3},
+				{InstrumentID: "IF2412", Direction: 1, Price: 3973.0, Volume: 2},
+			},
+			want: []string{"集合竞价价格 3973.4", "成交量 2", "买方剩余 1", "<polyline"},
+		},
+		{
+			name: "无成交",
+			orders: []order.Order{
+				{InstrumentID: "IF2412", Direction: 0, Price: 3973.0, Volume: 3},
+				{InstrumentID: "IF2412", Direction: 1, Price: 3973.4, Volume: 2},
+			},
+			want: []string{"无成交"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := RenderLadder(&buf, order.CalculateAuctionLadder(tt.orders)); err != nil {
+				t.Fatalf("RenderLadder() error = %v", err)
+			}
+			svg := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(svg, want) {
+					t.Errorf("SVG中缺少 %q", want)
+				}
+			}
+
+			// 校验输出为合法XML
+			decoder := xml.NewDecoder(&buf)
+			for {
+				if _, err := decoder.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("SVG不是合法XML: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestFileName(t *testing.T) {
+	if got := FileName("IF2412"); got != "IF2412.svg" {
+		t.Errorf("FileName(IF2412) = %v", got)
+	}
+	got := FileName("SP m2409&m2501")
+	if !strings.HasPrefix(got, "SP_m2409_m2501-") || !strings.HasSuffix(got, ".svg") {
+		t.Errorf("FileName() = %v", got)
+	}
+	if FileName("a&b") == FileName("a b") || FileName("a&b") == FileName("a_b") {
+		t.Errorf("替换字符后的文件名不应冲突: %v, %v, %v", FileName("a&b"), FileName("a b"), FileName("a_b"))
+	}
+}
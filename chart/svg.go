@@ -0,0 +1,186 @@
+package chart
+
+import (
+	"AuctionMatch/order"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"strings"
+)
+
+// 图表尺寸与配色
+const (
+	WIDTH         = 800
+	HEIGHT        = 480
+	MARGIN_LEFT   = 70
+	MARGIN_RIGHT  = 30
+	MARGIN_TOP    = 60
+	MARGIN_BOTTOM = 50
+	Y_TICKS       = 5 // 纵轴刻度数
+	X_LABELS      = 6 // 横轴最多标注的价格数
+
+	BUY_COLOR    = "#d62728"
+	SELL_COLOR   = "#2ca02c"
+	MARKER_COLOR = "#1f77b4"
+)
+
+// plot 绘图区域及坐标换算
+type plot struct {
+	ladder    *order.AuctionLadder
+	maxVolume int32
+}
+
+// x 第i个价格（按价格从低到高）的横坐标
+func (p *plot) x(i int) float64 {
+	n := len(p.ladder.Levels)
+	width := float64(WIDTH - MARGIN_LEFT - MARGIN_RIGHT)
+	if n <= 1 {
+		return MARGIN_LEFT + width/2
+	}
+	return MARGIN_LEFT + width*float64(i)/float64(n-1)
+}
+
+// y 成交量对应的纵坐标
+func (p *plot) y(volume int32) float64 {
+	height := float64(HEIGHT - MARGIN_TOP - MARGIN_BOTTOM)
+	return float64(HEIGHT-MARGIN_BOTTOM) - height*float64(volume)/float64(p.maxVolume)
+}
+
+// level 按价格从低到高取第i个档位，分价表本身从高到低排列
+func (p *plot) level(i int) order.LadderLevel {
+	return p.ladder.Levels[len(p.ladder.Levels)-1-i]
+}
+
+// RenderLadder 将合约分价表渲染为独立的SVG图，包含累计买入、累计卖出曲线，
+// 并标注集合竞价价格、成交量和剩余量
+func RenderLadder(w io.Writer, ladder order.AuctionLadder) error {
+	var svg strings.Builder
+	p := &plot{ladder: &ladder, maxVolume: 1}
+	for _, level := range ladder.Levels {
+		p.maxVolume = max(p.maxVolume, level.AccumBuy, level.AccumSell)
+	}
+
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		WIDTH, HEIGHT, WIDTH, HEIGHT)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="white"/>`+"\n", WIDTH, HEIGHT)
+	fmt.Fprintf(&svg, `<text x="%d" y="24" font-size="16" font-weight="bold">%s</text>`+"\n",
+		MARGIN_LEFT, html.EscapeString(ladder.InstrumentID))
+	fmt.Fprintf(&svg, `<text x="%d" y="44">%s</text>`+"\n", MARGIN_LEFT, html.EscapeString(summary(&ladder)))
+
+	if len(ladder.Levels) > 0 {
+		p.writeAxes(&svg)
+		p.writeCurve(&svg, BUY_COLOR, func(level order.LadderLevel) int32 { return level.AccumBuy })
+		p.writeCurve(&svg, SELL_COLOR, func(level order.LadderLevel) int32 { return level.AccumSell })
+		p.writeMarker(&svg)
+		p.writeLegend(&svg)
+	}
+
+	svg.WriteString("</svg>\n")
+	_, err := io.WriteString(w, svg.String())
+	return err
+}
+
+// summary 图表副标题
+func summary(ladder *order.AuctionLadder) string {
+	if ladder.Chosen < 0 {
+		return "无成交"
+	}
+	level := ladder.Levels[ladder.Chosen]
+	imbalance := "买卖平衡"
+	if level.AccumBuy > level.AccumSell {
+		imbalance = fmt.Sprintf("买方剩余 %d", level.RemainVolume)
+	} else if level.AccumBuy < level.AccumSell {
+		imbalance = fmt.Sprintf("卖方剩余 %d", level.RemainVolume)
+	}
+	return fmt.Sprintf("集合竞价价格 %.*f  成交量 %d  %s", ladder.Scale, level.Price, level.MatchVolume, imbalance)
+}
+
+func (p *plot) writeAxes(svg *strings.Builder) {
+	bottom := HEIGHT - MARGIN_BOTTOM
+	right := WIDTH - MARGIN_RIGHT
+	fmt.Fprintf(svg, `<g stroke="#333" stroke-width="1"><line x1="%d" y1="%d" x2="%d" y2="%d"/><line x1="%d" y1="%d" x2="%d" y2="%d"/></g>`+"\n",
+		MARGIN_LEFT, bottom, right, bottom, MARGIN_LEFT, MARGIN_TOP, MARGIN_LEFT, bottom)
+
+	// 纵轴刻度，成交量较小时跳过重复刻度
+	var lastVolume int32 = -1
+	for i := 0; i <= Y_TICKS; i++ {
+		volume := int32(int64(p.maxVolume) * int64(i) / Y_TICKS)
+		if volume == lastVolume {
+			continue
+		}
+		lastVolume = volume
+		y := p.y(volume)
+		fmt.Fprintf(svg, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`+"\n", MARGIN_LEFT, y, right, y)
+		fmt.Fprintf(svg, `<text x="%d" y="%.1f" text-anchor="end">%d</text>`+"\n", MARGIN_LEFT-6, y+4, volume)
+	}
+
+	// 横轴价格标注
+	n := len(p.ladder.Levels)
+	step := max((n+X_LABELS-1)/X_LABELS, 1)
+	for i := 0; i < n; i += step {
+		fmt.Fprintf(svg, `<text x="%.1f" y="%d" text-anchor="middle">%.*f</text>`+"\n",
+			p.x(i), bottom+18, p.ladder.Scale, p.level(i).Price)
+	}
+	fmt.Fprintf(svg, `<text x="%d" y="%d" text-anchor="middle">价格</text>`+"\n", (MARGIN_LEFT+right)/2, HEIGHT-8)
+	fmt.Fprintf(svg, `<text x="16" y="%d" text-anchor="middle" transform="rotate(-90 16 %d)">累计量</text>`+"\n",
+		(MARGIN_TOP+bottom)/2, (MARGIN_TOP+bottom)/2)
+}
+
+// writeCurve 以阶梯线绘制累计量曲线
+func (p *plot) writeCurve(svg *strings.Builder, color string, volumeOf func(order.LadderLevel) int32) {
+	fmt.Fprintf(svg, `<polyline fill="none" stroke="%s" stroke-width="2" points="`, color)
+	for i := range p.ladder.Levels {
+		y := p.y(volumeOf(p.level(i)))
+		if i > 0 {
+			fmt.Fprintf(svg, "%.1f,%.1f ", p.x(i), p.y(volumeOf(p.level(i-1))))
+		}
+		fmt.Fprintf(svg, "%.1f,%.1f ", p.x(i), y)
+	}
+	svg.WriteString(`"/>` + "\n")
+}
+
+// writeMarker 标注集合竞价价格和成交量
+func (p *plot) writeMarker(svg *strings.Builder) {
+	if p.ladder.Chosen < 0 {
+		return
+	}
+	i := len(p.ladder.Levels) - 1 - p.ladder.Chosen
+	level := p.level(i)
+	x := p.x(i)
+	y := p.y(level.MatchVolume)
+	fmt.Fprintf(svg, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="%s" stroke-dasharray="4 3"/>`+"\n",
+		x, MARGIN_TOP, x, HEIGHT-MARGIN_BOTTOM, MARKER_COLOR)
+	fmt.Fprintf(svg, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="%s" stroke-dasharray="4 3"/>`+"\n",
+		MARGIN_LEFT, y, WIDTH-MARGIN_RIGHT, y, MARKER_COLOR)
+	fmt.Fprintf(svg, `<circle cx="%.1f" cy="%.1f" r="4" fill="%s"/>`+"\n", x, y, MARKER_COLOR)
+	fmt.Fprintf(svg, `<text x="%.1f" y="%.1f" fill="%s">%.*f / %d</text>`+"\n",
+		x+6, y-6, MARKER_COLOR, p.ladder.Scale, level.Price, level.MatchVolume)
+}
+
+func (p *plot) writeLegend(svg *strings.Builder) {
+	x := WIDTH - MARGIN_RIGHT - 110
+	fmt.Fprintf(svg, `<line x1="%d" y1="20" x2="%d" y2="20" stroke="%s" stroke-width="2"/><text x="%d" y="24">累计买入</text>`+"\n",
+		x, x+20, BUY_COLOR, x+26)
+	fmt.Fprintf(svg, `<line x1="%d" y1="38" x2="%d" y2="38" stroke="%s" stroke-width="2"/><text x="%d" y="42">累计卖出</text>`+"\n",
+		x, x+20, SELL_COLOR, x+26)
+}
+
+// FileName 合约对应的SVG文件名，合约代码中的非常规字符替换为下划线，
+// 有替换时追加合约代码的8位FNV-1a哈希，避免"a&b"和"a b"这样的合约写入同一文件
+func FileName(instrumentID string) string {
+	name := []byte(instrumentID)
+	replaced := false
+	for i, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			name[i] = '_'
+			replaced = true
+		}
+	}
+	if replaced {
+		hash := fnv.New32a()
+		hash.Write([]byte(instrumentID))
+		return fmt.Sprintf("%s-%08x.svg", name, hash.Sum32())
+	}
+	return string(name) + ".svg"
+}
@@ -17,3 +17,50 @@ var CFE_PRODUCT_TICK = map[string]float32{
 	"T":  0.005, // 10年期国债期货
 	"TL": 0.01,  // 30年期国债期货
 }
+
+// 大商所部分品种tick，用于跨期、跨品种组合合约
+var DCE_PRODUCT_TICK = map[string]float32{
+	"m":  1,   // 豆粕
+	"y":  2,   // 豆油
+	"a":  1,   // 豆一
+	"c":  1,   // 玉米
+	"p":  2,   // 棕榈油
+	"i":  0.5, // 铁矿石
+	"j":  0.5, // 焦炭
+	"jm": 0.5, // 焦煤
+}
+
+// 郑商所部分品种tick，用于跨期、跨品种组合合约
+var CZCE_PRODUCT_TICK = map[string]float32{
+	"SR": 1, // 白糖
+	"CF": 5, // 棉花
+	"TA": 2, // PTA
+	"MA": 1, // 甲醇
+	"RM": 1, // 菜粕
+}
+
+// 商品期权tick，品种代码为"<标的品种代码>_o"
+var OPTION_PRODUCT_TICK = map[string]float32{
+	"m_o":  0.5, // 豆粕期权
+	"c_o":  0.5, // 玉米期权
+	"i_o":  0.1, // 铁矿石期权
+	"SR_o": 0.5, // 白糖期权
+	"CF_o": 1,   // 棉花期权
+	"TA_o": 0.5, // PTA期权
+	"MA_o": 0.5, // 甲醇期权
+	"RM_o": 0.5, // 菜粕期权
+}
+
+// 中金所合约限价指令每次最大下单数量（手）
+var CFE_MAX_ORDER_VOLUME = map[string]int32{
+	"IF": 20, "IC": 20, "IM": 20, "IH": 20,
+	"IO": 20, "MO": 20, "HO": 20,
+	"TS": 50, "TF": 50, "T": 50, "TL": 50,
+}
+
+// 中金所合约乘数（每点价值，元）；国债期货按面值除以100计
+var CFE_CONTRACT_MULTIPLIER = map[string]int32{
+	"IF": 300, "IH": 300, "IC": 200, "IM": 200,
+	"IO": 100, "MO": 100, "HO": 100,
+	"TS": 20000, "TF": 10000, "T": 10000, "TL": 10000,
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"AuctionMatch/order"
+	"fmt"
+	"strings"
+)
+
+const curveHeader = "instrumentID,price,buyVolume,sellVolume,accumBuy,accumSell,matchVolume,remainVolume,chosen\n"
+
+// runCurve 输出各合约的完整分价表
+func runCurve(argv []string) {
+	args := mustParseArgs(argv, argSpec{options: []string{"-o", "-scale"}})
+	stream := order.StreamOrders(args.inputFile)
+	waitErrors := logErrors(stream)
+
+	ladders := order.CollectLadders(stream, order.WithScaleMode(args.scaleMode))
+	waitErrors()
+
+	writeOutput(formatLadders(ladders), args.outputFile)
+}
+
+// formatLadders 将分价表格式化为CSV，每个价格档位一行
+func formatLadders(ladders []order.AuctionLadder) string {
+	var output strings.Builder
+	output.WriteString(curveHeader)
+
+	for _, ladder := range ladders {
+		for i, level := range ladder.Levels {
+			chosen := 0
+			if i == ladder.Chosen {
+				chosen = 1
+			}
+			output.WriteString(fmt.Sprintf("%s,%.*f,%d,%d,%d,%d,%d,%d,%d\n",
+				ladder.InstrumentID, ladder.Scale, level.Price,
+				level.BuyVolume, level.SellVolume, level.AccumBuy, level.AccumSell,
+				level.MatchVolume, level.RemainVolume, chosen))
+		}
+	}
+	return output.String()
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"AuctionMatch/fix"
+	"AuctionMatch/order"
+	"bufio"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+)
+
+// runFIX 读取FIX日志中的报单和撤单，撮合后按合约首次出现顺序输出结果
+func runFIX(argv []string) {
+	args := mustParseArgs(argv, argSpec{options: []string{"-o"}})
+	file, err := os.Open(args.inputFile)
+	if err != nil {
+		fatal("无法打开文件", err)
+	}
+	defer file.Close()
+
+	uncrossed, errs := fix.ReplayLog(file)
+	for _, err := range errs {
+		logError(err)
+	}
+
+	results := make([]order.ProcessResult, len(uncrossed))
+	for i, result := range uncrossed {
+		results[i] = result.ProcessResult
+	}
+	writeResults(results, args.outputFile)
+}
+
+// runFIXGateway 启动FIX报单网关，标准输入中的UNCROSS命令触发撮合
+func runFIXGateway(argv []string) {
+	flags := flag.NewFlagSet("fixgw", flag.ExitOnError)
+	addr := flags.String("addr", ":9878", "监听地址")
+	compID := flags.String("comp-id", "AUCTION", "网关的SenderCompID")
+	stpMode := flags.String("stp", "none", STP_USAGE)
+	applyLogFlags := addLogFlags(flags)
+	flags.Parse(argv)
+	applyLogFlags()
+	stp := mustParseSelfTradePrevention(*stpMode)
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fatal("无法监听", err, "addr", *addr)
+	}
+
+	gateway := fix.NewGateway(*compID, order.WithSelfTradePrevention(stp))
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if strings.EqualFold(strings.TrimSpace(scanner.Text()), "UNCROSS") {
+				for _, result := range gateway.Uncross() {
+					fmt.Printf("%s,%s\n", result.InstrumentID, result.FormatPrice())
+					if result.PreventedVolume > 0 {
+						slog.Info("已防范自成交", "instrument", result.InstrumentID, "volume", result.PreventedVolume)
+					}
+				}
+			}
+		}
+	}()
+
+	slog.Info("FIX网关监听，输入UNCROSS触发撮合", "addr", listener.Addr().String())
+	if err := gateway.Serve(listener); err != nil {
+		fatal("FIX网关退出", err)
+	}
+}
@@ -0,0 +1,185 @@
+package fix
+
+import (
+	"AuctionMatch/order"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeParse(t *testing.T) {
+	o := order.Order{InstrumentID: "IF2412", Direction: 1, Price: 3973.4, Volume: 3, Account: "A001"}
+	encoded := NewOrderSingle("c1", o, 1).Set(TagSenderCompID, "TRADER").Encode()
+
+	msg, err := Parse(encoded)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	clOrdID, got, err := ParseNewOrderSingle(msg)
+	if err != nil || clOrdID != "c1" || got != o {
+		t.Errorf("ParseNewOrderSingle() = %v, %+v, %v", clOrdID, got, err)
+	}
+
+	// 日志中常见的'|'分隔形式
+	if _, err := Parse([]byte(strings.ReplaceAll(string(encoded), "\x01", "|"))); err != nil {
+		t.Errorf("解析'|'分隔的消息 error = %v", err)
+	}
+
+	corrupted := []byte(strings.Replace(string(encoded), "3973.4", "3973.6", 1))
+	if _, err := Parse(corrupted); err == nil || !strings.Contains(err.Error(), "CheckSum") {
+		t.Errorf("篡改后的消息 error = %v", err)
+	}
+}
+
+func TestExecReportRoundTrip(t *testing.T) {
+	want := ExecReport{
+		OrderID: "c1", ClOrdID: "c1", ExecID: "7", ExecType: ExecTrade, OrdStatus: StatusPartiallyFilled,
+		Order: order.Order{InstrumentID: "TS2412", Direction: 0, Price: 101.234, Volume: 5},
+		Scale: 3, LastQty: 2, LastPx: 101.232, CumQty: 2, LeavesQty: 3,
+	}
+	msg, err := Parse(want.Message().Encode())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	got, err := ParseExecReport(msg)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseExecReport() = %+v, %v, want %+v", got, err, want)
+	}
+}
+
+func TestReplayLog(t *testing.T) {
+	buy := NewOrderSingle("b1", order.Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3}, 1).
+		Set(TagSenderCompID, "A")
+	sell := NewOrderSingle("s1", order.Order{InstrumentID: "IF2412", Direction: 1, Price: 3973.0, Volume: 2}, 1).
+		Set(TagSenderCompID, "B")
+	extra := NewOrderSingle("b2", order.Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.6, Volume: 5}, 1).
+		Set(TagSenderCompID, "A")
+	cancel := OrderCancelRequest("x1", "b2", order.Order{InstrumentID: "IF2412"}).Set(TagSenderCompID, "A")
+
+	log := strings.Join([]string{
+		"20241201-08:55:00.000 : " + buy.String(),
+		sell.String(),
+		extra.String(),
+		cancel.String(),
+		"8=FIX.4.4|9=5|35=D|10=000|",
+	}, "\n")
+
+	results, errs := ReplayLog(strings.NewReader(log))
+	if len(errs) != 1 {
+		t.Errorf("errs = %v, 期望1个错误", errs)
+	}
+	if len(results) != 1 || results[0].FormatPrice() != "3973.4" || results[0].MatchVolume != 2 {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+// logon 建立测试用的发起方会话
+func logon(t *testing.T, addr, compID string) *Session {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	s := NewSession(conn, compID)
+	s.TargetCompID = "AUCTION"
+	s.HeartBtInt = time.Second
+	if err := s.Logon(); err != nil {
+		t.Fatalf("Logon() error = %v", err)
+	}
+	return s
+}
+
+func receiveReport(t *testing.T, s *Session) ExecReport {
+	t.Helper()
+	s.conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	msg, err := s.Receive()
+	if err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	report, err := ParseExecReport(msg)
+	if err != nil {
+		t.Fatalf("ParseExecReport() error = %v, msg = %s", err, msg)
+	}
+	return report
+}
+
+func TestGateway(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	gateway := NewGateway("AUCTION")
+	go gateway.Serve(listener)
+	defer gateway.Close()
+
+	buyer := logon(t, listener.Addr().String(), "BUYER")
+	seller := logon(t, listener.Addr().String(), "SELLER")
+
+	buyer.Send(NewOrderSingle("b1", order.Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3}, 1))
+	if r := receiveReport(t, buyer); r.ExecType != ExecNew || r.ClOrdID != "b1" || r.LeavesQty != 3 {
+		t.Errorf("新单回报 = %+v", r)
+	}
+	buyer.Send(NewOrderSingle("b1", order.Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3}, 1))
+	if r := receiveReport(t, buyer); r.ExecType != ExecRejected || r.OrdStatus != ExecRejected || r.LeavesQty != 0 {
+		t.Errorf("重复新单的拒绝回报 = %+v, want LeavesQty=0", r)
+	}
+	seller.Send(NewOrderSingle("s1", order.Order{InstrumentID: "IF2412", Direction: 1, Price: 3973.0, Volume: 2}, 1))
+	receiveReport(t, seller)
+	seller.Send(NewOrderSingle("s2", order.Order{InstrumentID: "IF2412", Direction: 1, Price: 3975.0, Volume: 1}, 1))
+	receiveReport(t, seller)
+	seller.Send(NewOrderSingle("s3", order.Order{InstrumentID: "IF2412", Direction: 1, Price: 3980.0, Volume: 4}, 1))
+	receiveReport(t, seller)
+	seller.Send(OrderCancelRequest("x1", "s2", order.Order{InstrumentID: "IF2412", Direction: 1}))
+	if r := receiveReport(t, seller); r.ExecType != ExecCanceled {
+		t.Errorf("撤单回报 = %+v", r)
+	}
+	seller.Send(OrderCancelRequest("x2", "s2", order.Order{InstrumentID: "IF2412", Direction: 1}))
+	if r := receiveReport(t, seller); r.ExecType != ExecRejected {
+		t.Errorf("重复撤单回报 = %+v", r)
+	}
+
+	// 等待心跳间隔，会话应保持连接
+	time.Sleep(1500 * time.Millisecond)
+
+	results := gateway.Uncross()
+	if len(results) != 1 || results[0].FormatPrice() != "3973.4" {
+		t.Fatalf("Uncross() = %+v", results)
+	}
+	if r := receiveReport(t, buyer); r.ExecType != ExecTrade || r.LastQty != 2 || r.LeavesQty != 1 || r.OrdStatus != StatusPartiallyFilled {
+		t.Errorf("买方成交回报 = %+v", r)
+	}
+	if r := receiveReport(t, seller); r.ExecType != ExecTrade || r.LastQty != 2 || r.OrdStatus != ExecFilled {
+		t.Errorf("卖方成交回报 = %+v", r)
+	}
+	// 未成交的剩余数量失效
+	if r := receiveReport(t, buyer); r.ExecType != ExecExpired || r.ClOrdID != "b1" || r.CumQty != 2 || r.LeavesQty != 0 {
+		t.Errorf("买方剩余数量的失效回报 = %+v", r)
+	}
+	if r := receiveReport(t, seller); r.ExecType != ExecExpired || r.ClOrdID != "s3" || r.CumQty != 0 || r.LeavesQty != 0 {
+		t.Errorf("卖方未成交订单的失效回报 = %+v", r)
+	}
+}
+
+func TestSessionSeqTooLow(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	gateway := NewGateway("AUCTION")
+	go gateway.Serve(listener)
+	defer gateway.Close()
+
+	s := logon(t, listener.Addr().String(), "TRADER")
+	// 回退序号后发送，网关应登出
+	s.mu.Lock()
+	s.outSeq = 0
+	s.mu.Unlock()
+	s.Send(NewMessage(MsgHeartbeat))
+
+	s.conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	if _, err := s.Receive(); err != ErrLoggedOut {
+		t.Errorf("序号过小后 Receive() error = %v, want ErrLoggedOut", err)
+	}
+}
@@ -0,0 +1,238 @@
+package fix
+
+import (
+	"AuctionMatch/order"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Gateway FIX报单网关，所有会话共享同一个集合竞价时段。
+// 订单在时段内以"对端CompID:ClOrdID"为编号，撮合后按订单归属推送成交回报
+type Gateway struct {
+	SenderCompID string
+
+	session  *order.CallSession
+	sessions map[string]*Session // 按对端CompID索引
+	execID   int
+	listener net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+}
+
+// NewGateway 创建网关，opts为集合竞价时段的配置；NewOrderSingle中的Account(1)用于自成交防范
+func NewGateway(senderCompID string, opts ...order.SessionOption) *Gateway {
+	return &Gateway{
+		SenderCompID: senderCompID,
+		session:      order.NewCallSession(opts...),
+		sessions:     make(map[string]*Session),
+	}
+}
+
+// Serve 在listener上接受FIX会话，直至Close
+func (g *Gateway) Serve(listener net.Listener) error {
+	g.mu.Lock()
+	g.listener = listener
+	g.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		g.wg.Add(1)
+		go g.serveSession(NewSession(conn, g.SenderCompID))
+	}
+}
+
+// Close 停止接受连接并断开所有会话
+func (g *Gateway) Close() error {
+	g.mu.Lock()
+	var err error
+	if g.listener != nil {
+		err = g.listener.Close()
+	}
+	for _, s := range g.sessions {
+		s.Logout()
+	}
+	g.mu.Unlock()
+
+	g.wg.Wait()
+	return err
+}
+
+func (g *Gateway) serveSession(s *Session) {
+	defer g.wg.Done()
+	if err := s.Accept(); err != nil {
+		return
+	}
+
+	g.mu.Lock()
+	if old, ok := g.sessions[s.TargetCompID]; ok {
+		old.Logout()
+	}
+	g.sessions[s.TargetCompID] = s
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		if g.sessions[s.TargetCompID] == s {
+			delete(g.sessions, s.TargetCompID)
+		}
+		g.mu.Unlock()
+	}()
+
+	for {
+		msg, err := s.Receive()
+		if err != nil {
+			return
+		}
+		g.mu.Lock()
+		report := g.handleLocked(s.TargetCompID, msg)
+		g.mu.Unlock()
+		if report != nil {
+			s.Send(report.Message())
+		}
+	}
+}
+
+// handleLocked 处理应用层消息并返回应答的执行报告，调用方需持有g.mu
+func (g *Gateway) handleLocked(owner string, msg *Message) *ExecReport {
+	switch msg.MsgType() {
+	case MsgNewOrderSingle:
+		clOrdID, o, err := ParseNewOrderSingle(msg)
+		if err == nil {
+			err = g.session.Submit(owner+":"+clOrdID, owner, o)
+		}
+		report := g.reportLocked(clOrdID, o, ExecNew, ExecNew, err)
+		if err == nil {
+			report.LeavesQty = o.Volume // 拒绝回报（OrdStatus=8）的LeavesQty为0
+		}
+		return report
+
+	case MsgOrderCancelRequest:
+		clOrdID, origClOrdID, err := ParseOrderCancelRequest(msg)
+		var live order.LiveOrder
+		if err == nil {
+			live, _ = g.session.Lookup(owner + ":" + origClOrdID)
+			err = g.session.Cancel(owner + ":" + origClOrdID)
+		}
+		o := live.Order
+		return g.reportLocked(clOrdID, o, ExecCanceled, ExecCanceled, err)
+
+	default:
+		return &ExecReport{
+			ExecID:    g.nextExecIDLocked(),
+			ExecType:  ExecRejected,
+			OrdStatus: ExecRejected,
+			Text:      "不支持的消息类型: " + msg.MsgType(),
+		}
+	}
+}
+
+func (g *Gateway) reportLocked(clOrdID string, o order.Order, execType, status string, err error) *ExecReport {
+	report := &ExecReport{
+		OrderID:   clOrdID,
+		ClOrdID:   clOrdID,
+		ExecID:    g.nextExecIDLocked(),
+		ExecType:  execType,
+		OrdStatus: status,
+		Order:     o,
+		Scale:     o.GetScale(),
+	}
+	if err != nil {
+		report.ExecType = ExecRejected
+		report.OrdStatus = ExecRejected
+		report.Text = err.Error()
+	}
+	return report
+}
+
+func (g *Gateway) nextExecIDLocked() string {
+	g.execID++
+	return strconv.Itoa(g.execID)
+}
+
+// pendingReport 待推送的执行报告
+type pendingReport struct {
+	session *Session
+	report  ExecReport
+}
+
+// Uncross 撮合所有合约，向各订单归属的会话推送成交回报，未成交的剩余数量推送失效回报（ExecType=C），
+// 并开放新的集合竞价时段。回报在释放g.mu之后发送，单个会话发送缓慢不会阻塞其他会话和报单
+func (g *Gateway) Uncross() []order.UncrossResult {
+	g.mu.Lock()
+	results, reports := g.uncrossLocked()
+	g.mu.Unlock()
+
+	for _, pending := range reports {
+		pending.session.Send(pending.report.Message())
+	}
+	return results
+}
+
+// uncrossLocked 撮合并生成各订单的回报，调用方需持有g.mu
+func (g *Gateway) uncrossLocked() ([]order.UncrossResult, []pendingReport) {
+	results := g.session.Uncross()
+	var reports []pendingReport
+	for _, result := range results {
+		filled := make(map[string]int32, len(result.Fills))
+		for _, fill := range result.Fills {
+			filled[fill.OrderID] += fill.Volume
+			s, ok := g.sessions[fill.Owner]
+			if !ok {
+				continue
+			}
+			// 撮合后存量订单保留到下一次Open
+			live, _ := g.session.Lookup(fill.OrderID)
+			o := live.Order
+			clOrdID := fill.OrderID[len(fill.Owner)+1:]
+			status := ExecFilled
+			if fill.Volume < o.Volume {
+				status = StatusPartiallyFilled
+			}
+			reports = append(reports, pendingReport{session: s, report: ExecReport{
+				OrderID:   clOrdID,
+				ClOrdID:   clOrdID,
+				ExecID:    g.nextExecIDLocked(),
+				ExecType:  ExecTrade,
+				OrdStatus: status,
+				Order:     o,
+				Scale:     result.Scale,
+				LastQty:   fill.Volume,
+				LastPx:    fill.Price,
+				CumQty:    fill.Volume,
+				LeavesQty: o.Volume - fill.Volume,
+			}})
+		}
+
+		// 未成交的剩余数量在开放新时段时失效，按时间优先顺序推送
+		orders := g.session.State(result.InstrumentID).Orders()
+		sort.Slice(orders, func(i, j int) bool { return orders[i].Seq < orders[j].Seq })
+		for _, live := range orders {
+			s, ok := g.sessions[live.Owner]
+			if !ok || filled[live.OrderID] >= live.Volume {
+				continue
+			}
+			clOrdID := live.OrderID[len(live.Owner)+1:]
+			reports = append(reports, pendingReport{session: s, report: ExecReport{
+				OrderID:   clOrdID,
+				ClOrdID:   clOrdID,
+				ExecID:    g.nextExecIDLocked(),
+				ExecType:  ExecExpired,
+				OrdStatus: ExecExpired,
+				Order:     live.Order,
+				Scale:     result.Scale,
+				CumQty:    filled[live.OrderID],
+			}})
+		}
+	}
+	g.session.Open()
+	return results, reports
+}
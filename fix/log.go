@@ -0,0 +1,60 @@
+package fix
+
+import (
+	"AuctionMatch/order"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// ReplayLog 读取FIX日志，将其中的NewOrderSingle和OrderCancelRequest依次送入集合竞价时段后撮合。
+// 每行一条消息，消息前可以带时间戳等前缀；其他类型的消息被忽略，无法处理的消息通过errs返回
+func ReplayLog(r io.Reader) (results []order.UncrossResult, errs []error) {
+	session := order.NewCallSession()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		start := bytes.Index(line, []byte("8=FIX"))
+		if start == -1 {
+			continue
+		}
+		msg, err := Parse(line[start:])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("第%d行: %v", lineNum, err))
+			continue
+		}
+		if err := applyMessage(session, msg); err != nil {
+			errs = append(errs, fmt.Errorf("第%d行: %v", lineNum, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return session.Uncross(), errs
+}
+
+// applyMessage 以SenderCompID区分报单方，将订单消息应用到时段
+func applyMessage(session *order.CallSession, msg *Message) error {
+	owner, _ := msg.Get(TagSenderCompID)
+	switch msg.MsgType() {
+	case MsgNewOrderSingle:
+		clOrdID, o, err := ParseNewOrderSingle(msg)
+		if err != nil {
+			return err
+		}
+		return session.Submit(owner+":"+clOrdID, owner, o)
+	case MsgOrderCancelRequest:
+		_, origClOrdID, err := ParseOrderCancelRequest(msg)
+		if err != nil {
+			return err
+		}
+		return session.Cancel(owner + ":" + origClOrdID)
+	}
+	return nil
+}
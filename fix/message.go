@@ -0,0 +1,225 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	SOH          = '\x01'
+	BEGIN_STRING = "FIX.4.4"
+)
+
+// 使用到的tag
+const (
+	TagAccount         = 1
+	TagAvgPx           = 6
+	TagBeginSeqNo      = 7
+	TagBeginString     = 8
+	TagBodyLength      = 9
+	TagCheckSum        = 10
+	TagClOrdID         = 11
+	TagCumQty          = 14
+	TagEndSeqNo        = 16
+	TagExecID          = 17
+	TagLastPx          = 31
+	TagLastQty         = 32
+	TagMsgSeqNum       = 34
+	TagMsgType         = 35
+	TagOrderID         = 37
+	TagOrderQty        = 38
+	TagOrdStatus       = 39
+	TagOrdType         = 40
+	TagOrigClOrdID     = 41
+	TagPossDupFlag     = 43
+	TagPrice           = 44
+	TagSenderCompID    = 49
+	TagSendingTime     = 52
+	TagSide            = 54
+	TagSymbol          = 55
+	TagTargetCompID    = 56
+	TagText            = 58
+	TagTransactTime    = 60
+	TagEncryptMethod   = 98
+	TagHeartBtInt      = 108
+	TagTestReqID       = 112
+	TagExecType        = 150
+	TagLeavesQty       = 151
+	TagResetSeqNumFlag = 141
+)
+
+// MsgType取值
+const (
+	MsgHeartbeat          = "0"
+	MsgTestRequest        = "1"
+	MsgResendRequest      = "2"
+	MsgLogout             = "5"
+	MsgExecutionReport    = "8"
+	MsgLogon              = "A"
+	MsgNewOrderSingle     = "D"
+	MsgOrderCancelRequest = "F"
+)
+
+type (
+	// Field tag=value字段
+	Field struct {
+		Tag   int
+		Value string
+	}
+
+	// Message FIX消息，Fields不含BeginString、BodyLength和CheckSum，按出现顺序保存
+	Message struct {
+		Fields []Field
+	}
+)
+
+func NewMessage(msgType string) *Message {
+	return &Message{Fields: []Field{{Tag: TagMsgType, Value: msgType}}}
+}
+
+// Get 返回tag的值
+func (m *Message) Get(tag int) (string, bool) {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set 设置tag的值，已存在时覆盖
+func (m *Message) Set(tag int, value string) *Message {
+	for i := range m.Fields {
+		if m.Fields[i].Tag == tag {
+			m.Fields[i].Value = value
+			return m
+		}
+	}
+	m.Fields = append(m.Fields, Field{Tag: tag, Value: value})
+	return m
+}
+
+// MsgType 返回消息类型
+func (m *Message) MsgType() string {
+	msgType, _ := m.Get(TagMsgType)
+	return msgType
+}
+
+// Encode 编码为完整的FIX消息，自动补齐BeginString、BodyLength和CheckSum
+func (m *Message) Encode() []byte {
+	var body bytes.Buffer
+	for _, f := range m.Fields {
+		body.WriteString(strconv.Itoa(f.Tag))
+		body.WriteByte('=')
+		body.WriteString(f.Value)
+		body.WriteByte(SOH)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "8=%s%c9=%d%c", BEGIN_STRING, SOH, body.Len(), SOH)
+	msg.Write(body.Bytes())
+	fmt.Fprintf(&msg, "10=%03d%c", checksum(msg.Bytes()), SOH)
+	return msg.Bytes()
+}
+
+// String 以'|'分隔的可读形式
+func (m *Message) String() string {
+	return string(bytes.ReplaceAll(m.Encode(), []byte{SOH}, []byte{'|'}))
+}
+
+func checksum(data []byte) int {
+	sum := 0
+	for _, b := range data {
+		sum += int(b)
+	}
+	return sum % 256
+}
+
+// Parse 解析一条完整的FIX消息并校验BodyLength和CheckSum，
+// 分隔符可以是SOH或日志中常见的'|'
+func Parse(data []byte) (*Message, error) {
+	data = bytes.TrimRight(data, "\r\n")
+	if bytes.IndexByte(data, SOH) == -1 {
+		data = bytes.ReplaceAll(data, []byte{'|'}, []byte{SOH})
+	}
+	if len(data) == 0 || data[len(data)-1] != SOH {
+		data = append(data, SOH)
+	}
+
+	fields, err := splitFields(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 4 || fields[0].Tag != TagBeginString || fields[1].Tag != TagBodyLength ||
+		fields[len(fields)-1].Tag != TagCheckSum {
+		return nil, fmt.Errorf("消息头尾不完整")
+	}
+	if fields[0].Value != BEGIN_STRING {
+		return nil, fmt.Errorf("不支持的BeginString: %s", fields[0].Value)
+	}
+
+	// 校验BodyLength：从9=后的第一个字段到10=之前
+	bodyStart := bytes.Index(data, []byte{SOH, '3', '5', '='}) + 1
+	bodyEnd := bytes.LastIndex(data, []byte{SOH, '1', '0', '='}) + 1
+	if bodyStart <= 0 || bodyEnd <= bodyStart {
+		return nil, fmt.Errorf("缺少MsgType")
+	}
+	if bodyLength, err := strconv.Atoi(fields[1].Value); err != nil || bodyLength != bodyEnd-bodyStart {
+		return nil, fmt.Errorf("BodyLength不匹配: %s, 实际%d", fields[1].Value, bodyEnd-bodyStart)
+	}
+	if sum, err := strconv.Atoi(fields[len(fields)-1].Value); err != nil || sum != checksum(data[:bodyEnd]) {
+		return nil, fmt.Errorf("CheckSum不匹配: %s, 实际%03d", fields[len(fields)-1].Value, checksum(data[:bodyEnd]))
+	}
+
+	return &Message{Fields: fields[2 : len(fields)-1]}, nil
+}
+
+func splitFields(data []byte) ([]Field, error) {
+	fields := make([]Field, 0, 16)
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, SOH)
+		eq := bytes.IndexByte(data[:end], '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("无效的字段: %q", data[:end])
+		}
+		tag, err := strconv.Atoi(string(data[:eq]))
+		if err != nil {
+			return nil, fmt.Errorf("无效的tag: %q", data[:eq])
+		}
+		fields = append(fields, Field{Tag: tag, Value: string(data[eq+1 : end])})
+		data = data[end+1:]
+	}
+	return fields, nil
+}
+
+// ReadMessage 从连接中读取一条以SOH分隔的FIX消息
+func ReadMessage(r *bufio.Reader) (*Message, error) {
+	var raw bytes.Buffer
+	// 8=FIX.4.4<SOH>9=N<SOH>
+	for i := 0; i < 2; i++ {
+		field, err := r.ReadBytes(SOH)
+		if err != nil {
+			return nil, err
+		}
+		raw.Write(field)
+	}
+	header, err := splitFields(raw.Bytes())
+	if err != nil || header[0].Tag != TagBeginString || header[1].Tag != TagBodyLength {
+		return nil, fmt.Errorf("无效的消息头: %q", raw.Bytes())
+	}
+	bodyLength, err := strconv.Atoi(header[1].Value)
+	if err != nil || bodyLength <= 0 || bodyLength > 1<<20 {
+		return nil, fmt.Errorf("无效的BodyLength: %s", header[1].Value)
+	}
+
+	// 消息体及"10=xxx<SOH>"
+	rest := make([]byte, bodyLength+7)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, err
+	}
+	raw.Write(rest)
+	return Parse(raw.Bytes())
+}
@@ -0,0 +1,198 @@
+package fix
+
+import (
+	"AuctionMatch/order"
+	"fmt"
+	"strconv"
+)
+
+// Side取值
+const (
+	SideBuy  = "1"
+	SideSell = "2"
+)
+
+// ExecType / OrdStatus取值
+const (
+	ExecNew      = "0"
+	ExecFilled   = "2"
+	ExecCanceled = "4"
+	ExecRejected = "8"
+	ExecExpired  = "C" // 集合竞价结束时未成交的剩余数量失效
+	ExecTrade    = "F"
+
+	StatusPartiallyFilled = "1"
+)
+
+// ExecReport 执行报告（35=8）的内容
+type ExecReport struct {
+	OrderID   string
+	ClOrdID   string
+	ExecID    string
+	ExecType  string
+	OrdStatus string
+	Order     order.Order
+	Scale     uint // 价格输出精度
+	LastQty   int32
+	LastPx    float32
+	CumQty    int32
+	LeavesQty int32
+	Text      string
+}
+
+// ParseNewOrderSingle 将NewOrderSingle（35=D）转为订单，返回ClOrdID
+func ParseNewOrderSingle(msg *Message) (string, order.Order, error) {
+	if msg.MsgType() != MsgNewOrderSingle {
+		return "", order.Order{}, fmt.Errorf("不是NewOrderSingle: 35=%s", msg.MsgType())
+	}
+	clOrdID, ok := msg.Get(TagClOrdID)
+	if !ok || clOrdID == "" {
+		return "", order.Order{}, fmt.Errorf("缺少ClOrdID(11)")
+	}
+	if ordType, ok := msg.Get(TagOrdType); ok && ordType != "2" {
+		return clOrdID, order.Order{}, fmt.Errorf("集合竞价仅支持限价单(40=2)，实际40=%s", ordType)
+	}
+
+	symbol, _ := msg.Get(TagSymbol)
+	side, _ := msg.Get(TagSide)
+	price, _ := msg.Get(TagPrice)
+	qty, _ := msg.Get(TagOrderQty)
+	account, _ := msg.Get(TagAccount)
+	o, err := order.ParseOrder([]string{symbol, sideToDirection(side), price, qty, "", account})
+	if err == nil && symbol == "" {
+		err = fmt.Errorf("缺少Symbol(55)")
+	}
+	return clOrdID, o, err
+}
+
+// ParseOrderCancelRequest 解析OrderCancelRequest（35=F），返回ClOrdID和被撤订单的OrigClOrdID
+func ParseOrderCancelRequest(msg *Message) (clOrdID, origClOrdID string, err error) {
+	if msg.MsgType() != MsgOrderCancelRequest {
+		return "", "", fmt.Errorf("不是OrderCancelRequest: 35=%s", msg.MsgType())
+	}
+	clOrdID, _ = msg.Get(TagClOrdID)
+	origClOrdID, ok := msg.Get(TagOrigClOrdID)
+	if !ok || origClOrdID == "" {
+		return clOrdID, "", fmt.Errorf("缺少OrigClOrdID(41)")
+	}
+	return clOrdID, origClOrdID, nil
+}
+
+// NewOrderSingle 将订单编码为NewOrderSingle（35=D）
+func NewOrderSingle(clOrdID string, o order.Order, scale uint) *Message {
+	msg := NewMessage(MsgNewOrderSingle).
+		Set(TagClOrdID, clOrdID).
+		Set(TagSymbol, o.InstrumentID).
+		Set(TagSide, directionToSide(o.Direction)).
+		Set(TagOrderQty, strconv.Itoa(int(o.Volume))).
+		Set(TagOrdType, "2").
+		Set(TagPrice, formatPrice(o.Price, scale))
+	if o.Account != "" {
+		msg.Set(TagAccount, o.Account)
+	}
+	return msg
+}
+
+// OrderCancelRequest 编码撤单请求（35=F）
+func OrderCancelRequest(clOrdID, origClOrdID string, o order.Order) *Message {
+	return NewMessage(MsgOrderCancelRequest).
+		Set(TagClOrdID, clOrdID).
+		Set(TagOrigClOrdID, origClOrdID).
+		Set(TagSymbol, o.InstrumentID).
+		Set(TagSide, directionToSide(o.Direction))
+}
+
+// Message 将执行报告编码为ExecutionReport（35=8）
+func (r ExecReport) Message() *Message {
+	msg := NewMessage(MsgExecutionReport).
+		Set(TagOrderID, r.OrderID).
+		Set(TagClOrdID, r.ClOrdID).
+		Set(TagExecID, r.ExecID).
+		Set(TagExecType, r.ExecType).
+		Set(TagOrdStatus, r.OrdStatus).
+		Set(TagSymbol, r.Order.InstrumentID).
+		Set(TagSide, directionToSide(r.Order.Direction)).
+		Set(TagOrderQty, strconv.Itoa(int(r.Order.Volume))).
+		Set(TagPrice, formatPrice(r.Order.Price, r.Scale)).
+		Set(TagLastQty, strconv.Itoa(int(r.LastQty))).
+		Set(TagLastPx, formatPrice(r.LastPx, r.Scale)).
+		Set(TagCumQty, strconv.Itoa(int(r.CumQty))).
+		Set(TagLeavesQty, strconv.Itoa(int(r.LeavesQty))).
+		Set(TagAvgPx, formatPrice(r.LastPx, r.Scale))
+	if r.Text != "" {
+		msg.Set(TagText, r.Text)
+	}
+	return msg
+}
+
+// ParseExecReport 解析ExecutionReport（35=8）
+func ParseExecReport(msg *Message) (ExecReport, error) {
+	if msg.MsgType() != MsgExecutionReport {
+		return ExecReport{}, fmt.Errorf("不是ExecutionReport: 35=%s", msg.MsgType())
+	}
+	var r ExecReport
+	r.OrderID, _ = msg.Get(TagOrderID)
+	r.ClOrdID, _ = msg.Get(TagClOrdID)
+	r.ExecID, _ = msg.Get(TagExecID)
+	r.ExecType, _ = msg.Get(TagExecType)
+	r.OrdStatus, _ = msg.Get(TagOrdStatus)
+	r.Text, _ = msg.Get(TagText)
+	r.Order.InstrumentID, _ = msg.Get(TagSymbol)
+	switch side, _ := msg.Get(TagSide); side {
+	case SideBuy:
+		r.Order.Direction = 0
+	case SideSell:
+		r.Order.Direction = 1
+	default:
+		return r, fmt.Errorf("无效的Side(54): %s", side)
+	}
+
+	ints := []struct {
+		tag int
+		dst *int32
+	}{{TagOrderQty, &r.Order.Volume}, {TagLastQty, &r.LastQty}, {TagCumQty, &r.CumQty}, {TagLeavesQty, &r.LeavesQty}}
+	for _, f := range ints {
+		value, _ := msg.Get(f.tag)
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return r, fmt.Errorf("无效的数量(%d): %s", f.tag, value)
+		}
+		*f.dst = int32(n)
+	}
+	floats := []struct {
+		tag int
+		dst *float32
+	}{{TagPrice, &r.Order.Price}, {TagLastPx, &r.LastPx}}
+	for _, f := range floats {
+		value, _ := msg.Get(f.tag)
+		n, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return r, fmt.Errorf("无效的价格(%d): %s", f.tag, value)
+		}
+		*f.dst = float32(n)
+		r.Scale = max(r.Scale, order.PriceScale(value))
+	}
+	return r, nil
+}
+
+// sideToDirection FIX的Side转为订单方向，无法识别时原样返回交给ParseOrder报错
+func sideToDirection(side string) string {
+	switch side {
+	case SideBuy:
+		return "0"
+	case SideSell:
+		return "1"
+	}
+	return "side=" + side
+}
+
+func directionToSide(direction int8) string {
+	if direction == 0 {
+		return SideBuy
+	}
+	return SideSell
+}
+
+func formatPrice(price float32, scale uint) string {
+	return strconv.FormatFloat(float64(price), 'f', int(scale), 32)
+}
@@ -0,0 +1,237 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SENDING_TIME_LAYOUT   = "20060102-15:04:05.000"
+	DEFAULT_HEARTBEAT_INT = 30 * time.Second
+)
+
+var ErrLoggedOut = errors.New("对端已登出")
+
+// Session FIX会话层，负责登录、心跳、测试请求和消息序号；Receive只返回应用层消息
+type Session struct {
+	SenderCompID string
+	TargetCompID string // 发起方在Logon前设置，接收方从对端Logon中获得
+	HeartBtInt   time.Duration
+
+	conn     net.Conn
+	reader   *bufio.Reader
+	outSeq   int // 已发送的最大序号
+	inSeq    int // 已接收的最大序号
+	lastSent time.Time
+	lastRecv time.Time
+	done     chan struct{}
+	closed   bool
+	mu       sync.Mutex
+}
+
+func NewSession(conn net.Conn, senderCompID string) *Session {
+	return &Session{
+		SenderCompID: senderCompID,
+		HeartBtInt:   DEFAULT_HEARTBEAT_INT,
+		conn:         conn,
+		reader:       bufio.NewReader(conn),
+		done:         make(chan struct{}),
+	}
+}
+
+// Send 补齐会话层头部并发送消息
+func (s *Session) Send(msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return net.ErrClosed
+	}
+
+	s.outSeq++
+	now := time.Now()
+	header := []Field{
+		{Tag: TagMsgType, Value: msg.MsgType()},
+		{Tag: TagSenderCompID, Value: s.SenderCompID},
+		{Tag: TagTargetCompID, Value: s.TargetCompID},
+		{Tag: TagMsgSeqNum, Value: strconv.Itoa(s.outSeq)},
+		{Tag: TagSendingTime, Value: now.UTC().Format(SENDING_TIME_LAYOUT)},
+	}
+	out := &Message{Fields: header}
+	for _, f := range msg.Fields {
+		switch f.Tag {
+		case TagMsgType, TagSenderCompID, TagTargetCompID, TagMsgSeqNum, TagSendingTime:
+		default:
+			out.Fields = append(out.Fields, f)
+		}
+	}
+
+	s.lastSent = now
+	_, err := s.conn.Write(out.Encode())
+	return err
+}
+
+// Logon 作为发起方登录，等待对端的Logon应答
+func (s *Session) Logon() error {
+	logon := NewMessage(MsgLogon).
+		Set(TagEncryptMethod, "0").
+		Set(TagHeartBtInt, strconv.Itoa(int(s.HeartBtInt/time.Second)))
+	if err := s.Send(logon); err != nil {
+		return err
+	}
+	reply, err := s.readChecked()
+	if err != nil {
+		return err
+	}
+	if reply.MsgType() != MsgLogon {
+		return fmt.Errorf("期望Logon应答，收到35=%s", reply.MsgType())
+	}
+	go s.heartbeatLoop()
+	return nil
+}
+
+// Accept 作为接收方等待对端Logon并应答
+func (s *Session) Accept() error {
+	logon, err := s.readChecked()
+	if err != nil {
+		return err
+	}
+	if logon.MsgType() != MsgLogon {
+		s.Close()
+		return fmt.Errorf("首条消息必须是Logon，收到35=%s", logon.MsgType())
+	}
+	s.TargetCompID, _ = logon.Get(TagSenderCompID)
+	if value, ok := logon.Get(TagHeartBtInt); ok {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+			s.HeartBtInt = time.Duration(seconds) * time.Second
+		}
+	}
+	reply := NewMessage(MsgLogon).
+		Set(TagEncryptMethod, "0").
+		Set(TagHeartBtInt, strconv.Itoa(int(s.HeartBtInt/time.Second)))
+	if err := s.Send(reply); err != nil {
+		return err
+	}
+	go s.heartbeatLoop()
+	return nil
+}
+
+// Receive 返回下一条应用层消息，会话层消息在内部处理
+func (s *Session) Receive() (*Message, error) {
+	for {
+		msg, err := s.readChecked()
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg.MsgType() {
+		case MsgHeartbeat, MsgResendRequest:
+			// 不保存已发送消息，ResendRequest仅记录不重发
+		case MsgTestRequest:
+			testReqID, _ := msg.Get(TagTestReqID)
+			s.Send(NewMessage(MsgHeartbeat).Set(TagTestReqID, testReqID))
+		case MsgLogout:
+			s.Send(NewMessage(MsgLogout))
+			s.Close()
+			return nil, ErrLoggedOut
+		default:
+			return msg, nil
+		}
+	}
+}
+
+// readChecked 读取消息并校验序号：序号过小且非重发时登出，出现缺口时发送ResendRequest后继续处理
+func (s *Session) readChecked() (*Message, error) {
+	msg, err := ReadMessage(s.reader)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	value, _ := msg.Get(TagMsgSeqNum)
+	seq, err := strconv.Atoi(value)
+	if err != nil {
+		s.logout("缺少MsgSeqNum")
+		return nil, fmt.Errorf("无效的MsgSeqNum: %q", value)
+	}
+
+	s.mu.Lock()
+	expected := s.inSeq + 1
+	s.lastRecv = time.Now()
+	if seq >= expected {
+		s.inSeq = seq
+	}
+	s.mu.Unlock()
+
+	if seq < expected {
+		if possDup, _ := msg.Get(TagPossDupFlag); possDup == "Y" {
+			return s.readChecked()
+		}
+		s.logout(fmt.Sprintf("MsgSeqNum过小，期望%d，收到%d", expected, seq))
+		return nil, fmt.Errorf("MsgSeqNum过小，期望%d，收到%d", expected, seq)
+	}
+	if seq > expected && msg.MsgType() != MsgLogon {
+		s.Send(NewMessage(MsgResendRequest).
+			Set(TagBeginSeqNo, strconv.Itoa(expected)).
+			Set(TagEndSeqNo, "0"))
+	}
+	return msg, nil
+}
+
+func (s *Session) logout(text string) {
+	s.Send(NewMessage(MsgLogout).Set(TagText, text))
+	s.Close()
+}
+
+// Logout 主动登出并关闭连接
+func (s *Session) Logout() {
+	s.logout("")
+}
+
+// heartbeatLoop 空闲超过心跳间隔时发送Heartbeat，对端静默超过1.5个间隔发送TestRequest，超过3个间隔断开
+func (s *Session) heartbeatLoop() {
+	ticker := time.NewTicker(min(s.HeartBtInt/4, time.Second))
+	defer ticker.Stop()
+	testRequested := false
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			sinceSent, sinceRecv := now.Sub(s.lastSent), now.Sub(s.lastRecv)
+			s.mu.Unlock()
+
+			switch {
+			case sinceRecv > 3*s.HeartBtInt:
+				s.Close()
+				return
+			case sinceRecv > s.HeartBtInt*3/2 && !testRequested:
+				s.Send(NewMessage(MsgTestRequest).Set(TagTestReqID, now.Format(SENDING_TIME_LAYOUT)))
+				testRequested = true
+			case sinceSent >= s.HeartBtInt:
+				s.Send(NewMessage(MsgHeartbeat))
+			}
+			if sinceRecv <= s.HeartBtInt {
+				testRequested = false
+			}
+		}
+	}
+}
+
+// Close 关闭连接，可重复调用
+func (s *Session) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.conn.Close()
+}
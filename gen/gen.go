@@ -0,0 +1,192 @@
+package gen
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/refdata"
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Config 订单生成参数
+	Config struct {
+		Seed            int64              // 随机种子，相同的种子和参数生成相同的订单
+		Orders          int                // 订单数（含畸形行）
+		Products        []string           // 品种代码，为空时使用全部有内置参考价的品种
+		Months          int                // 每个品种的合约数，从FIRST_MONTH起按季月排列
+		ReferencePrices map[string]float64 // 品种参考价，覆盖内置值
+		Skew            float64            // 合约热度的Zipf参数，须大于1，越大越集中在少数合约
+		Spread          float64            // 报价相对参考价的标准差（比例）
+		MalformedRate   float64            // 畸形行的比例
+		Registry        *refdata.Registry  // tick和最大下单数量的来源，为nil时使用refdata.Default
+	}
+
+	// contract 生成订单用的合约
+	contract struct {
+		id        string
+		ticks     instrument.TickSchedule
+		scale     int
+		refTicks  int64 // 参考价（档位序号）
+		sigma     float64
+		maxVolume int32
+	}
+)
+
+const (
+	FIRST_MONTH        = 2412 // 第一个合约月份（YYMM）
+	DEFAULT_SKEW       = 1.3
+	DEFAULT_SPREAD     = 0.002
+	DEFAULT_MAX_VOLUME = 100 // 参考数据中没有最大下单数量时使用
+)
+
+// REFERENCE_PRICES 内置的品种参考价，期权不在其中，需通过Config.ReferencePrices指定
+var REFERENCE_PRICES = map[string]float64{
+	"IF": 3900, "IC": 5800, "IM": 6200, "IH": 2700,
+	"TS": 102.5, "TF": 105.5, "T": 106.5, "TL": 118,
+}
+
+// malformedLines 畸形行的样式，覆盖字段数不符、方向、价格、数量和时间戳无效等情况
+var malformedLines = []func(id string) string{
+	func(id string) string { return id + ",0,3900.2" },
+	func(id string) string { return id + ",2,3900.2,1" },
+	func(id string) string { return id + ",0,abc,1" },
+	func(id string) string { return id + ",1,3900.2,x" },
+	func(id string) string { return id + ",1,3900.2,1,25:61:00" },
+	func(id string) string { return id + ",0,NaN,1" },
+	func(id string) string { return "," + id + ",,," },
+}
+
+// DefaultConfig 返回默认参数
+func DefaultConfig() Config {
+	return Config{Seed: 1, Orders: 100000, Months: 2, Skew: DEFAULT_SKEW, Spread: DEFAULT_SPREAD}
+}
+
+// Generate 按参数生成"instrumentID,direction,price,volume"格式的订单写入w。
+// 合约热度服从Zipf分布，价格围绕各合约参考价正态分布并对齐到tick，数量服从指数分布且不超过最大下单数量
+func Generate(w io.Writer, config Config) error {
+	if config.Orders < 0 || config.Months <= 0 {
+		return fmt.Errorf("订单数和合约数须为正数")
+	}
+	if config.Skew <= 1 {
+		return fmt.Errorf("热度参数须大于1: %v", config.Skew)
+	}
+	if config.MalformedRate < 0 || config.MalformedRate > 1 {
+		return fmt.Errorf("畸形行比例须在0到1之间: %v", config.MalformedRate)
+	}
+
+	rng := rand.New(rand.NewSource(config.Seed))
+	instruments, err := newInstruments(config, rng)
+	if err != nil {
+		return err
+	}
+	zipf := rand.NewZipf(rng, config.Skew, 1, uint64(len(instruments)-1))
+
+	out := bufio.NewWriter(w)
+	for i := 0; i < config.Orders; i++ {
+		inst := instruments[zipf.Uint64()]
+		if config.MalformedRate > 0 && rng.Float64() < config.MalformedRate {
+			out.WriteString(malformedLines[rng.Intn(len(malformedLines))](inst.id) + "\n")
+			continue
+		}
+
+		direction := rng.Intn(2)
+		// 买单略偏向参考价下方、卖单偏向上方，两边在参考价附近交叉
+		bias := 0.3 * inst.sigma
+		if direction == 0 {
+			bias = -bias
+		}
+		ticks := max(inst.refTicks+int64(math.Round(rng.NormFloat64()*inst.sigma+bias)), 1)
+		price := inst.ticks.ToFloat(ticks)
+
+		volume := min(1+int32(rng.ExpFloat64()*float64(inst.maxVolume)/5), inst.maxVolume)
+		fmt.Fprintf(out, "%s,%d,%.*f,%d\n", inst.id, direction, inst.scale, price, volume)
+	}
+	return out.Flush()
+}
+
+// newInstruments 按品种和月份构造合约并打乱顺序，使最热门的合约不总是第一个品种的近月
+func newInstruments(config Config, rng *rand.Rand) ([]contract, error) {
+	registry := config.Registry
+	if registry == nil {
+		registry = refdata.Default
+	}
+	codes := config.Products
+	if len(codes) == 0 {
+		for _, product := range registry.Products() {
+			if _, ok := REFERENCE_PRICES[product.Code]; ok {
+				codes = append(codes, product.Code)
+			}
+		}
+	}
+
+	instruments := make([]contract, 0, len(codes)*config.Months)
+	for _, code := range codes {
+		product, ok := registry.Product(code)
+		if !ok {
+			return nil, fmt.Errorf("参考数据中没有品种: %s", code)
+		}
+		refPrice, ok := config.ReferencePrices[code]
+		if !ok {
+			if refPrice, ok = REFERENCE_PRICES[code]; !ok {
+				return nil, fmt.Errorf("品种%s缺少参考价", code)
+			}
+		}
+		maxVolume := product.MaxOrderVolume
+		if maxVolume <= 0 {
+			maxVolume = DEFAULT_MAX_VOLUME
+		}
+
+		for m := 0; m < config.Months; m++ {
+			// 远月合约的参考价略有升贴水
+			price := refPrice * (1 + 0.003*float64(m)*(rng.Float64()-0.5))
+			ticks := instrument.NewTickSchedule(product.Tick, product.TickBands)
+			refTicks := ticks.ToInt(float32(price))
+			instruments = append(instruments, contract{
+				id:        code + contractMonth(m),
+				ticks:     ticks,
+				scale:     int(ticks.Scale()),
+				refTicks:  refTicks,
+				sigma:     math.Max(float64(refTicks)*config.Spread, 3),
+				maxVolume: maxVolume,
+			})
+		}
+	}
+	if len(instruments) == 0 {
+		return nil, fmt.Errorf("没有可生成的合约")
+	}
+
+	rng.Shuffle(len(instruments), func(i, j int) {
+		instruments[i], instruments[j] = instruments[j], instruments[i]
+	})
+	return instruments, nil
+}
+
+// contractMonth 从FIRST_MONTH起第n个季月，例如2412、2503、2506
+func contractMonth(n int) string {
+	year, month := FIRST_MONTH/100, FIRST_MONTH%100+3*n
+	year += (month - 1) / 12
+	month = (month-1)%12 + 1
+	return strconv.Itoa(year*100 + month)
+}
+
+// ParseReferencePrices 解析"IF=3900,T=106.5"格式的参考价
+func ParseReferencePrices(text string) (map[string]float64, error) {
+	prices := make(map[string]float64)
+	if text == "" {
+		return prices, nil
+	}
+	for _, item := range strings.Split(text, ",") {
+		code, value, ok := strings.Cut(item, "=")
+		price, err := strconv.ParseFloat(value, 64)
+		if !ok || err != nil || price <= 0 {
+			return nil, fmt.Errorf("无效的参考价: %s", item)
+		}
+		prices[strings.TrimSpace(code)] = price
+	}
+	return prices, nil
+}
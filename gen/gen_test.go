@@ -0,0 +1,99 @@
+package gen
+
+import (
+	"AuctionMatch/order"
+	"AuctionMatch/refdata"
+	"AuctionMatch/utils"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func generate(t *testing.T, config Config) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Generate(&buf, config); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestGenerate(t *testing.T) {
+	config := DefaultConfig()
+	config.Orders = 20000
+	output := generate(t, config)
+	if output != generate(t, config) {
+		t.Fatal("相同的种子应生成相同的订单")
+	}
+	config.Seed = 2
+	if output == generate(t, config) {
+		t.Fatal("不同的种子应生成不同的订单")
+	}
+
+	counts := make(map[string]int)
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if len(lines) != 20000 {
+		t.Fatalf("行数 = %d, want 20000", len(lines))
+	}
+	for _, line := range lines {
+		record := utils.CustomSplit(line)
+		o, err := order.ParseOrder(record)
+		if err != nil {
+			t.Fatalf("无效的订单 %q: %v", line, err)
+		}
+		counts[o.InstrumentID]++
+
+		tick := o.GetTick()
+		if order.ToFloat(order.ToInt(o.Price, tick), tick) != o.Price || order.PriceScale(record[2]) != o.GetScale() {
+			t.Errorf("价格不在tick上或精度不符: %q", line)
+		}
+		product, _ := refdata.Default.Product(strings.TrimRight(o.InstrumentID, "0123456789"))
+		if o.Volume < 1 || o.Volume > product.MaxOrderVolume {
+			t.Errorf("数量超出[1, %d]: %q", product.MaxOrderVolume, line)
+		}
+	}
+
+	// 8个品种各2个合约，热度偏斜时最热门合约的占比应明显高于均匀分布的1/16
+	if len(counts) != 16 {
+		t.Errorf("合约数 = %d, want 16", len(counts))
+	}
+	top := 0
+	for _, count := range counts {
+		top = max(top, count)
+	}
+	if top < 20000/16*3 {
+		t.Errorf("最热门合约只有%d笔订单，热度没有偏斜", top)
+	}
+}
+
+func TestGenerateMalformed(t *testing.T) {
+	config := DefaultConfig()
+	config.Orders = 10000
+	config.Products = []string{"T"}
+	config.Months = 1
+	config.MalformedRate = 0.1
+
+	malformed := 0
+	for _, line := range strings.Split(strings.TrimSuffix(generate(t, config), "\n"), "\n") {
+		record := utils.CustomSplit(line)
+		if !order.IsValidRecord(record) {
+			malformed++
+			continue
+		}
+		if o, err := order.ParseOrder(record); err != nil {
+			malformed++
+		} else if o.InstrumentID != "T2412" {
+			t.Errorf("合约 = %s, want T2412", o.InstrumentID)
+		}
+	}
+	if malformed < 800 || malformed > 1200 {
+		t.Errorf("畸形行 = %d, want 约1000", malformed)
+	}
+
+	config.Products = []string{"IO"}
+	if err := Generate(&bytes.Buffer{}, config); err == nil {
+		t.Error("期权没有内置参考价，应返回错误")
+	}
+	config.ReferencePrices = map[string]float64{"IO": 85}
+	generate(t, config)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"AuctionMatch/gen"
+	"bufio"
+	"flag"
+	"os"
+	"strings"
+)
+
+// runGen 生成模拟订单，-o 指定输出文件，否则输出到标准输出
+func runGen(argv []string) {
+	config := gen.DefaultConfig()
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	flags.Int64Var(&config.Seed, "seed", config.Seed, "随机种子")
+	flags.IntVar(&config.Orders, "n", config.Orders, "订单数（含畸形行）")
+	products := flags.String("products", "", "以逗号分隔的品种代码，默认为全部有内置参考价的品种")
+	prices := flags.String("price", "", "品种参考价，例如IF=3900,T=106.5")
+	flags.IntVar(&config.Months, "months", config.Months, "每个品种的合约数")
+	flags.Float64Var(&config.Skew, "skew", config.Skew, "合约热度的Zipf参数（>1）")
+	flags.Float64Var(&config.Spread, "spread", config.Spread, "报价相对参考价的标准差（比例）")
+	flags.Float64Var(&config.MalformedRate, "malformed", 0, "畸形行的比例")
+	refdataFile := flags.String("refdata", "", "先加载的参考数据文件（product,tick[,maxOrderVolume[,multiplier]]，tick可按价格分段，例如0.2;100:1）")
+	outputFile := flags.String("o", "", "输出文件")
+	applyLogFlags := addLogFlags(flags)
+	flags.Parse(argv)
+	applyLogFlags()
+
+	if *refdataFile != "" {
+		loadRefdata(*refdataFile)
+	}
+	if *products != "" {
+		config.Products = strings.Split(*products, ",")
+	}
+	var err error
+	if config.ReferencePrices, err = gen.ParseReferencePrices(*prices); err != nil {
+		fatal("参数错误", err)
+	}
+
+	output := os.Stdout
+	if *outputFile != "" {
+		if output, err = os.Create(*outputFile); err != nil {
+			fatal("无法创建输出文件", err)
+		}
+	}
+	writer := bufio.NewWriter(output)
+	if err := gen.Generate(writer, config); err != nil {
+		fatal("生成订单失败", err)
+	}
+	if err := writer.Flush(); err != nil {
+		fatal("写入订单失败", err)
+	}
+	if err := output.Close(); err != nil {
+		fatal("写入订单失败", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"AuctionMatch/order"
+	"fmt"
+	"strings"
+)
+
+const indicativeHeader = "seq,instrumentID,price,matchVolume,imbalance\n"
+
+// runIndicative 逐笔回放订单，输出每个事件后对应合约的指示性价格序列。
+// volume为负数的行表示按该价格撤销相应数量；-snapshot-in从快照恢复后继续回放，-snapshot-out在回放结束后写出快照
+func runIndicative(argv []string) {
+	args := mustParseArgs(argv, argSpec{options: []string{"-o", "-scale"}, extra: []string{"-snapshot-in", "-snapshot-out"}})
+	auction := order.NewIncrementalAuction(order.WithScaleMode(args.scaleMode))
+	if path := args.options["-snapshot-in"]; path != "" {
+		auction = readSnapshot(path, order.WithScaleMode(args.scaleMode))
+	}
+	stream := order.StreamOrders(args.inputFile)
+
+	var output strings.Builder
+	output.WriteString(indicativeHeader)
+	seq := 0
+
+	waitErrors := logErrors(stream)
+	order.ReadOrders(stream, func(o order.Order, record []string) {
+		indicative, err := auction.Apply(o, record[2])
+		if err != nil {
+			stream.Reject(order.STAGE_APPLY, o.InstrumentID, fmt.Errorf("撤单出错: %v", err))
+			return
+		}
+
+		seq++
+		writeIndicative(&output, seq, indicative, auction.Book(o.InstrumentID).Scale)
+	})
+	waitErrors()
+
+	writeOutput(output.String(), args.outputFile)
+	if path := args.options["-snapshot-out"]; path != "" {
+		writeSnapshot(path, auction)
+	}
+}
+
+func writeIndicative(output *strings.Builder, seq int, indicative order.Indicative, scale uint) {
+	if indicative.MatchVolume == 0 {
+		output.WriteString(fmt.Sprintf("%d,%s,,0,%d\n", seq, indicative.InstrumentID, indicative.Imbalance))
+		return
+	}
+	output.WriteString(fmt.Sprintf("%d,%s,%.*f,%d,%d\n", seq, indicative.InstrumentID,
+		scale, indicative.Price, indicative.MatchVolume, indicative.Imbalance))
+}
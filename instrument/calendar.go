@@ -0,0 +1,201 @@
+package instrument
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// DATE_LAYOUT 交易日和节假日文件的日期格式
+const DATE_LAYOUT = "20060102"
+
+const (
+	REASON_EXPIRED    = "expired"    // 合约已过最后交易日
+	REASON_NOT_LISTED = "not_listed" // 合约尚未挂牌
+)
+
+type (
+	// Calendar 交易日历：周六、周日和节假日文件中的日期不是交易日。nil表示没有节假日
+	Calendar struct {
+		holidays map[int]bool // YYYYMMDD
+	}
+
+	// ExpiryRule 由交割年月计算最后交易日
+	ExpiryRule func(year int, month time.Month, calendar *Calendar) time.Time
+
+	// ContractRule 品种的最后交易日和挂牌月份规则。挂牌合约为最近的未到期月份起连续Consecutive个月，
+	// 之后再加Quarterly个季月（3、6、9、12月）
+	ContractRule struct {
+		LastTradingDay ExpiryRule
+		Consecutive    int
+		Quarterly      int
+	}
+
+	// NotTradableError 合约在交易日不可交易
+	NotTradableError struct {
+		InstrumentID string
+		TradingDate  time.Time
+		Expiry       time.Time // 最后交易日
+		Kind         string    // REASON_EXPIRED或REASON_NOT_LISTED
+	}
+)
+
+// CONTRACT_RULES 内置的品种规则，最后交易日遇节假日顺延；期权没有单独规则时使用标的期货品种的规则。
+// 各交易所、期货与期权的最后交易日规则差别很大（如上期所为交割月15日、大商所期权为标的期货交割月前一个月），
+// 不在表中的品种无从判断，视为可交易
+var CONTRACT_RULES = map[string]ContractRule{
+	// 股指期货：合约月份的第三个周五，当月、下月及随后两个季月
+	"IF": {LastTradingDay: NthWeekday(3, time.Friday), Consecutive: 2, Quarterly: 2},
+	"IH": {LastTradingDay: NthWeekday(3, time.Friday), Consecutive: 2, Quarterly: 2},
+	"IC": {LastTradingDay: NthWeekday(3, time.Friday), Consecutive: 2, Quarterly: 2},
+	"IM": {LastTradingDay: NthWeekday(3, time.Friday), Consecutive: 2, Quarterly: 2},
+	// 股指期权：合约月份的第三个周五，当月、下2个月及随后3个季月
+	"IO": {LastTradingDay: NthWeekday(3, time.Friday), Consecutive: 3, Quarterly: 3},
+	"MO": {LastTradingDay: NthWeekday(3, time.Friday), Consecutive: 3, Quarterly: 3},
+	"HO": {LastTradingDay: NthWeekday(3, time.Friday), Consecutive: 3, Quarterly: 3},
+	// 国债期货：合约月份的第二个周五，最近的三个季月
+	"TS": {LastTradingDay: NthWeekday(2, time.Friday), Quarterly: 3},
+	"TF": {LastTradingDay: NthWeekday(2, time.Friday), Quarterly: 3},
+	"T":  {LastTradingDay: NthWeekday(2, time.Friday), Quarterly: 3},
+	"TL": {LastTradingDay: NthWeekday(2, time.Friday), Quarterly: 3},
+}
+
+// NewCalendar 由节假日创建交易日历
+func NewCalendar(holidays []time.Time) *Calendar {
+	c := &Calendar{holidays: make(map[int]bool, len(holidays))}
+	for _, holiday := range holidays {
+		c.holidays[dateKey(holiday)] = true
+	}
+	return c
+}
+
+// LoadCalendar 读取节假日文件
+func LoadCalendar(filename string) (*Calendar, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseCalendar(file)
+}
+
+// ParseCalendar 解析节假日文件，每行为"YYYYMMDD[,说明]"，#开头的行为注释
+func ParseCalendar(reader io.Reader) (*Calendar, error) {
+	var holidays []time.Time
+	scanner := bufio.NewScanner(reader)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		text, _, _ := strings.Cut(line, ",")
+		date, err := time.ParseInLocation(DATE_LAYOUT, strings.TrimSpace(text), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("节假日第%d行: 无效的日期 %s", lineNum, text)
+		}
+		holidays = append(holidays, date)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewCalendar(holidays), nil
+}
+
+// IsTradingDay 是否为交易日
+func (c *Calendar) IsTradingDay(date time.Time) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	return c == nil || !c.holidays[dateKey(date)]
+}
+
+// NextTradingDay 返回不早于date的第一个交易日
+func (c *Calendar) NextTradingDay(date time.Time) time.Time {
+	for !c.IsTradingDay(date) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+func dateKey(date time.Time) int {
+	return date.Year()*10000 + int(date.Month())*100 + date.Day()
+}
+
+// NthWeekday 合约月份的第n个星期weekday，不是交易日时顺延至下一交易日
+func NthWeekday(n int, weekday time.Weekday) ExpiryRule {
+	return func(year int, month time.Month, calendar *Calendar) time.Time {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+		offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+		return calendar.NextTradingDay(first.AddDate(0, 0, offset+7*(n-1)))
+	}
+}
+
+// NthTradingDay 合约月份的第n个交易日
+func NthTradingDay(n int) ExpiryRule {
+	return func(year int, month time.Month, calendar *Calendar) time.Time {
+		date := calendar.NextTradingDay(time.Date(year, month, 1, 0, 0, 0, 0, time.Local))
+		for i := 1; i < n; i++ {
+			date = calendar.NextTradingDay(date.AddDate(0, 0, 1))
+		}
+		return date
+	}
+}
+
+// check 检查交割年月为year、month的合约在交易日是否已到期或尚未挂牌，可交易时返回nil
+func (rule ContractRule) check(instrumentID string, year int, month time.Month, tradingDate time.Time, calendar *Calendar) error {
+	tradingDay := time.Date(tradingDate.Year(), tradingDate.Month(), tradingDate.Day(), 0, 0, 0, 0, time.Local)
+	expiry := rule.LastTradingDay(year, month, calendar)
+	if tradingDay.After(expiry) {
+		return &NotTradableError{InstrumentID: instrumentID, TradingDate: tradingDay, Expiry: expiry, Kind: REASON_EXPIRED}
+	}
+
+	// 最近的未到期月份：当月合约已过最后交易日时为下月
+	nearest := tradingDay.Year()*12 + int(tradingDay.Month()) - 1
+	if tradingDay.After(rule.LastTradingDay(tradingDay.Year(), tradingDay.Month(), calendar)) {
+		nearest++
+	}
+	target := year*12 + int(month) - 1
+	if !rule.listed(target, nearest) {
+		return &NotTradableError{InstrumentID: instrumentID, TradingDate: tradingDay, Expiry: expiry, Kind: REASON_NOT_LISTED}
+	}
+	return nil
+}
+
+// listed 以年*12+月-1表示月份，nearest为最近的未到期月份，判断target月份的合约是否已挂牌
+func (rule ContractRule) listed(target, nearest int) bool {
+	if target < nearest {
+		return false
+	}
+	if target < nearest+rule.Consecutive {
+		return true
+	}
+	quarter := nearest + rule.Consecutive - 1
+	for i := 0; i < rule.Quarterly; i++ {
+		quarter++
+		for quarter%3 != 2 { // 季月为3、6、9、12月
+			quarter++
+		}
+		if target == quarter {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *NotTradableError) Error() string {
+	if e.Kind == REASON_EXPIRED {
+		return fmt.Sprintf("合约%s已于%s到期，交易日为%s", e.InstrumentID,
+			e.Expiry.Format(DATE_LAYOUT), e.TradingDate.Format(DATE_LAYOUT))
+	}
+	return fmt.Sprintf("合约%s在交易日%s尚未挂牌", e.InstrumentID, e.TradingDate.Format(DATE_LAYOUT))
+}
+
+// Reason 拒绝原因，用于指标标签
+func (e *NotTradableError) Reason() string {
+	return e.Kind
+}
@@ -0,0 +1,203 @@
+// Package instrument 解析合约代码（期货、期权、组合合约），并结合品种参考数据按合约缓存tick、合约乘数、
+// 最大下单数量和最后交易日等信息；按交易日历和品种规则判断合约在交易日是否已到期或尚未挂牌。
+package instrument
+
+import (
+	"AuctionMatch/consts"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind 合约类型
+type Kind int8
+
+const (
+	Future Kind = iota // 期货
+	Option             // 期权
+	Spread             // 组合合约
+)
+
+const (
+	EXCHANGE_CFFEX = "CFFEX" // 中金所
+	EXCHANGE_DCE   = "DCE"   // 大商所
+	EXCHANGE_CZCE  = "CZCE"  // 郑商所
+)
+
+// OPTION_PRODUCT_SUFFIX 商品期权在参考数据中的品种代码后缀，例如豆粕期权为"m_o"；
+// 中金所期权有独立的品种代码（IO、MO、HO），不加后缀
+const OPTION_PRODUCT_SUFFIX = "_o"
+
+type (
+	// Instrument 解析后的合约代码
+	Instrument struct {
+		ID       string
+		Exchange string // 交易所，按内置品种表和郑商所的3位月份识别，无法识别时为空
+		Kind     Kind
+		Product  string      // 品种代码，期权为合约代码中的品种代码，组合合约为第一腿的品种代码
+		Year     int         // 交割年份，组合合约为第一腿的交割年份
+		Month    time.Month  // 交割月份
+		CallPut  byte        // 期权类型，'C'为看涨，'P'为看跌
+		Strike   float64     // 期权行权价
+		Spread   Combination // 组合合约的类型和各腿
+	}
+
+	// Combination 组合合约，例如大商所跨期价差"SP m2409&m2501"、跨品种价差"SPC a2409&m2409"，郑商所"SPD SR501&SR505"。
+	// 买入组合即买入第一腿、卖出第二腿，价格为两腿价格之差，tick取第一腿品种的tick
+	Combination struct {
+		Type string   // 组合类型，例如SP、SPC、SPD
+		Legs []string // 各腿合约代码
+	}
+)
+
+// Parse 解析合约代码，支持各交易所的命名方式，郑商所3位月份的年份按tradingDate确定（见ContractMonth）：
+//   - 期货：IF2412、m2409、SR501
+//   - 期权：中金所IO2412-C-3900、大商所m2409-C-3000、上期所cu2409C70000、郑商所SR501P5000
+//   - 组合合约：SP m2409&m2501，见ParseCombination
+func Parse(instrumentID string, tradingDate time.Time) (Instrument, bool) {
+	if IsCombination(instrumentID) {
+		combination, ok := ParseCombination(instrumentID)
+		if !ok {
+			return Instrument{}, false
+		}
+		first, ok := Parse(combination.Legs[0], tradingDate)
+		if !ok {
+			return Instrument{}, false
+		}
+		first.ID, first.Kind, first.CallPut, first.Strike, first.Spread = instrumentID, Spread, 0, 0, combination
+		return first, true
+	}
+
+	i, j, ok := scanPrefix(instrumentID)
+	if !ok {
+		return Instrument{}, false
+	}
+	year, month, ok := ContractMonth(instrumentID, tradingDate)
+	if !ok {
+		return Instrument{}, false
+	}
+	instrument := Instrument{ID: instrumentID, Exchange: exchangeOf(instrumentID[:i], j-i),
+		Kind: Future, Product: instrumentID[:i], Year: year, Month: month}
+	if j == len(instrumentID) {
+		return instrument, true
+	}
+
+	// 期权：[-]C|P[-]行权价，带分隔符时两侧都要有
+	rest := instrumentID[j:]
+	dashed := strings.HasPrefix(rest, "-")
+	if dashed {
+		rest = rest[1:]
+	}
+	if rest == "" || (rest[0] != 'C' && rest[0] != 'P') {
+		return Instrument{}, false
+	}
+	instrument.CallPut, rest = rest[0], rest[1:]
+	if dashed {
+		if rest, ok = strings.CutPrefix(rest, "-"); !ok {
+			return Instrument{}, false
+		}
+	}
+	if rest == "" || rest[0] < '0' || rest[0] > '9' {
+		return Instrument{}, false
+	}
+	strike, err := strconv.ParseFloat(rest, 64)
+	if err != nil || strike <= 0 {
+		return Instrument{}, false
+	}
+	instrument.Kind, instrument.Strike = Option, strike
+	return instrument, true
+}
+
+// exchangeOf 按内置品种表识别交易所，不在表中的3位月份合约视为郑商所
+func exchangeOf(product string, monthDigits int) string {
+	switch {
+	case consts.CFE_PRODUCT_TICK[product] > 0:
+		return EXCHANGE_CFFEX
+	case consts.DCE_PRODUCT_TICK[product] > 0:
+		return EXCHANGE_DCE
+	case consts.CZCE_PRODUCT_TICK[product] > 0, monthDigits == 3:
+		return EXCHANGE_CZCE
+	}
+	return ""
+}
+
+// IsCombination 合约代码是否为组合合约的形式（包含空格或&）
+func IsCombination(instrumentID string) bool {
+	return strings.ContainsAny(instrumentID, " &")
+}
+
+// ParseCombination 解析"<类型> <腿1>&<腿2>[&...]"形式的组合合约代码，类型为大写字母，各腿以字母开头
+func ParseCombination(instrumentID string) (Combination, bool) {
+	comboType, legs, ok := strings.Cut(instrumentID, " ")
+	if !ok || comboType == "" {
+		return Combination{}, false
+	}
+	for _, c := range comboType {
+		if c < 'A' || c > 'Z' {
+			return Combination{}, false
+		}
+	}
+
+	combination := Combination{Type: comboType, Legs: strings.Split(legs, "&")}
+	if len(combination.Legs) < 2 {
+		return Combination{}, false
+	}
+	for _, leg := range combination.Legs {
+		if leg == "" || !isLetter(leg[0]) || strings.ContainsRune(leg, ' ') {
+			return Combination{}, false
+		}
+	}
+	return combination, true
+}
+
+// ContractMonth 从合约ID中解析交割年月：品种代码之后的4位数字为YYMM，3位数字（郑商所格式）为YMM，
+// 年份取不早于交易日前一年的最近年份；之后可以跟期权的行权方向和价格，例如IO2412-C-3900。
+// 品种代码的识别与Parse相同，见scanPrefix
+func ContractMonth(instrumentID string, tradingDate time.Time) (year int, month time.Month, ok bool) {
+	i, j, ok := scanPrefix(instrumentID)
+	if !ok {
+		return 0, 0, false
+	}
+	digits, _ := strconv.Atoi(instrumentID[i:j])
+	month = time.Month(digits % 100)
+	if month < time.January || month > time.December {
+		return 0, 0, false
+	}
+
+	if j-i == 4 {
+		year = tradingDate.Year()/100*100 + digits/100
+	} else {
+		year = tradingDate.Year()/10*10 + digits/100
+		if year < tradingDate.Year()-1 {
+			year += 10
+		}
+	}
+	return year, month, true
+}
+
+// ProductCode 取合约代码开头至多MAX_PRODUCT_LETTERS个字母作为品种代码，用于无法解析的合约代码
+func ProductCode(instrumentID string) string {
+	i, _, _ := scanPrefix(instrumentID)
+	return instrumentID[:min(i, MAX_PRODUCT_LETTERS)]
+}
+
+// MAX_PRODUCT_LETTERS 品种代码的最大字母数
+const MAX_PRODUCT_LETTERS = 2
+
+// scanPrefix 扫描合约代码开头的字母和其后的数字，返回两者的结束位置；品种代码为1到MAX_PRODUCT_LETTERS个字母、
+// 月份为3或4位数字时ok为true。Parse、ContractMonth和ProductCode都由此识别品种代码，对同一合约代码的判断一致
+func scanPrefix(instrumentID string) (productEnd, monthEnd int, ok bool) {
+	for productEnd < len(instrumentID) && isLetter(instrumentID[productEnd]) {
+		productEnd++
+	}
+	monthEnd = productEnd
+	for monthEnd < len(instrumentID) && instrumentID[monthEnd] >= '0' && instrumentID[monthEnd] <= '9' {
+		monthEnd++
+	}
+	digits := monthEnd - productEnd
+	return productEnd, monthEnd, productEnd > 0 && productEnd <= MAX_PRODUCT_LETTERS && (digits == 3 || digits == 4)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
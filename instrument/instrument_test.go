@@ -0,0 +1,309 @@
+package instrument
+
+import (
+	"AuctionMatch/refdata"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTradingDate = time.Date(2024, 11, 20, 0, 0, 0, 0, time.Local)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		instrumentID string
+		want         Instrument
+		ok           bool
+	}{
+		{instrumentID: "IF2412", want: Instrument{Exchange: EXCHANGE_CFFEX, Kind: Future, Product: "IF", Year: 2024, Month: 12}, ok: true},
+		{instrumentID: "T2503", want: Instrument{Exchange: EXCHANGE_CFFEX, Kind: Future, Product: "T", Year: 2025, Month: 3}, ok: true},
+		{instrumentID: "SR501", want: Instrument{Exchange: EXCHANGE_CZCE, Kind: Future, Product: "SR", Year: 2025, Month: 1}, ok: true},
+		{instrumentID: "cu2409", want: Instrument{Kind: Future, Product: "cu", Year: 2024, Month: 9}, ok: true},
+		{
+			instrumentID: "IO2412-C-3900",
+			want:         Instrument{Exchange: EXCHANGE_CFFEX, Kind: Option, Product: "IO", Year: 2024, Month: 12, CallPut: 'C', Strike: 3900},
+			ok:           true,
+		},
+		{
+			instrumentID: "m2409-P-3000",
+			want:         Instrument{Exchange: EXCHANGE_DCE, Kind: Option, Product: "m", Year: 2024, Month: 9, CallPut: 'P', Strike: 3000},
+			ok:           true,
+		},
+		{
+			instrumentID: "cu2409C70000",
+			want:         Instrument{Kind: Option, Product: "cu", Year: 2024, Month: 9, CallPut: 'C', Strike: 70000},
+			ok:           true,
+		},
+		{
+			instrumentID: "SR501P5000",
+			want:         Instrument{Exchange: EXCHANGE_CZCE, Kind: Option, Product: "SR", Year: 2025, Month: 1, CallPut: 'P', Strike: 5000},
+			ok:           true,
+		},
+		{
+			instrumentID: "HO2412-C-2.5",
+			want:         Instrument{Exchange: EXCHANGE_CFFEX, Kind: Option, Product: "HO", Year: 2024, Month: 12, CallPut: 'C', Strike: 2.5},
+			ok:           true,
+		},
+		{
+			instrumentID: "SP m2409&m2501",
+			want: Instrument{Exchange: EXCHANGE_DCE, Kind: Spread, Product: "m", Year: 2024, Month: 9,
+				Spread: Combination{Type: "SP", Legs: []string{"m2409", "m2501"}}},
+			ok: true,
+		},
+		{instrumentID: "IF24120", ok: false},
+		{instrumentID: "IF2413", ok: false},
+		{instrumentID: "ABC2412", ok: false},
+		{instrumentID: "IF", ok: false},
+		{instrumentID: "2412", ok: false},
+		{instrumentID: "IO2412-C3900", ok: false},
+		{instrumentID: "IO2412-X-3900", ok: false},
+		{instrumentID: "IO2412-C-", ok: false},
+		{instrumentID: "IO2412-C-abc", ok: false},
+		{instrumentID: "SP 2409&m2501", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Parse(tt.instrumentID, testTradingDate)
+		if ok {
+			tt.want.ID = tt.instrumentID
+		}
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v, %v", tt.instrumentID, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseCombination(t *testing.T) {
+	tests := []struct {
+		instrumentID string
+		want         Combination
+		ok           bool
+	}{
+		{instrumentID: "SP m2409&m2501", want: Combination{Type: "SP", Legs: []string{"m2409", "m2501"}}, ok: true},
+		{instrumentID: "SPC a2409&m2409", want: Combination{Type: "SPC", Legs: []string{"a2409", "m2409"}}, ok: true},
+		{instrumentID: "SPD SR501&SR505", want: Combination{Type: "SPD", Legs: []string{"SR501", "SR505"}}, ok: true},
+		{instrumentID: "IF2412", ok: false},
+		{instrumentID: "SP m2409", ok: false},
+		{instrumentID: "sp m2409&m2501", ok: false},
+		{instrumentID: "SP m2409&", ok: false},
+		{instrumentID: "SP 2409&m2501", ok: false},
+		{instrumentID: " m2409&m2501", ok: false},
+		{instrumentID: "m2409&m2501", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseCombination(tt.instrumentID)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseCombination(%q) = %+v, %v, want %+v, %v", tt.instrumentID, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestContractMonth(t *testing.T) {
+	tests := []struct {
+		instrumentID string
+		year         int
+		month        time.Month
+		ok           bool
+	}{
+		{"IF2412", 2024, time.December, true},
+		{"IO2412-C-3900", 2024, time.December, true},
+		{"m2409C3000", 2024, time.September, true},
+		{"SR501", 2025, time.January, true},
+		{"SR909", 2029, time.September, true},
+		{"IF2413", 0, 0, false},
+		{"IF24", 0, 0, false},
+		{"2412", 0, 0, false},
+		{"ABC2412", 0, 0, false}, // 品种代码超过两个字母，与Parse一致
+	}
+	for _, tt := range tests {
+		year, month, ok := ContractMonth(tt.instrumentID, testTradingDate)
+		if year != tt.year || month != tt.month || ok != tt.ok {
+			t.Errorf("ContractMonth(%s) = %d, %d, %v, want %d, %d, %v", tt.instrumentID, year, month, ok, tt.year, tt.month, tt.ok)
+		}
+	}
+}
+
+func TestTickSchedule(t *testing.T) {
+	products, err := refdata.ParseProducts(strings.NewReader("ZB,0.2;10:1;100:5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticks := NewTickSchedule(products[0].Tick, products[0].TickBands)
+
+	tests := []struct {
+		price    float32
+		priceInt int64
+		tick     float32
+	}{
+		{price: -1, priceInt: -5, tick: 0.2},
+		{price: 9.8, priceInt: 49, tick: 0.2},
+		{price: 10, priceInt: 50, tick: 1},
+		{price: 11, priceInt: 51, tick: 1},
+		{price: 99, priceInt: 139, tick: 1},
+		{price: 100, priceInt: 140, tick: 5},
+		{price: 105, priceInt: 141, tick: 5},
+	}
+	for _, tt := range tests {
+		if got := ticks.ToInt(tt.price); got != tt.priceInt {
+			t.Errorf("ToInt(%v) = %d, want %d", tt.price, got, tt.priceInt)
+		}
+		if got := ticks.ToFloat(tt.priceInt); got != tt.price {
+			t.Errorf("ToFloat(%d) = %v, want %v", tt.priceInt, got, tt.price)
+		}
+		if got := ticks.Tick(tt.price); got != tt.tick {
+			t.Errorf("Tick(%v) = %v, want %v", tt.price, got, tt.tick)
+		}
+	}
+	if got := ticks.ToInt(10.4); got != 50 {
+		t.Errorf("不在tick上的价格 ToInt(10.4) = %d, want 50", got)
+	}
+	for _, tt := range []struct {
+		price       float32
+		floor, ceil int64
+	}{
+		{price: 10.6, floor: 50, ceil: 51},
+		{price: 9.9, floor: 49, ceil: 50}, // 向上取到下一价格段的起点
+		{price: -0.3, floor: -2, ceil: -1},
+		{price: 11, floor: 51, ceil: 51},
+		{price: 0.2, floor: 1, ceil: 1}, // float32的0.2略大于0.2，取整到万分之一后恰在tick上
+	} {
+		if got := ticks.Floor(tt.price); got != tt.floor {
+			t.Errorf("Floor(%v) = %d, want %d", tt.price, got, tt.floor)
+		}
+		if got := ticks.Ceil(tt.price); got != tt.ceil {
+			t.Errorf("Ceil(%v) = %d, want %d", tt.price, got, tt.ceil)
+		}
+	}
+	if got := ticks.Scale(); got != 1 {
+		t.Errorf("Scale() = %d, want 1", got)
+	}
+	if got := NewTickSchedule(0.005, nil).Scale(); got != 3 {
+		t.Errorf("Scale() = %d, want 3", got)
+	}
+
+	for _, text := range []string{"ZB,0.2;10.1:1", "ZB,0.2;10:1;10:5", "ZB,0.2;10", "ZB,0.2;10:0"} {
+		if _, err := refdata.ParseProducts(strings.NewReader(text)); err == nil {
+			t.Errorf("ParseProducts(%q) 应返回错误", text)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	products := refdata.NewRegistry()
+	registry := NewRegistry(products, testTradingDate)
+
+	info := registry.Lookup("IF2412")
+	if !info.Valid || info.Multiplier != 300 || info.MaxOrderVolume != 20 || info.Ticks.Tick(0) != 0.2 ||
+		!info.Expiry.Equal(time.Date(2024, 12, 20, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Lookup(IF2412) = %+v", info)
+	}
+	if registry.Lookup("IF2412") != info {
+		t.Errorf("同一合约应只解析一次")
+	}
+	if got := registry.Lookup("m2409-C-3000").Ticks.Tick(0); got != 0.5 {
+		t.Errorf("豆粕期权的tick = %v, want 期权品种m_o的tick 0.5", got)
+	}
+	if info := registry.Lookup("ZZ"); info.Valid || info.Product != "ZZ" || info.Ticks.Tick(0) != DEFAULT_TICK {
+		t.Errorf("Lookup(ZZ) = %+v, want 无法解析、使用默认tick", info)
+	}
+
+	// 参考数据变化后缓存失效
+	products.Put(refdata.Product{Code: "IF", Tick: 0.4})
+	if got := registry.Lookup("IF2412").Ticks.Tick(0); got != 0.4 {
+		t.Errorf("参考数据修改后 tick = %v, want 0.4", got)
+	}
+
+	registry.SetTradingDate(time.Date(2031, 1, 5, 0, 0, 0, 0, time.Local))
+	if info := registry.Lookup("SR501"); info.Year != 2035 {
+		t.Errorf("修改交易日后 SR501 的交割年份 = %d, want 2035", info.Year)
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	calendar, err := ParseCalendar(strings.NewReader("# 节假日\n20241220,测试用假日\n\n20250101\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calendar.IsTradingDay(time.Date(2024, 12, 20, 0, 0, 0, 0, time.Local)) ||
+		calendar.IsTradingDay(time.Date(2024, 12, 21, 0, 0, 0, 0, time.Local)) ||
+		!calendar.IsTradingDay(time.Date(2024, 12, 23, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("节假日和周末不应是交易日")
+	}
+	if _, err := ParseCalendar(strings.NewReader("2024-12-20\n")); err == nil {
+		t.Errorf("ParseCalendar() 无效日期应返回错误")
+	}
+
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+	expiries := []struct {
+		rule     ExpiryRule
+		calendar *Calendar
+		month    time.Month
+		want     time.Time
+	}{
+		{NthWeekday(3, time.Friday), nil, time.November, date(2024, 11, 15)},
+		{NthWeekday(3, time.Friday), nil, time.December, date(2024, 12, 20)},
+		{NthWeekday(3, time.Friday), calendar, time.December, date(2024, 12, 23)}, // 遇节假日顺延
+		{NthWeekday(2, time.Friday), nil, time.December, date(2024, 12, 13)},
+		{NthTradingDay(10), nil, time.November, date(2024, 11, 14)},
+	}
+	for _, tt := range expiries {
+		if got := tt.rule(2024, tt.month, tt.calendar); !got.Equal(tt.want) {
+			t.Errorf("最后交易日(2024, %v) = %v, want %v", tt.month, got.Format(DATE_LAYOUT), tt.want.Format(DATE_LAYOUT))
+		}
+	}
+
+	registry := NewRegistry(refdata.NewRegistry(), testTradingDate)
+	tests := []struct {
+		instrumentID string
+		reason       string // 空表示可交易
+	}{
+		{"IF2411", REASON_EXPIRED},
+		{"IF2412", ""},
+		{"IF2501", ""},
+		{"IF2502", REASON_NOT_LISTED},
+		{"IF2503", ""},
+		{"IF2506", ""},
+		{"IF2509", REASON_NOT_LISTED},
+		{"IO2502-C-3900", ""}, // 股指期权挂牌当月及下2个月
+		{"T2412", ""},
+		{"T2501", REASON_NOT_LISTED},
+		{"T2506", ""},
+		{"T2509", REASON_NOT_LISTED},
+		{"m2411", ""}, // 没有内置规则的品种视为可交易
+		{"m2512", ""},
+		{"m2409-C-3000", ""},
+		{"cu2411", ""},
+		{"SR501", ""},
+		{"SP m2411&m2501", ""},
+		{"SP IF2411&IF2412", REASON_EXPIRED},
+		{"SP IF2412&IF2502", REASON_NOT_LISTED},
+		{"ZZ", ""}, // 无法解析的合约视为可交易
+	}
+	if info := registry.Lookup("m2411"); !info.Expiry.IsZero() {
+		t.Errorf("没有规则的品种 m2411 最后交易日 = %v, want 零值", info.Expiry)
+	}
+	for _, tt := range tests {
+		err := registry.Lookup(tt.instrumentID).Tradable()
+		reason := ""
+		if err != nil {
+			reason = err.(*NotTradableError).Reason()
+		}
+		if reason != tt.reason {
+			t.Errorf("Lookup(%q).Tradable() = %v, want %q", tt.instrumentID, err, tt.reason)
+		}
+	}
+
+	// 当月合约的最后交易日因节假日顺延后仍可交易
+	registry.SetCalendar(calendar)
+	registry.SetTradingDate(date(2024, 12, 23))
+	if err := registry.Lookup("IF2412").Tradable(); err != nil {
+		t.Errorf("节假日顺延后 IF2412 应可交易: %v", err)
+	}
+	if info := registry.Lookup("IF2412"); !info.Expiry.Equal(date(2024, 12, 23)) {
+		t.Errorf("IF2412 最后交易日 = %v, want 20241223", info.Expiry.Format(DATE_LAYOUT))
+	}
+}
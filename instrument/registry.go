@@ -0,0 +1,172 @@
+package instrument
+
+import (
+	"AuctionMatch/refdata"
+	"sync"
+	"time"
+)
+
+// DEFAULT_TICK 参考数据中没有品种时使用的tick
+const DEFAULT_TICK = 0.2
+
+type (
+	// Info 合约的解析结果及其品种参考数据
+	Info struct {
+		Instrument
+		Valid          bool         // 合约代码可以解析；否则只有ID和Product（取开头至多两个字母）
+		Ticks          TickSchedule // 按价格分段的tick，没有参考数据时为DEFAULT_TICK
+		MaxOrderVolume int32        // 每次最大下单数量，0表示未知
+		Multiplier     int32        // 合约乘数，0表示未知
+		Expiry         time.Time    // 最后交易日，见CONTRACT_RULES；无法解析或品种没有规则时为零值
+		tradable       error
+	}
+
+	// Registry 按合约缓存Info，品种参考数据变化后自动失效
+	Registry struct {
+		products    *refdata.Registry
+		tradingDate time.Time
+		calendar    *Calendar
+		version     uint64 // 缓存对应的参考数据版本
+		infos       map[string]*Info
+		mu          sync.RWMutex
+	}
+)
+
+// Default 进程内默认的合约表，使用refdata.Default，交易日为当天
+var Default = NewRegistry(refdata.Default, time.Time{})
+
+// NewRegistry 创建合约表，tradingDate用于确定郑商所3位月份合约的年份，零值表示当天
+func NewRegistry(products *refdata.Registry, tradingDate time.Time) *Registry {
+	if tradingDate.IsZero() {
+		tradingDate = time.Now()
+	}
+	return &Registry{
+		products:    products,
+		tradingDate: tradingDate,
+		infos:       make(map[string]*Info),
+	}
+}
+
+// SetTradingDate 修改交易日并清空缓存，零值表示当天
+func (r *Registry) SetTradingDate(tradingDate time.Time) {
+	if tradingDate.IsZero() {
+		tradingDate = time.Now()
+	}
+	r.mu.Lock()
+	r.tradingDate = tradingDate
+	r.infos = make(map[string]*Info)
+	r.mu.Unlock()
+}
+
+// SetCalendar 修改交易日历并清空缓存，nil表示只排除周末
+func (r *Registry) SetCalendar(calendar *Calendar) {
+	r.mu.Lock()
+	r.calendar = calendar
+	r.infos = make(map[string]*Info)
+	r.mu.Unlock()
+}
+
+// Calendar 返回交易日历，nil表示只排除周末
+func (r *Registry) Calendar() *Calendar {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.calendar
+}
+
+// TradingDate 返回交易日
+func (r *Registry) TradingDate() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tradingDate
+}
+
+// Lookup 返回合约的Info，同一合约只解析一次；返回值在合约表中共享，不应修改
+func (r *Registry) Lookup(instrumentID string) *Info {
+	version := r.products.Version()
+	r.mu.RLock()
+	info, ok := r.infos[instrumentID]
+	stale := r.version != version
+	r.mu.RUnlock()
+	if ok && !stale {
+		return info
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.version != version {
+		r.infos = make(map[string]*Info)
+		r.version = version
+	}
+	if info, ok := r.infos[instrumentID]; ok {
+		return info
+	}
+	info = r.resolve(instrumentID)
+	r.infos[instrumentID] = info
+	return info
+}
+
+// resolve 解析合约代码并查询品种参考数据，商品期权优先使用"<品种代码>_o"的参考数据
+func (r *Registry) resolve(instrumentID string) *Info {
+	info := &Info{Instrument: Instrument{ID: instrumentID, Product: ProductCode(instrumentID)}}
+	if instrument, ok := Parse(instrumentID, r.tradingDate); ok {
+		info.Instrument, info.Valid = instrument, true
+		if rule, ok := r.rule(instrument); ok {
+			info.Expiry = rule.LastTradingDay(instrument.Year, instrument.Month, r.calendar)
+		}
+		info.tradable = r.checkTradable(instrument)
+	}
+
+	product, ok := refdata.Product{}, false
+	if info.Kind == Option {
+		product, ok = r.products.Product(info.Product + OPTION_PRODUCT_SUFFIX)
+	}
+	if !ok {
+		product, ok = r.products.Product(info.Product)
+	}
+	if !ok {
+		info.Ticks = NewTickSchedule(DEFAULT_TICK, nil)
+		return info
+	}
+	info.Ticks = NewTickSchedule(product.Tick, product.TickBands)
+	info.MaxOrderVolume = product.MaxOrderVolume
+	info.Multiplier = product.Multiplier
+	return info
+}
+
+// rule 返回合约的品种规则，商品期权优先使用"<品种代码>_o"的规则，没有规则时ok为false
+func (r *Registry) rule(instrument Instrument) (ContractRule, bool) {
+	if instrument.Kind == Option {
+		if rule, ok := CONTRACT_RULES[instrument.Product+OPTION_PRODUCT_SUFFIX]; ok {
+			return rule, true
+		}
+	}
+	rule, ok := CONTRACT_RULES[instrument.Product]
+	return rule, ok
+}
+
+// checkTradable 检查合约在交易日是否可交易，组合合约要求各腿都可交易，没有品种规则的合约视为可交易
+func (r *Registry) checkTradable(instrument Instrument) error {
+	if instrument.Kind != Spread {
+		rule, ok := r.rule(instrument)
+		if !ok {
+			return nil
+		}
+		return rule.check(instrument.ID, instrument.Year, instrument.Month, r.tradingDate, r.calendar)
+	}
+	for _, leg := range instrument.Spread.Legs {
+		legInstrument, ok := Parse(leg, r.tradingDate)
+		if !ok {
+			continue
+		}
+		if err := r.checkTradable(legInstrument); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tradable 返回合约在合约表的交易日是否可交易：已过最后交易日或尚未挂牌时返回*NotTradableError。
+// 无法解析的合约代码或品种不在CONTRACT_RULES中时无从判断，视为可交易
+func (info *Info) Tradable() error {
+	return info.tradable
+}
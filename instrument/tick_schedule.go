@@ -0,0 +1,123 @@
+package instrument
+
+import (
+	"AuctionMatch/refdata"
+	"math"
+	"sort"
+)
+
+type (
+	// TickSchedule 合约按价格分段的最小变动价位，负责价格与分价表档位序号之间的换算。
+	// 档位序号在各价格段内按该段的tick递增，跨段时连续，因此相邻序号即分价表中相邻的有效价格；
+	// 只有一段时序号即价格除以tick，与order.ToInt、order.ToFloat一致
+	TickSchedule struct {
+		bands []tickBand // 按起点升序，第一段起点为0，同时适用于更低（包括负）的价格
+	}
+
+	tickBand struct {
+		from   int64 // 起点（万分之一单位）
+		tick   int64 // tick（万分之一单位）
+		offset int64 // 起点对应的档位序号
+	}
+)
+
+const (
+	PRICE_UNIT  = 10000 // 价格整数化的放大倍数
+	PRICE_SCALE = 4     // PRICE_UNIT对应的小数位数
+)
+
+// NewTickSchedule 由最低价格段的tick和更高价格段构造价格分段，价格段须满足refdata中的校验规则
+func NewTickSchedule(tick float32, bands []refdata.TickBand) TickSchedule {
+	schedule := TickSchedule{bands: make([]tickBand, 1, len(bands)+1)}
+	schedule.bands[0] = tickBand{tick: tickUnits(tick)}
+	for _, band := range bands {
+		prev := schedule.bands[len(schedule.bands)-1]
+		from := int64(math.Round(float64(band.From) * PRICE_UNIT))
+		schedule.bands = append(schedule.bands, tickBand{
+			from:   from,
+			tick:   tickUnits(band.Tick),
+			offset: prev.offset + (from-prev.from)/prev.tick,
+		})
+	}
+	return schedule
+}
+
+// ToInt 将价格换算为档位序号，按四舍五入取档位，与order.ToInt一致：float32略小于tick整数倍的价格不会落到低一档，
+// 不在tick上的价格取所在价格段内最近的档位
+func (s TickSchedule) ToInt(price float32) int64 {
+	if len(s.bands) == 1 {
+		return int64(math.Round(float64(price) * PRICE_UNIT / float64(s.bands[0].tick)))
+	}
+	band := s.bandOf(int64(math.Round(float64(price) * PRICE_UNIT)))
+	return band.offset + int64(math.Round((float64(price)*PRICE_UNIT-float64(band.from))/float64(band.tick)))
+}
+
+// Floor 将价格换算为不高于该价格的最近档位序号，价格先取整到万分之一以消除浮点误差
+func (s TickSchedule) Floor(price float32) int64 {
+	units := int64(math.Round(float64(price) * PRICE_UNIT))
+	band := s.bandOf(units)
+	return band.offset + floorDiv(units-band.from, band.tick)
+}
+
+// Ceil 将价格换算为不低于该价格的最近档位序号，价格先取整到万分之一以消除浮点误差
+func (s TickSchedule) Ceil(price float32) int64 {
+	units := int64(math.Round(float64(price) * PRICE_UNIT))
+	band := s.bandOf(units)
+	return band.offset - floorDiv(band.from-units, band.tick)
+}
+
+// ToFloat 将档位序号换算为价格
+func (s TickSchedule) ToFloat(priceInt int64) float32 {
+	i := sort.Search(len(s.bands), func(i int) bool { return s.bands[i].offset > priceInt }) - 1
+	band := s.bands[max(i, 0)]
+	return float32(float64(band.from+(priceInt-band.offset)*band.tick) / PRICE_UNIT)
+}
+
+// Tick 返回价格所在价格段的tick
+func (s TickSchedule) Tick(price float32) float32 {
+	band := s.bandOf(int64(math.Round(float64(price) * PRICE_UNIT)))
+	return float32(float64(band.tick) / PRICE_UNIT)
+}
+
+// Bands 返回最低价格段的tick和更高的价格段，以NewTickSchedule(tick, bands)可重建同样的分段
+func (s TickSchedule) Bands() (tick float32, bands []refdata.TickBand) {
+	for _, band := range s.bands[1:] {
+		bands = append(bands, refdata.TickBand{
+			From: float32(float64(band.from) / PRICE_UNIT),
+			Tick: float32(float64(band.tick) / PRICE_UNIT),
+		})
+	}
+	return float32(float64(s.bands[0].tick) / PRICE_UNIT), bands
+}
+
+// Scale 返回各价格段tick中最大的小数位数，用于输出价格
+func (s TickSchedule) Scale() uint {
+	var scale uint
+	for _, band := range s.bands {
+		bandScale := uint(PRICE_SCALE)
+		for units := band.tick; bandScale > 0 && units%10 == 0; units /= 10 {
+			bandScale--
+		}
+		scale = max(scale, bandScale)
+	}
+	return scale
+}
+
+// bandOf 返回价格（万分之一单位）所在的价格段
+func (s TickSchedule) bandOf(units int64) tickBand {
+	i := sort.Search(len(s.bands), func(i int) bool { return s.bands[i].from > units }) - 1
+	return s.bands[max(i, 0)]
+}
+
+// floorDiv 向负无穷取整的整数除法，b须为正
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+func tickUnits(tick float32) int64 {
+	return int64(math.Round(float64(tick) * PRICE_UNIT))
+}
@@ -0,0 +1,109 @@
+// Package golden 提供基于testdata目录的黄金文件测试：发现输入与期望输出文件对，
+// 比较不一致时输出逐行差异，go test -update 时以实际输出重写期望文件。
+package golden
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// EXPECTED_EXT 期望输出文件的扩展名，与输入文件同名
+const EXPECTED_EXT = ".golden"
+
+var update = flag.Bool("update", false, "以实际输出重写黄金文件")
+
+// Case 一对输入和期望输出文件
+type Case struct {
+	Name     string // 输入文件名去掉扩展名
+	Input    string // 输入文件路径
+	Expected string // 期望输出文件路径
+}
+
+// Discover 返回dir中匹配pattern的输入文件及其同名的.golden文件，按名称排序
+func Discover(t *testing.T, dir, pattern string) []Case {
+	t.Helper()
+	inputs, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		t.Fatalf("查找黄金文件失败: %v", err)
+	}
+	sort.Strings(inputs)
+
+	cases := make([]Case, 0, len(inputs))
+	for _, input := range inputs {
+		base := strings.TrimSuffix(input, filepath.Ext(input))
+		cases = append(cases, Case{Name: filepath.Base(base), Input: input, Expected: base + EXPECTED_EXT})
+	}
+	if len(cases) == 0 {
+		t.Fatalf("%s 中没有匹配 %s 的输入文件", dir, pattern)
+	}
+	return cases
+}
+
+// Assert 比较实际输出与期望文件，-update时改为写入期望文件
+func Assert(t *testing.T, expectedPath, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(expectedPath, []byte(got), 0644); err != nil {
+			t.Fatalf("写入黄金文件失败: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(expectedPath)
+	if err != nil {
+		t.Fatalf("读取黄金文件失败: %v（使用 go test -update 生成）", err)
+	}
+	if string(want) != got {
+		t.Errorf("输出与 %s 不一致（-期望 +实际）:\n%s", expectedPath, Diff(string(want), got))
+	}
+}
+
+// Diff 按行比较两段文本，输出最长公共子序列之外的删除(-)和新增(+)行，相同的行以空格开头
+func Diff(want, got string) string {
+	a, b := splitLines(want), splitLines(got)
+
+	// lcs[i][j] 为a[i:]和b[j:]的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&diff, "  %d: %s\n", i+1, a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			fmt.Fprintf(&diff, "+ %d: %s\n", j+1, b[j])
+			j++
+		default:
+			fmt.Fprintf(&diff, "- %d: %s\n", i+1, a[i])
+			i++
+		}
+	}
+	return diff.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,20 @@
+package golden
+
+import "testing"
+
+// TestDiff 测试逐行差异只标出删除和新增的行
+func TestDiff(t *testing.T) {
+	want := "IF2412,3973.4\nTS2412,101.236\nT2503,105.010\n"
+	got := "IF2412,3973.4\nT2503,105.010\nTL2503,\n"
+
+	expected := "  1: IF2412,3973.4\n" +
+		"- 2: TS2412,101.236\n" +
+		"  3: T2503,105.010\n" +
+		"+ 3: TL2503,\n"
+	if diff := Diff(want, got); diff != expected {
+		t.Errorf("Diff() =\n%s\nwant\n%s", diff, expected)
+	}
+	if diff := Diff("", "IF2412,\n"); diff != "+ 1: IF2412,\n" {
+		t.Errorf("Diff() = %q", diff)
+	}
+}
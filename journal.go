@@ -0,0 +1,32 @@
+package main
+
+import (
+	"AuctionMatch/journal"
+	"AuctionMatch/order"
+	"log/slog"
+)
+
+// runJournal 从预写日志恢复集合竞价时段，输出各合约按存量订单计算的集合竞价价格
+func runJournal(argv []string) {
+	args := mustParseArgs(argv, argSpec{options: []string{"-o"}})
+	session := recoverJournal(args.inputFile)
+	writeResults(session.Results(), args.outputFile)
+}
+
+// recoverJournal 截断日志损坏的尾部并重建时段，截断情况提示到标准错误
+func recoverJournal(path string, opts ...order.SessionOption) *order.CallSession {
+	recovery, err := journal.Recover(path)
+	if err != nil {
+		fatal("恢复预写日志失败", err)
+	}
+	if recovery.TruncatedBytes > 0 {
+		slog.Warn("预写日志尾部已截断", "path", path, "bytes", recovery.TruncatedBytes, "reason", recovery.Reason)
+	}
+
+	session, err := journal.Rebuild(recovery.Events, opts...)
+	if err != nil {
+		fatal("重建集合竞价时段失败", err)
+	}
+	slog.Info("已从预写日志恢复", "path", path, "events", len(recovery.Events))
+	return session
+}
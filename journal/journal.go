@@ -0,0 +1,280 @@
+package journal
+
+import (
+	"AuctionMatch/order"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// 记录格式（小端）：payload长度uint32 | payload的CRC32-C uint32 | payload
+// payload：事件类型uint8 | orderID | owner | instrumentID（均为uvarint长度+字节）| direction int8 | price float32位 | volume int32
+// [| account（uvarint长度+字节，账号为空时省略）]
+const (
+	HEADER_SIZE     = 8
+	MAX_RECORD_SIZE = 1 << 16 // 单条记录payload上限，超过视为损坏
+
+	DEFAULT_SYNC_INTERVAL = 100 * time.Millisecond
+)
+
+// EventType 事件类型
+type EventType uint8
+
+const (
+	EventNew     EventType = iota + 1 // 新增订单
+	EventCancel                       // 撤单
+	EventAmend                        // 改单，Order中为修改后的价格和数量
+	EventOpen                         // 开放新的集合竞价时段
+	EventUncross                      // 撮合并关闭时段
+)
+
+// SyncPolicy 落盘策略
+type SyncPolicy int
+
+const (
+	SyncAlways   SyncPolicy = iota // 每条记录写入后fsync
+	SyncInterval                   // 后台按固定间隔fsync
+	SyncNever                      // 不主动fsync，交给操作系统
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type (
+	// Event 一条已接受的订单事件
+	Event struct {
+		Type    EventType
+		OrderID string
+		Owner   string
+		Order   order.Order
+	}
+
+	// Writer 只追加写入的预写日志
+	Writer struct {
+		file   *os.File
+		policy SyncPolicy
+		dirty  bool
+		done   chan struct{}
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+	}
+
+	// Recovery 恢复时读取和截断的情况
+	Recovery struct {
+		Events         []Event
+		ValidBytes     int64  // 有效记录的总字节数
+		TruncatedBytes int64  // 被截断的尾部字节数
+		Reason         string // 截断原因，未截断时为空
+	}
+)
+
+// ParseSyncPolicy 解析落盘策略名：always、interval、never
+func ParseSyncPolicy(name string) (SyncPolicy, error) {
+	switch name {
+	case "always":
+		return SyncAlways, nil
+	case "interval":
+		return SyncInterval, nil
+	case "never":
+		return SyncNever, nil
+	}
+	return 0, fmt.Errorf("无效的落盘策略: %s", name)
+}
+
+// encode 将事件编码为完整记录
+func (e Event) encode() []byte {
+	payload := make([]byte, 0, 64)
+	payload = append(payload, byte(e.Type))
+	for _, s := range []string{e.OrderID, e.Owner, e.Order.InstrumentID} {
+		payload = binary.AppendUvarint(payload, uint64(len(s)))
+		payload = append(payload, s...)
+	}
+	payload = append(payload, byte(e.Order.Direction))
+	payload = binary.LittleEndian.AppendUint32(payload, math.Float32bits(e.Order.Price))
+	payload = binary.LittleEndian.AppendUint32(payload, uint32(e.Order.Volume))
+	if e.Order.Account != "" {
+		payload = binary.AppendUvarint(payload, uint64(len(e.Order.Account)))
+		payload = append(payload, e.Order.Account...)
+	}
+
+	record := make([]byte, HEADER_SIZE, HEADER_SIZE+len(payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(payload, crcTable))
+	return append(record, payload...)
+}
+
+func decodePayload(payload []byte) (Event, error) {
+	var e Event
+	if len(payload) < 1 {
+		return e, errors.New("空记录")
+	}
+	e.Type = EventType(payload[0])
+	if e.Type < EventNew || e.Type > EventUncross {
+		return e, fmt.Errorf("未知事件类型: %d", payload[0])
+	}
+	payload = payload[1:]
+
+	strs := make([]string, 3)
+	for i := range strs {
+		n, size := binary.Uvarint(payload)
+		if size <= 0 || uint64(len(payload)-size) < n {
+			return e, errors.New("字符串字段越界")
+		}
+		strs[i] = string(payload[size : size+int(n)])
+		payload = payload[size+int(n):]
+	}
+	if len(payload) < 9 {
+		return e, errors.New("记录长度不符")
+	}
+	e.OrderID, e.Owner, e.Order.InstrumentID = strs[0], strs[1], strs[2]
+	e.Order.Direction = int8(payload[0])
+	e.Order.Price = math.Float32frombits(binary.LittleEndian.Uint32(payload[1:5]))
+	e.Order.Volume = int32(binary.LittleEndian.Uint32(payload[5:9]))
+
+	if payload = payload[9:]; len(payload) > 0 {
+		n, size := binary.Uvarint(payload)
+		if size <= 0 || n == 0 || uint64(len(payload)-size) != n {
+			return e, errors.New("记录长度不符")
+		}
+		e.Order.Account = string(payload[size:])
+	}
+	return e, nil
+}
+
+// Recover 读取日志中的全部有效记录；遇到不完整或校验失败的尾部记录时将文件截断到最后一条有效记录。
+// 文件不存在时返回空结果
+func Recover(path string) (Recovery, error) {
+	var recovery Recovery
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return recovery, nil
+	}
+	if err != nil {
+		return recovery, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return recovery, err
+	}
+
+	reader := bufio.NewReader(file)
+	header := make([]byte, HEADER_SIZE)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err != io.EOF {
+				recovery.Reason = "记录头不完整"
+			}
+			break
+		}
+		length := binary.LittleEndian.Uint32(header[0:4])
+		if length == 0 || length > MAX_RECORD_SIZE {
+			recovery.Reason = fmt.Sprintf("记录长度无效: %d", length)
+			break
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			recovery.Reason = "记录内容不完整"
+			break
+		}
+		if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+			recovery.Reason = "校验和不匹配"
+			break
+		}
+		event, err := decodePayload(payload)
+		if err != nil {
+			recovery.Reason = err.Error()
+			break
+		}
+		recovery.Events = append(recovery.Events, event)
+		recovery.ValidBytes += int64(HEADER_SIZE + length)
+	}
+
+	recovery.TruncatedBytes = info.Size() - recovery.ValidBytes
+	if recovery.TruncatedBytes > 0 {
+		if err := file.Truncate(recovery.ValidBytes); err != nil {
+			return recovery, err
+		}
+		if err := file.Sync(); err != nil {
+			return recovery, err
+		}
+	}
+	return recovery, nil
+}
+
+// OpenWriter 以追加方式打开日志，调用前应先Recover以截断损坏的尾部
+func OpenWriter(path string, policy SyncPolicy, interval time.Duration) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{file: file, policy: policy, done: make(chan struct{})}
+	if policy == SyncInterval {
+		if interval <= 0 {
+			interval = DEFAULT_SYNC_INTERVAL
+		}
+		w.wg.Add(1)
+		go w.syncLoop(interval)
+	}
+	return w, nil
+}
+
+// Append 追加一条事件，SyncAlways策略下返回时已落盘
+func (w *Writer) Append(e Event) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Write(e.encode()); err != nil {
+		return err
+	}
+	if w.policy == SyncAlways {
+		return w.file.Sync()
+	}
+	w.dirty = true
+	return nil
+}
+
+func (w *Writer) syncLoop(interval time.Duration) {
+	defer w.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.Sync()
+		}
+	}
+}
+
+// Sync 将已写入的记录落盘
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.dirty {
+		return nil
+	}
+	w.dirty = false
+	return w.file.Sync()
+}
+
+// Close 落盘并关闭日志
+func (w *Writer) Close() error {
+	close(w.done)
+	w.wg.Wait()
+	w.mu.Lock()
+	w.dirty = true
+	w.mu.Unlock()
+	if err := w.Sync(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,139 @@
+package journal
+
+import (
+	"AuctionMatch/order"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// randomEvents 生成随机的新增、撤单和改单事件，只保留能被时段接受的事件
+func randomEvents(rng *rand.Rand, session *order.CallSession, n int) []Event {
+	instruments := []string{"IF2412", "TS2412", "IC2501"}
+	events := make([]Event, 0, n)
+	live := make([]string, 0)
+
+	for i := 0; len(events) < n; i++ {
+		var e Event
+		switch {
+		case len(live) > 0 && rng.Intn(5) == 0:
+			e = Event{Type: EventCancel, OrderID: live[rng.Intn(len(live))]}
+		case len(live) > 0 && rng.Intn(5) == 0:
+			id := live[rng.Intn(len(live))]
+			o, _ := session.Lookup(id)
+			e = Event{Type: EventAmend, OrderID: id, Order: order.Order{
+				Price:  o.Price + float32(rng.Intn(3)-1)*o.GetTick(),
+				Volume: int32(1 + rng.Intn(10)),
+			}}
+		default:
+			instrumentID := instruments[rng.Intn(len(instruments))]
+			tick := (&order.Order{InstrumentID: instrumentID}).GetTick()
+			e = Event{Type: EventNew, OrderID: "o" + strconv.Itoa(i), Owner: "c1", Order: order.Order{
+				InstrumentID: instrumentID,
+				Direction:    int8(rng.Intn(2)),
+				Price:        order.ToFloat(int64(10000+rng.Intn(20)), tick),
+				Volume:       int32(1 + rng.Intn(10)),
+				Account:      []string{"", "A001", "B002"}[rng.Intn(3)],
+			}}
+		}
+		if err := Apply(session, e); err != nil {
+			continue
+		}
+		if e.Type == EventNew {
+			live = append(live, e.OrderID)
+		} else if e.Type == EventCancel {
+			for j, id := range live {
+				if id == e.OrderID {
+					live = append(live[:j], live[j+1:]...)
+					break
+				}
+			}
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestRecoverReproducesResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auction.wal")
+	for _, policy := range []SyncPolicy{SyncAlways, SyncInterval, SyncNever} {
+		os.Remove(path)
+		session := order.NewCallSession()
+		events := randomEvents(rand.New(rand.NewSource(int64(policy))), session, 500)
+
+		w, err := OpenWriter(path, policy, 0)
+		if err != nil {
+			t.Fatalf("OpenWriter() error = %v", err)
+		}
+		for _, e := range events {
+			if err := w.Append(e); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		recovery, err := Recover(path)
+		if err != nil || recovery.TruncatedBytes != 0 || !reflect.DeepEqual(recovery.Events, events) {
+			t.Fatalf("策略%d: Recover() 读到%d条事件, 截断%d字节, error = %v",
+				policy, len(recovery.Events), recovery.TruncatedBytes, err)
+		}
+		rebuilt, err := Rebuild(recovery.Events)
+		if err != nil {
+			t.Fatalf("Rebuild() error = %v", err)
+		}
+		if got, want := rebuilt.Results(), session.Results(); !reflect.DeepEqual(got, want) {
+			t.Errorf("策略%d: 恢复后结果 %+v, want %+v", policy, got, want)
+		}
+	}
+}
+
+func TestRecoverTruncatesCorruptTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auction.wal")
+	w, err := OpenWriter(path, SyncNever, 0)
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	events := []Event{
+		{Type: EventNew, OrderID: "b1", Owner: "c1", Order: order.Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3}},
+		{Type: EventNew, OrderID: "s1", Owner: "c1", Order: order.Order{InstrumentID: "IF2412", Direction: 1, Price: 3973.0, Volume: 2}},
+	}
+	for _, e := range events {
+		w.Append(e)
+	}
+	w.Close()
+	info, _ := os.Stat(path)
+	validSize := info.Size()
+
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+		want    int
+	}{
+		{"尾部写了一半", func(data []byte) []byte { return append(data, events[0].encode()[:10]...) }, 2},
+		{"最后一条校验失败", func(data []byte) []byte { data[len(data)-1] ^= 0xff; return data }, 1},
+	}
+	original, _ := os.ReadFile(path)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.corrupt(append([]byte(nil), original...))
+			os.WriteFile(path, data, 0644)
+
+			recovery, err := Recover(path)
+			if err != nil {
+				t.Fatalf("Recover() error = %v", err)
+			}
+			if len(recovery.Events) != tt.want || recovery.TruncatedBytes == 0 || recovery.Reason == "" {
+				t.Errorf("Recover() = %d条事件, 截断%d字节, 原因%q", len(recovery.Events), recovery.TruncatedBytes, recovery.Reason)
+			}
+			info, _ := os.Stat(path)
+			if info.Size() != recovery.ValidBytes || info.Size() > validSize {
+				t.Errorf("截断后文件大小 %d, 有效字节 %d", info.Size(), recovery.ValidBytes)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package journal
+
+import "AuctionMatch/order"
+
+// Apply 将事件应用到集合竞价时段
+func Apply(session *order.CallSession, e Event) error {
+	switch e.Type {
+	case EventNew:
+		return session.Submit(e.OrderID, e.Owner, e.Order)
+	case EventCancel:
+		return session.Cancel(e.OrderID)
+	case EventAmend:
+		return session.Amend(e.OrderID, e.Order.Price, e.Order.Volume)
+	case EventOpen:
+		session.Open()
+	case EventUncross:
+		session.Uncross()
+	}
+	return nil
+}
+
+// Rebuild 依次应用日志中的事件，以opts配置重建集合竞价时段
+func Rebuild(events []Event, opts ...order.SessionOption) (*order.CallSession, error) {
+	session := order.NewCallSession(opts...)
+	for _, e := range events {
+		if err := Apply(session, e); err != nil {
+			return session, err
+		}
+	}
+	return session, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"AuctionMatch/order"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// 日志输出格式
+const (
+	LOG_FORMAT_TEXT = "text"
+	LOG_FORMAT_JSON = "json"
+)
+
+// newLogger 创建写入w的结构化日志器，level为debug/info/warn/error，format为text/json
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("无效的日志级别: %s", level)
+	}
+
+	options := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case LOG_FORMAT_TEXT:
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case LOG_FORMAT_JSON:
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("无效的日志格式: %s", format)
+	}
+}
+
+// setupLogger 将标准错误上的结构化日志器设为默认，参数错误时提示并退出
+func setupLogger(level, format string) {
+	logger, err := newLogger(os.Stderr, level, format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "参数错误: %v！使用 -h 查看帮助信息\n", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+}
+
+// addLogFlags 为flag形式的子命令注册-log-level和-log-format，解析后调用返回的函数使其生效
+func addLogFlags(flags *flag.FlagSet) func() {
+	level := flags.String("log-level", "info", "日志级别: debug、info、warn、error")
+	format := flags.String("log-format", LOG_FORMAT_TEXT, "日志格式: text、json")
+	return func() {
+		setupLogger(*level, *format)
+	}
+}
+
+// logError 输出错误日志，订单行错误附带instrument、line和stage字段
+func logError(err error) {
+	var recordErr *order.RecordError
+	if errors.As(err, &recordErr) {
+		slog.Error("订单处理出错", "error", recordErr.Err, "instrument", recordErr.InstrumentID,
+			"line", recordErr.Line, "stage", recordErr.Stage)
+		return
+	}
+	slog.Error("处理出错", "error", err)
+}
+
+// fatal 输出错误日志后退出，args为附加的键值对字段
+func fatal(msg string, err error, args ...any) {
+	slog.Error(msg, append([]any{"error", err}, args...)...)
+	os.Exit(1)
+}
@@ -1,92 +1,383 @@
 package main
 
 import (
+	"AuctionMatch/instrument"
 	"AuctionMatch/order"
+	"AuctionMatch/risk"
 	"fmt"
+	"log/slog"
 	"os"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 )
 
-func checkArgs() {
+// cliArgs 命令行参数
+type cliArgs struct {
+	inputFile     string
+	outputFile    string
+	scaleMode     order.ScaleMode
+	stats         bool                      // 结束时向标准错误输出运行统计
+	implied       bool                      // 为两腿组合合约加入由单腿委托簿推导的隐含订单
+	stp           order.SelfTradePrevention // 按账号防范自成交并报告各合约被防范的量
+	riskFile      string                    // 风控规则文件
+	limitsFile    string                    // 持仓限额表，为空时不检查持仓限额
+	positionsFile string                    // 期初持仓文件
+	tradingDate   time.Time                 // 交易日，零值表示当天
+	calendarFile  string                    // 节假日文件，指定时拒绝已到期或尚未挂牌的合约
+	logLevel      string                    // 日志级别
+	logFormat     string                    // 日志格式
+	options       map[string]string         // 子命令额外支持的带值参数
+}
+
+// argSpec 命令支持的参数。读取输入文件的子命令使用parseArgs而不是flag.FlagSet，
+// 因为输入文件写在选项之前（如chart orders.csv -o dir），flag包遇到第一个非选项参数就会停止解析；
+// 与flag.FlagSet一样，命令不使用的选项视为错误，而不是静默忽略
+type argSpec struct {
+	options []string // 使用的通用选项，-log-level和-log-format总是支持
+	extra   []string // 子命令额外支持的带值参数，取值保存在cliArgs.options中
+}
+
+// MAIN_ARGS 不带子命令时支持的参数
+var MAIN_ARGS = argSpec{options: []string{
+	"-o", "-scale", "-stats", "-implied", "-stp", "-risk", "-limits", "-positions", "-trading-date", "-calendar",
+}}
+
+// allows 判断命令是否支持该选项
+func (spec argSpec) allows(option string) bool {
+	return option == "-log-level" || option == "-log-format" ||
+		slices.Contains(spec.options, option) || slices.Contains(spec.extra, option)
+}
+
+// parseArgs 按spec解析命令行参数，参数错误或使用了命令不支持的选项时返回error
+func parseArgs(args []string, spec argSpec) (cliArgs, error) {
+	parsed := cliArgs{logLevel: "info", logFormat: LOG_FORMAT_TEXT, options: make(map[string]string)}
+	for i := 0; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "-") && !spec.allows(args[i]) {
+			return parsed, fmt.Errorf("不支持的参数: %s", args[i])
+		}
+		if slices.Contains(spec.extra, args[i]) {
+			if i+1 >= len(args) {
+				return parsed, fmt.Errorf("%s 缺少取值", args[i])
+			}
+			parsed.options[args[i]] = args[i+1]
+			i++
+			continue
+		}
+		switch args[i] {
+		case "-o":
+			if i+1 >= len(args) {
+				return parsed, fmt.Errorf("-o 缺少输出文件")
+			}
+			i++
+			parsed.outputFile = args[i]
+		case "-stats":
+			parsed.stats = true
+		case "-implied":
+			parsed.implied = true
+		case "-stp":
+			if i+1 >= len(args) {
+				return parsed, fmt.Errorf("-stp 缺少取值")
+			}
+			i++
+			stp, err := order.ParseSelfTradePrevention(args[i])
+			if err != nil {
+				return parsed, err
+			}
+			parsed.stp = stp
+		case "-risk", "-limits", "-positions", "-calendar":
+			if i+1 >= len(args) {
+				return parsed, fmt.Errorf("%s 缺少文件", args[i])
+			}
+			switch args[i] {
+			case "-risk":
+				parsed.riskFile = args[i+1]
+			case "-limits":
+				parsed.limitsFile = args[i+1]
+			case "-calendar":
+				parsed.calendarFile = args[i+1]
+			default:
+				parsed.positionsFile = args[i+1]
+			}
+			i++
+		case "-trading-date":
+			if i+1 >= len(args) {
+				return parsed, fmt.Errorf("-trading-date 缺少取值")
+			}
+			i++
+			date, err := time.ParseInLocation(instrument.DATE_LAYOUT, args[i], time.Local)
+			if err != nil {
+				return parsed, fmt.Errorf("无效的交易日: %s", args[i])
+			}
+			parsed.tradingDate = date
+		case "-log-level", "-log-format":
+			if i+1 >= len(args) {
+				return parsed, fmt.Errorf("%s 缺少取值", args[i])
+			}
+			if args[i] == "-log-level" {
+				parsed.logLevel = args[i+1]
+			} else {
+				parsed.logFormat = args[i+1]
+			}
+			i++
+		case "-scale":
+			if i+1 >= len(args) {
+				return parsed, fmt.Errorf("-scale 缺少取值")
+			}
+			i++
+			switch args[i] {
+			case "tick":
+				parsed.scaleMode = order.ScaleByTick
+			case "input":
+				parsed.scaleMode = order.ScaleByInput
+			default:
+				return parsed, fmt.Errorf("无效的-scale取值: %s", args[i])
+			}
+		default:
+			if parsed.inputFile != "" {
+				return parsed, fmt.Errorf("多余的参数: %s", args[i])
+			}
+			parsed.inputFile = args[i]
+		}
+	}
+	if parsed.inputFile == "" {
+		return parsed, fmt.Errorf("缺少输入文件")
+	}
+	if parsed.positionsFile != "" && parsed.limitsFile == "" {
+		return parsed, fmt.Errorf("-positions 需要同时指定 -limits")
+	}
+	return parsed, nil
+}
+
+// subcommands 子命令，参数为子命令之后的命令行参数
+var subcommands = map[string]func(argv []string){
+	"curve":      runCurve,
+	"chart":      runChart,
+	"indicative": runIndicative,
+	"serve":      runServe,
+	"tcp":        runTCP,
+	"fix":        runFIX,
+	"fixgw":      runFIXGateway,
+	"journal":    runJournal,
+	"snapshot":   runSnapshot,
+	"replay":     runReplay,
+	"benchcmp":   runBenchCompare,
+	"gen":        runGen,
+}
+
+func checkArgs() cliArgs {
 	if len(os.Args) == 2 && os.Args[1] == "-h" {
 		printUsage()
-		return
+		os.Exit(0)
 	}
 
-	if len(os.Args) < 2 {
-		fmt.Println("参数错误！使用 -h 查看帮助信息")
-		return
+	return mustParseArgs(os.Args[1:], MAIN_ARGS)
+}
+
+// mustParseArgs 解析参数并按参数设置日志和合约表的交易日，出错时打印提示并退出
+func mustParseArgs(argv []string, spec argSpec) cliArgs {
+	args, err := parseArgs(argv, spec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "参数错误: %v！使用 -h 查看帮助信息\n", err)
+		os.Exit(2)
+	}
+	setupLogger(args.logLevel, args.logFormat)
+	instrument.Default.SetTradingDate(args.tradingDate)
+	return args
+}
+
+// setupCalendar 按-calendar加载节假日文件并设置合约表的交易日历，返回是否需要检查合约可交易
+func setupCalendar(args cliArgs) bool {
+	if args.calendarFile == "" {
+		return false
+	}
+	calendar, err := instrument.LoadCalendar(args.calendarFile)
+	if err != nil {
+		fatal("加载节假日文件失败", err, "path", args.calendarFile)
+	}
+	instrument.Default.SetCalendar(calendar)
+	return true
+}
+
+// processorOptions 按命令行参数构造订单处理器的选项，同时返回风控检查器（未指定风控时为nil），
+// 风控检查器带有持仓等状态，每次处理都应重新构造
+func processorOptions(args cliArgs) ([]order.ProcessorOption, *risk.Checker) {
+	opts := []order.ProcessorOption{order.WithScaleMode(args.scaleMode)}
+	if args.implied {
+		opts = append(opts, order.WithImpliedSpreads())
+	}
+	if args.stp != order.AllowSelfTrade {
+		opts = append(opts, order.WithSelfTradeCheck(args.stp))
+	}
+	if setupCalendar(args) {
+		opts = append(opts, order.WithTradableCheck())
 	}
+	checker := newRiskChecker(args)
+	if checker != nil {
+		opts = append(opts, order.WithPreTradeCheck(checker.Check))
+	}
+	return opts, checker
 }
 
 func printUsage() {
 	fmt.Println("集合竞价撮合程序")
 	fmt.Println("\n用法:")
-	fmt.Println("  ./auctionMatch <input.csv> -o <output.csv>")
+	fmt.Println("  ./auctionMatch <input.csv> [-o <output.csv>] [-scale tick|input] [-risk <rules.csv>]")
+	fmt.Println("                [-limits <limits.csv> [-positions <positions.csv>] [-trading-date YYYYMMDD]]")
+	fmt.Println("                [-calendar <holidays.csv>] [-implied] [-stp none|cancel-newest|cancel-oldest|decrement-both|cancel-both] [-stats]")
+	fmt.Println("  ./auctionMatch curve <input.csv> [-o <curve.csv>] [-scale tick|input]")
+	fmt.Println("  ./auctionMatch chart <input.csv> [-o <dir>] [-scale tick|input]")
+	fmt.Println("  ./auctionMatch indicative <input.csv> [-o <series.csv>] [-scale tick|input] [-snapshot-in <snap>] [-snapshot-out <snap>]")
+	fmt.Println("  ./auctionMatch serve [-addr :8080] [-max-body <bytes>] [-refdata <products.csv>] [-snapshot <snap>]")
+	fmt.Println("  ./auctionMatch tcp [-addr :9000] [-refdata <products.csv>] [-journal <wal>] [-fsync always|interval|never] [-stp none|cancel-newest|cancel-oldest|decrement-both|cancel-both]")
+	fmt.Println("                [-admin-token <token>]")
+	fmt.Println("  ./auctionMatch fix <fix.log> [-o <output.csv>]")
+	fmt.Println("  ./auctionMatch fixgw [-addr :9878] [-comp-id AUCTION] [-stp none|cancel-newest|cancel-oldest|decrement-both|cancel-both]")
+	fmt.Println("  ./auctionMatch journal <wal> [-o <output.csv>]")
+	fmt.Println("  ./auctionMatch snapshot <snap> [-o <output.csv>]")
+	fmt.Println("  ./auctionMatch replay <input.csv> [-cutoff 08:58:30,08:59:00] [-every 30s] [-o <output.csv>] [-scale tick|input]")
+	fmt.Println("  ./auctionMatch gen [-n 100000] [-seed 1] [-products IF,T] [-price IF=3900] [-months 2] [-skew 1.3] [-malformed 0.01] [-o <orders.csv>]")
+	fmt.Println("  ./auctionMatch benchcmp [-threshold 10] <old.txt> <new.txt>")
 	fmt.Println("  ./auctionMatch -h")
 	fmt.Println("\n参数:")
-	fmt.Println("  input.csv    输入的订单CSV文件")
+	fmt.Println("  input.csv    输入的订单CSV文件，格式为instrumentID,direction,price,volume[,HH:MM:SS[.fff][,account]]；")
+	fmt.Println("               期权合约按各交易所格式书写，例如IO2412-C-3900、m2409-P-3000、SR501C5000；")
+	fmt.Println("               组合合约写作\"<类型> <腿1>&<腿2>\"（例如SP m2409&m2501），价格为两腿价差，可以为负")
 	fmt.Println("  output.csv   输出的结果CSV文件")
+	fmt.Println("  -scale      输出价格精度: tick按合约tick（默认），input按输入中出现的最大小数位数")
+	fmt.Println("  -log-level  日志级别: debug、info（默认）、warn、error，日志输出到标准错误")
+	fmt.Println("  -log-format 日志格式: text（默认）、json")
+	fmt.Println("  -risk       风控规则文件，每行为rule,account,scope,limit[,window]，rule为max_order_volume、max_position、")
+	fmt.Println("              max_notional或order_rate；未通过的订单不参与撮合，结束时向标准错误输出各账号的风控汇总")
+	fmt.Println("  -limits     持仓限额表，每行为product,spec|hedge,一般月份,交割月前一个月,交割月份，留空表示不限；")
+	fmt.Println("              按期初持仓加同方向委托全部成交后的单边持仓检查")
+	fmt.Println("  -positions  期初持仓，每行为account,instrumentID,long,short[,spec|hedge]")
+	fmt.Println("  -trading-date 交易日（YYYYMMDD，默认当天），用于判断合约所处的交割月阶段、是否到期或挂牌，以及郑商所3位月份合约的年份")
+	fmt.Println("  -calendar   节假日文件，每行为YYYYMMDD[,说明]，周末不必列出；指定时按交易日拒绝已过最后交易日或尚未挂牌的合约，")
+	fmt.Println("              最后交易日按品种规则计算（如股指期货为合约月份第三个周五，遇节假日顺延），空文件表示只排除周末；")
+	fmt.Println("              目前只内置中金所股指期货、股指期权和国债期货的规则，其他品种不检查")
+	fmt.Println("  -implied    由两腿的委托簿推导组合合约的隐含买卖单（最优档位），与组合合约的订单一起集合竞价；")
+	fmt.Println("              隐含单只用于指示组合合约的价格，不从单腿中扣除，单腿的结果不受影响")
+	fmt.Println("  -stp        同一账号的买卖单在分配成交时相遇的自成交防范方式，默认none；成交价不变，")
+	fmt.Println("              按输入顺序逐笔分配成交，在日志中报告各合约被防范的量")
+	fmt.Println("  -stats      结束时向标准错误输出运行统计（读取行数、拒绝原因、各阶段耗时等），")
+	fmt.Println("              以及前20个合约各自的订单数、分价表档位数和计算耗时")
 	fmt.Println("  -h          显示帮助信息")
+	fmt.Println("\n子命令:")
+	fmt.Println("  curve       输出各合约完整分价表，每个价格档位一行，chosen=1为集合竞价价格")
+	fmt.Println("  chart       为每个合约输出累计买卖曲线SVG图，-o 指定输出目录")
+	fmt.Println("  indicative  逐笔回放订单，输出每个事件后的指示性价格、成交量和不平衡量；volume为负数表示撤单")
+	fmt.Println("  serve       启动HTTP服务: POST /v1/auction 撮合CSV或JSON订单，GET/POST /v1/refdata 查询或上传参考数据，GET /healthz 健康检查，")
+	fmt.Println("              GET /metrics 运行指标（Prometheus文本格式），")
+	fmt.Println("              GET/POST /v1/books 查询或追加聚合委托簿，GET/PUT /v1/books/snapshot 下载或恢复快照")
+	fmt.Println("  tcp         启动长连接集合竞价仿真服务，行协议支持NEW/CXL/AMD/SUB/OPEN/UNCROSS，")
+	fmt.Println("              OPEN和UNCROSS须先以AUTH <token>认证为管理连接，见server/tcp.go")
+	fmt.Println("  fix         读取FIX 4.4日志中的NewOrderSingle(35=D)和OrderCancelRequest(35=F)并撮合")
+	fmt.Println("  fixgw       启动FIX 4.4报单网关，标准输入中的UNCROSS触发撮合并推送ExecutionReport")
+	fmt.Println("  journal     从预写日志恢复集合竞价时段（截断损坏的尾部记录）并输出各合约价格")
+	fmt.Println("  snapshot    从聚合委托簿快照恢复并输出各合约价格")
+	fmt.Println("  replay      按时间戳回放订单，输出集合竞价在各截止时刻结束时的结果")
+	fmt.Println("  gen         按参考数据生成模拟订单：合约热度偏斜，价格围绕参考价分布且在tick上，数量不超过最大下单数量")
+	fmt.Println("  benchcmp    对比两次go test -bench的输出，耗时或分配次数增幅超过阈值（百分比）时返回1，见scripts/bench.sh")
 	fmt.Println("\n示例:")
 	fmt.Println("  ./auctionMatch orders.csv -o results.csv")
 }
 
 // writeResults 将结果写入标准输出
 func writeResults(results []order.ProcessResult, outputFile string) {
-	var output strings.Builder
-
 	// 处理空结果的情况
 	if len(results) == 0 {
 		return
 	}
+	writeOutput(formatResults(results), outputFile)
+}
 
-	// 构建输出字符串
-	for _, item := range results {
-		if item.Price == 0 {
-			output.WriteString(fmt.Sprintf("%s,\n", item.InstrumentID))
-		} else {
-			// 与输入精度保持一致
-			output.WriteString(fmt.Sprintf("%s,%.*f\n", item.InstrumentID, item.Scale, item.Price))
+// logPrevented 在日志中报告各合约被自成交防范的量
+func logPrevented(results []order.ProcessResult) {
+	for _, result := range results {
+		if result.PreventedVolume > 0 {
+			slog.Info("已防范自成交", "instrument", result.InstrumentID, "volume", result.PreventedVolume)
 		}
 	}
+}
 
-	// 写入输出
-	outputStr := output.String()
+// formatResults 每个合约输出一行"合约,价格"
+func formatResults(results []order.ProcessResult) string {
+	var output strings.Builder
+	for _, item := range results {
+		output.WriteString(item.InstrumentID + "," + item.FormatPrice() + "\n")
+	}
+	return output.String()
+}
+
+// writeOutput 输出到标准输出或文件，写文件时使用CRLF换行
+func writeOutput(outputStr string, outputFile string) {
 	if outputFile == "" {
 		fmt.Print(outputStr)
 	} else {
 		// 确保使用UTF-8编码并保持原有的换行符
 		if err := os.WriteFile(outputFile, []byte(strings.ReplaceAll(outputStr, "\n", "\r\n")), 0644); err != nil {
-			fmt.Printf("写入结果时发生错误: %v\n", err)
-			os.Exit(1)
+			fatal("写入结果时发生错误", err)
+		}
+	}
+}
+
+// logErrors 在后台将订单流中的错误输出到日志。返回的函数在订单流处理完毕后调用，
+// 等待读取结束并输出全部错误，输入无法读取时同样输出错误日志
+func logErrors(stream *order.OrderStream) func() {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range stream.Error {
+			logError(err)
+		}
+	}()
+
+	return func() {
+		<-stream.Done
+		// 处理方和读取协程均已退出，不会再有错误写入
+		close(stream.Error)
+		<-done
+		if stream.Err != nil {
+			slog.Error("读取输入失败", "stage", order.STAGE_INGEST, "error", stream.Err)
 		}
 	}
 }
 
 func main() {
-	checkArgs()
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
+	args := checkArgs()
+	start := time.Now()
+	if args.stats {
+		order.EnableInstrumentMetrics(STATS_INSTRUMENTS)
+	}
 	// 创建订单流
-	stream := order.StreamOrders(os.Args[1])
+	stream := order.StreamOrders(args.inputFile)
 
-	processor := order.NewOrderProcessor(runtime.NumCPU())
+	opts, checker := processorOptions(args)
+	processor := order.NewOrderProcessor(runtime.NumCPU(), opts...)
 
 	// 处理错误
-	go func() {
-		for err := range stream.Error {
-			fmt.Println(err)
-		}
-	}()
+	waitErrors := logErrors(stream)
 
 	// 等待所有数据处理完成
 	results := processor.Process(stream)
-	<-stream.Done
+	waitErrors()
 
 	// 输出结果
-	if len(os.Args) == 4 && os.Args[2] == "-o" {
-		writeResults(results, os.Args[3])
-	} else {
-		writeResults(results, "")
+	writeResults(results, args.outputFile)
+	logPrevented(results)
+	if checker != nil {
+		printRiskSummary(os.Stderr, checker)
+	}
+	if args.stats {
+		printStats(os.Stderr, time.Since(start))
 	}
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"AuctionMatch/gen"
+	"AuctionMatch/instrument"
+	"AuctionMatch/internal/golden"
 	"AuctionMatch/order"
 	"AuctionMatch/utils"
 	"bytes"
@@ -10,7 +13,6 @@ import (
 	"runtime"
 	"strings"
 	"testing"
-	"time"
 )
 
 // TestCalculateAuctionPrice 测试价格计算逻辑
@@ -165,35 +167,17 @@ func orderEqual(a, b order.Order) bool {
 		a.Volume == b.Volume
 }
 
-// generateLargeTestData 生成大批量测试数据
+// generateLargeTestData 用gen生成大批量测试数据
 func generateLargeTestData(numOrders int) string {
+	config := gen.DefaultConfig()
+	config.Orders = numOrders
 	var buffer bytes.Buffer
-	instruments := []string{"IF2412", "IF2306", "IF2403", "IF2406"}
-
-	for i := 0; i < numOrders; i++ {
-		// 随机选择合约
-		instrument := instruments[i%len(instruments)]
-		// 随机生成买卖方向 (0或1)
-		direction := i % 2
-		// 生成基准价格 3900.0 到 4000.0 之间的随机价格
-		basePrice := 3900.0 + float64(i%100)
-		// 添加随机小数位
-		price := basePrice + float64(i%10)*0.2
-		// 生成 1-10 之间的随机数量
-		volume := 1 + (i % 10)
-
-		buffer.WriteString(fmt.Sprintf("%s,%d,%.1f,%d\n",
-			instrument, direction, price, volume))
+	if err := gen.Generate(&buffer, config); err != nil {
+		panic(err)
 	}
 	return buffer.String()
 }
 
-func TestGenerateLargeTestData(t *testing.T) {
-	data := generateLargeTestData(1000000)
-	// write to file
-	os.WriteFile("large_test_data.csv", []byte(data), 0644)
-}
-
 // TestStreamOrders 测试流式读取订单
 func TestStreamOrders(t *testing.T) {
 	// 创建测试数据
@@ -247,142 +231,142 @@ IF2306,1,3972.0,1`
 	}
 }
 
-// TestLargeScaleAuctionMatch 压力测试
-func TestLargeScaleAuctionMatch(t *testing.T) {
-	tests := []struct {
+// BenchmarkLargeScaleAuctionMatch 从文件读取并撮合的端到端基准，不同规模的输入写入临时目录
+func BenchmarkLargeScaleAuctionMatch(b *testing.B) {
+	sizes := []struct {
 		name      string
 		numOrders int
 	}{
-		{"1000个订单测试", 1000},
-		{"100000个订单测试", 100000},
-		{"1000000个订单测试", 1000000},
+		{"orders=1000", 1000},
+		{"orders=100000", 100000},
+		{"orders=1000000", 1000000},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// 生成测试数据
-			inputContent := generateLargeTestData(tt.numOrders)
-
-			// 创建临时测试文件
-			tmpDir := t.TempDir()
-			inputFile := filepath.Join(tmpDir, "large_input.csv")
-			err := os.WriteFile(inputFile, []byte(inputContent), 0644)
-			if err != nil {
-				t.Fatalf("创建大规模测试文件失败: %v", err)
+	for _, size := range sizes {
+		b.Run(size.name, func(b *testing.B) {
+			inputContent := generateLargeTestData(size.numOrders)
+			inputFile := filepath.Join(b.TempDir(), "large_input.csv")
+			if err := os.WriteFile(inputFile, []byte(inputContent), 0644); err != nil {
+				b.Fatalf("创建大规模测试文件失败: %v", err)
 			}
-
-			// 记录初始内存状态
-			var initialMemStats, finalMemStats runtime.MemStats
-			runtime.ReadMemStats(&initialMemStats)
-
-			// 记录开始时间
-			start := time.Now()
-			// 启动流式处理
-			stream := order.StreamOrders(inputFile)
-			// 创建合适的处理器
 			processor := order.NewOrderProcessor(runtime.NumCPU())
 
-			// 处理错误
-			go func() {
-				for err := range stream.Error {
-					fmt.Println(err)
-				}
-			}()
-
-			// 等待所有数据处理完成
-			results := processor.Process(stream)
-			<-stream.Done
-			t.Logf("处理结果长度: %v", len(results))
+			b.SetBytes(int64(len(inputContent)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				stream := order.StreamOrders(inputFile)
+				go func() {
+					for range stream.Error {
+					}
+				}()
+				processor.Process(stream)
+				<-stream.Done
+				close(stream.Error)
+			}
+		})
+	}
+}
 
-			// 记录最终内存状态
-			runtime.ReadMemStats(&finalMemStats)
+// TestGolden 对testdata/golden中的每个输入文件运行订单处理，结果与同名的.golden文件比较。
+// 同名的.args文件可指定命令行选项（如-scale input、-risk、-limits）。重新生成期望输出：go test -run TestGolden -update
+func TestGolden(t *testing.T) {
+	tradingDate, calendar := instrument.Default.TradingDate(), instrument.Default.Calendar()
+	t.Cleanup(func() {
+		instrument.Default.SetTradingDate(tradingDate)
+		instrument.Default.SetCalendar(calendar)
+	})
 
-			duration := time.Since(start)
-			allocatedMemory := float64(finalMemStats.TotalAlloc-initialMemStats.TotalAlloc) / 1024 / 1024
-			t.Logf("处理 %d 个订单耗时: %v", tt.numOrders, duration)
-			t.Logf("内存分配: %.2f MB", allocatedMemory)
+	for _, c := range golden.Discover(t, "testdata/golden", "*.csv") {
+		t.Run(c.Name, func(t *testing.T) {
+			argv := []string{c.Input}
+			if options, err := os.ReadFile(strings.TrimSuffix(c.Input, ".csv") + ".args"); err == nil {
+				argv = append(argv, strings.Fields(string(options))...)
+			}
+			args, err := parseArgs(argv, MAIN_ARGS)
+			if err != nil {
+				t.Fatalf("parseArgs(%v) error = %v", argv, err)
+			}
+			instrument.Default.SetTradingDate(args.tradingDate)
+			instrument.Default.SetCalendar(nil)
+
+			for _, numCPU := range []int{1, runtime.NumCPU()} {
+				opts, _ := processorOptions(args)
+				stream := order.StreamOrders(args.inputFile)
+				go func() {
+					for range stream.Error {
+					}
+				}()
+				results := order.NewOrderProcessor(numCPU, opts...).Process(stream)
+				<-stream.Done
+				close(stream.Error)
+				if stream.Err != nil {
+					t.Fatalf("读取 %s 失败: %v", c.Input, stream.Err)
+				}
+				golden.Assert(t, c.Expected, formatResults(results))
+			}
 		})
 	}
 }
 
-func TestExample(t *testing.T) {
-	testFiles := []string{
-		"input/example1_normal_small.csv",
-		"input/example2_new_decision_algorithm_in_email.csv",
-		"input/example3_only_sells.csv",
-		"input/example5_long_time.csv",
+// TestParseArgs 测试各命令只接受自己使用的选项
+func TestParseArgs(t *testing.T) {
+	chartArgs := argSpec{options: []string{"-o", "-scale"}}
+	replayArgs := argSpec{options: []string{"-o"}, extra: []string{"-cutoff"}}
+	tests := []struct {
+		name    string
+		argv    []string
+		spec    argSpec
+		wantErr bool
+	}{
+		{name: "主命令全部选项", argv: []string{"in.csv", "-implied", "-risk", "r.csv", "-calendar", "h.csv", "-log-level", "debug"}, spec: MAIN_ARGS},
+		{name: "子命令使用的选项", argv: []string{"in.csv", "-o", "out", "-scale", "input", "-log-format", "json"}, spec: chartArgs},
+		{name: "子命令额外参数", argv: []string{"in.csv", "-cutoff", "08:59:00"}, spec: replayArgs},
+		{name: "子命令不使用-risk", argv: []string{"in.csv", "-risk", "r.csv"}, spec: chartArgs, wantErr: true},
+		{name: "子命令不使用-implied", argv: []string{"in.csv", "-implied"}, spec: chartArgs, wantErr: true},
+		{name: "子命令不使用-calendar", argv: []string{"in.csv", "-calendar", "h.csv"}, spec: replayArgs, wantErr: true},
+		{name: "子命令不使用-limits", argv: []string{"in.csv", "-limits", "l.csv"}, spec: replayArgs, wantErr: true},
+		{name: "主命令不支持子命令参数", argv: []string{"in.csv", "-cutoff", "08:59:00"}, spec: MAIN_ARGS, wantErr: true},
+		{name: "主命令-stp", argv: []string{"in.csv", "-stp", "cancel-both"}, spec: MAIN_ARGS},
+		{name: "无效的-stp", argv: []string{"in.csv", "-stp", "cancel-all"}, spec: MAIN_ARGS, wantErr: true},
+		{name: "子命令不使用-stp", argv: []string{"in.csv", "-stp", "cancel-both"}, spec: chartArgs, wantErr: true},
+		{name: "未知选项", argv: []string{"in.csv", "-verbose"}, spec: MAIN_ARGS, wantErr: true},
 	}
 
-	testOutputs := []string{
-		"output/example1_output.csv",
-		"output/example2_output.csv",
-		"output/example3_output.csv",
-		"output/example5_output.csv",
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseArgs(tt.argv, tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseArgs(%v) error = %v, wantErr %v", tt.argv, err, tt.wantErr)
+			}
+		})
 	}
+}
 
-	for index, tt := range testFiles {
-		t.Run(tt, func(t *testing.T) {
-			// 捕获标准输出
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			// 运行主程序
-			os.Args = []string{"cmd", tt}
-			// 记录开始时间
-			start := time.Now()
-			main()
-			duration := time.Since(start)
-			t.Logf("测试文件 %s 集合竞价耗时: %vms", tt, duration.Milliseconds())
-
-			// 恢复标准输出
-			w.Close()
-			os.Stdout = oldStdout
-			var buf bytes.Buffer
-			buf.ReadFrom(r)
-			output := buf.String()
-
-			// 比较输出和预期输出文件内容是否一致
-			expectedOutput, _ := os.ReadFile(testOutputs[index])
-			expectedStr := string(expectedOutput)
-			if output != expectedStr {
-				// 按行分割进行比较
-				expectedLines := strings.Split(expectedStr, "\n")
-				actualLines := strings.Split(output, "\n")
-
-				t.Errorf("输出不匹配:\n期望输出长度: %d, 实际输出长度: %d", len(expectedStr), len(output))
-
-				// 找出第一个不匹配的行
-				minLen := len(expectedLines)
-				if len(actualLines) < minLen {
-					minLen = len(actualLines)
-				}
+// TestNewLogger 测试日志级别、格式和订单行错误的上下文字段
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "warn", "json")
+	if err != nil {
+		t.Fatalf("newLogger() error = %v", err)
+	}
+	logger.Info("不应输出")
+	logger.Error("订单处理出错", "instrument", "IF2412", "line", 3, "stage", order.STAGE_PARSE)
 
-				for i := 0; i < minLen; i++ {
-					if expectedLines[i] != actualLines[i] {
-						t.Errorf("\n第 %d 行不匹配:\n期望: [%s]\n实际: [%s]", i+1, expectedLines[i], actualLines[i])
-						// 如果是数值不匹配，尝试解析并比较
-						expectedParts := strings.Split(expectedLines[i], ",")
-						actualParts := strings.Split(actualLines[i], ",")
-						if len(expectedParts) == 2 && len(actualParts) == 2 {
-							t.Errorf("价格比较:\n期望价格: [%s]\n实际价格: [%s]",
-								expectedParts[1], actualParts[1])
-						}
-						break
-					}
-				}
+	output := buf.String()
+	for _, want := range []string{`"level":"ERROR"`, `"instrument":"IF2412"`, `"line":3`, `"stage":"parse"`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("日志缺少 %s: %s", want, output)
+		}
+	}
+	if strings.Contains(output, "不应输出") {
+		t.Errorf("低于warn级别的日志不应输出: %s", output)
+	}
 
-				// 如果行数不同，显示多出或缺少的行
-				if len(expectedLines) != len(actualLines) {
-					t.Errorf("\n行数不匹配:\n期望行数: %d\n实际行数: %d",
-						len(expectedLines), len(actualLines))
-					if len(expectedLines) > len(actualLines) {
-						t.Errorf("缺少的行:\n%s", strings.Join(expectedLines[len(actualLines):], "\n"))
-					} else {
-						t.Errorf("多出的行:\n%s", strings.Join(actualLines[len(expectedLines):], "\n"))
-					}
-				}
-			}
-		})
+	if _, err := newLogger(&buf, "loud", "text"); err == nil {
+		t.Error("无效的日志级别应返回错误")
+	}
+	if _, err := newLogger(&buf, "info", "xml"); err == nil {
+		t.Error("无效的日志格式应返回错误")
 	}
 }
@@ -0,0 +1,387 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// Registry 指标注册表，按注册顺序以Prometheus文本格式输出
+	Registry struct {
+		metrics []metric
+		mu      sync.Mutex
+	}
+
+	metric interface {
+		write(w io.Writer)
+	}
+
+	// Counter 只增不减的计数器，可带标签
+	Counter struct {
+		name   string
+		help   string
+		labels []string
+		values map[string]*counterValue
+		mu     sync.Mutex
+	}
+
+	counterValue struct {
+		labelValues []string
+		value       float64
+	}
+
+	// Histogram 按上界累计的直方图，可带标签
+	Histogram struct {
+		name    string
+		help    string
+		labels  []string
+		buckets []float64 // 升序排列的桶上界，不含+Inf
+		values  map[string]*histogramValue
+		mu      sync.Mutex
+	}
+
+	histogramValue struct {
+		labelValues []string
+		counts      []uint64 // 各桶内（非累计）的观测数，最后一个为+Inf
+		count       uint64
+		sum         float64
+	}
+
+	// HistogramBatch 直方图的本地观测缓冲，观测时不加锁，Flush时一次合并到直方图；不可并发使用
+	HistogramBatch struct {
+		histogram *Histogram
+		values    map[string]*histogramValue
+	}
+
+	// LabelCap 限制标签的取值个数：最先出现的limit个取值原样保留，其余归入OTHER_LABEL，避免指标序列无限增长
+	LabelCap struct {
+		limit int
+		seen  map[string]struct{}
+		mu    sync.Mutex
+	}
+)
+
+// OTHER_LABEL 超出LabelCap上限的标签取值
+const OTHER_LABEL = "other"
+
+// CONTENT_TYPE Prometheus文本格式的Content-Type
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// DURATION_BUCKETS 耗时直方图的默认桶上界（秒）
+	DURATION_BUCKETS = []float64{0.00001, 0.0001, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+	// SIZE_BUCKETS 数量直方图的默认桶上界
+	SIZE_BUCKETS = []float64{1, 10, 100, 1000, 10000, 100000, 1000000}
+)
+
+// Default 进程内默认的指标注册表
+var Default = NewRegistry()
+
+// NewRegistry 创建空的指标注册表
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewCounter 在默认注册表中注册计数器
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// NewHistogram 在默认注册表中注册直方图
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+// NewCounter 注册计数器
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: labels, values: make(map[string]*counterValue)}
+	r.register(c)
+	return c
+}
+
+// NewHistogram 注册直方图，buckets为升序排列的桶上界
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogramValue)}
+	r.register(h)
+	return h
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	r.metrics = append(r.metrics, m)
+	r.mu.Unlock()
+}
+
+// WriteText 以Prometheus文本格式输出全部指标
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// ServeHTTP 实现/metrics接口
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", CONTENT_TYPE)
+	r.WriteText(w)
+}
+
+// Add 按标签值增加计数，delta不能为负
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("计数器%s不能减少", c.name))
+	}
+	c.mu.Lock()
+	c.value(labelValues).value += delta
+	c.mu.Unlock()
+}
+
+// Inc 按标签值计数加一
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Value 返回标签值对应的当前计数
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, ok := c.values[labelKey(labelValues)]; ok {
+		return v.value
+	}
+	return 0
+}
+
+// Each 按标签值排序遍历全部计数
+func (c *Counter) Each(fn func(labelValues []string, value float64)) {
+	c.mu.Lock()
+	values := make([]counterValue, 0, len(c.values))
+	for _, v := range sortedValues(c.values) {
+		values = append(values, *v)
+	}
+	c.mu.Unlock()
+
+	for _, v := range values {
+		fn(v.labelValues, v.value)
+	}
+}
+
+func (c *Counter) value(labelValues []string) *counterValue {
+	key := labelKey(labelValues)
+	v, ok := c.values[key]
+	if !ok {
+		checkLabels(c.name, c.labels, labelValues)
+		v = &counterValue{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = v
+	}
+	return v
+}
+
+func (c *Counter) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, escapeHelp(c.help), c.name)
+	c.Each(func(labelValues []string, value float64) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, labelValues, "", ""), formatValue(value))
+	})
+}
+
+// Observe 按标签值记录一次观测
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	v := h.value(labelValues)
+	v.counts[sort.SearchFloat64s(h.buckets, value)]++
+	v.count++
+	v.sum += value
+	h.mu.Unlock()
+}
+
+// Count 返回标签值对应的观测次数和观测值之和
+func (h *Histogram) Count(labelValues ...string) (count uint64, sum float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if v, ok := h.values[labelKey(labelValues)]; ok {
+		return v.count, v.sum
+	}
+	return 0, 0
+}
+
+// Each 按标签值排序遍历全部观测次数和观测值之和
+func (h *Histogram) Each(fn func(labelValues []string, count uint64, sum float64)) {
+	h.mu.Lock()
+	values := make([]histogramValue, 0, len(h.values))
+	for _, v := range sortedValues(h.values) {
+		values = append(values, histogramValue{labelValues: v.labelValues, count: v.count, sum: v.sum})
+	}
+	h.mu.Unlock()
+
+	for _, v := range values {
+		fn(v.labelValues, v.count, v.sum)
+	}
+}
+
+func (h *Histogram) value(labelValues []string) *histogramValue {
+	return h.valueIn(h.values, labelValues)
+}
+
+func (h *Histogram) valueIn(values map[string]*histogramValue, labelValues []string) *histogramValue {
+	key := labelKey(labelValues)
+	v, ok := values[key]
+	if !ok {
+		checkLabels(h.name, h.labels, labelValues)
+		v = &histogramValue{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)+1),
+		}
+		values[key] = v
+	}
+	return v
+}
+
+// NewBatch 创建直方图的本地观测缓冲
+func (h *Histogram) NewBatch() *HistogramBatch {
+	return &HistogramBatch{histogram: h, values: make(map[string]*histogramValue)}
+}
+
+// Observe 按标签值在本地记录一次观测
+func (b *HistogramBatch) Observe(value float64, labelValues ...string) {
+	v := b.histogram.valueIn(b.values, labelValues)
+	v.counts[sort.SearchFloat64s(b.histogram.buckets, value)]++
+	v.count++
+	v.sum += value
+}
+
+// Flush 将本地观测合并到直方图并清空
+func (b *HistogramBatch) Flush() {
+	if len(b.values) == 0 {
+		return
+	}
+	h := b.histogram
+	h.mu.Lock()
+	for _, local := range b.values {
+		v := h.value(local.labelValues)
+		for i, count := range local.counts {
+			v.counts[i] += count
+		}
+		v.count += local.count
+		v.sum += local.sum
+	}
+	h.mu.Unlock()
+	clear(b.values)
+}
+
+// NewLabelCap 创建最多保留limit个取值的标签限制
+func NewLabelCap(limit int) *LabelCap {
+	return &LabelCap{limit: limit, seen: make(map[string]struct{})}
+}
+
+// Value 返回value对应的标签取值，已满且未出现过的取值返回OTHER_LABEL
+func (c *LabelCap) Value(value string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.seen[value]; ok {
+		return value
+	}
+	if len(c.seen) >= c.limit {
+		return OTHER_LABEL
+	}
+	c.seen[value] = struct{}{}
+	return value
+}
+
+func (h *Histogram) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, escapeHelp(h.help), h.name)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, v := range sortedValues(h.values) {
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += v.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name,
+				formatLabels(h.labels, v.labelValues, "le", formatValue(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, v.labelValues, "le", "+Inf"), v.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, v.labelValues, "", ""), formatValue(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, v.labelValues, "", ""), v.count)
+	}
+}
+
+// checkLabels 标签值个数必须与注册时的标签名一致
+func checkLabels(name string, labels, labelValues []string) {
+	if len(labels) != len(labelValues) {
+		panic(fmt.Sprintf("指标%s需要%d个标签值，实际为%d个", name, len(labels), len(labelValues)))
+	}
+}
+
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// sortedValues 按标签值排序返回，保证输出稳定
+func sortedValues[V any](values map[string]*V) []*V {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sorted := make([]*V, len(keys))
+	for i, key := range keys {
+		sorted[i] = values[key]
+	}
+	return sorted
+}
+
+// formatLabels 格式化标签，extraName非空时追加一个标签（用于直方图的le）
+func formatLabels(labels, labelValues []string, extraName, extraValue string) string {
+	if len(labels) == 0 && extraName == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(label + `="` + escapeLabel(labelValues[i]) + `"`)
+	}
+	if extraName != "" {
+		if len(labels) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extraName + `="` + extraValue + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	lines := r.NewCounter("lines_total", "读取行数")
+	rejects := r.NewCounter("rejects_total", "拒绝数", "reason")
+	latency := r.NewHistogram("latency_seconds", "耗时", []float64{0.1, 1}, "instrument")
+
+	lines.Add(3)
+	rejects.Inc("price")
+	rejects.Inc(`a"b`)
+	rejects.Inc("price")
+	latency.Observe(0.05, "IF2412")
+	latency.Observe(0.1, "IF2412")
+	latency.Observe(2, "IF2412")
+
+	want := `# HELP lines_total 读取行数
+# TYPE lines_total counter
+lines_total 3
+# HELP rejects_total 拒绝数
+# TYPE rejects_total counter
+rejects_total{reason="a\"b"} 1
+rejects_total{reason="price"} 2
+# HELP latency_seconds 耗时
+# TYPE latency_seconds histogram
+latency_seconds_bucket{instrument="IF2412",le="0.1"} 2
+latency_seconds_bucket{instrument="IF2412",le="1"} 2
+latency_seconds_bucket{instrument="IF2412",le="+Inf"} 3
+latency_seconds_sum{instrument="IF2412"} 2.15
+latency_seconds_count{instrument="IF2412"} 3
+`
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if got := rec.Body.String(); got != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", got, want)
+	}
+	if rec.Header().Get("Content-Type") != CONTENT_TYPE {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+
+	if count, sum := latency.Count("IF2412"); count != 3 || sum != 2.15 {
+		t.Errorf("Count() = %d, %v", count, sum)
+	}
+	if rejects.Value("volume") != 0 || rejects.Value("price") != 2 {
+		t.Errorf("Value() = %v, %v", rejects.Value("volume"), rejects.Value("price"))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("标签值个数不符时应panic")
+		}
+	}()
+	rejects.Inc()
+}
+
+func TestHistogramBatch(t *testing.T) {
+	r := NewRegistry()
+	latency := r.NewHistogram("latency_seconds", "耗时", []float64{0.1, 1}, "instrument")
+	latency.Observe(0.05, "IF2412")
+
+	batch := latency.NewBatch()
+	batch.Observe(0.5, "IF2412")
+	batch.Observe(2, "TS2412")
+	if count, _ := latency.Count("TS2412"); count != 0 {
+		t.Errorf("Flush前不应合并, count = %d", count)
+	}
+	batch.Flush()
+	batch.Flush()
+	if count, sum := latency.Count("IF2412"); count != 2 || sum != 0.55 {
+		t.Errorf("Count(IF2412) = %d, %v", count, sum)
+	}
+	if count, sum := latency.Count("TS2412"); count != 1 || sum != 2 {
+		t.Errorf("Count(TS2412) = %d, %v", count, sum)
+	}
+}
+
+func TestLabelCap(t *testing.T) {
+	labels := NewLabelCap(2)
+	for _, tt := range []struct{ value, want string }{
+		{"IF2412", "IF2412"}, {"TS2412", "TS2412"}, {"T2412", OTHER_LABEL}, {"IF2412", "IF2412"},
+	} {
+		if got := labels.Value(tt.value); got != tt.want {
+			t.Errorf("Value(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
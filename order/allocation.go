@@ -0,0 +1,182 @@
+package order
+
+import (
+	"AuctionMatch/instrument"
+	"fmt"
+	"math"
+	"sort"
+)
+
+type (
+	// LiveOrder 集合竞价时段内带编号的存量订单
+	LiveOrder struct {
+		Order
+		OrderID string
+		Owner   string // 提交方标识，用于回报路由
+		Seq     uint64 // 时间优先序号，越小越优先
+	}
+
+	// Fill 订单在集合竞价中的成交
+	Fill struct {
+		OrderID      string
+		Owner        string
+		InstrumentID string
+		Direction    int8
+		Price        float32
+		Volume       int32
+	}
+
+	// SelfTradePrevention 自成交防范方式，同一非空账号的买单和卖单在分配成交时相遇即触发
+	SelfTradePrevention int
+)
+
+const (
+	AllowSelfTrade SelfTradePrevention = iota // 不防范，允许同账号成交
+	CancelNewest                              // 撤销两者中较晚的订单的剩余量
+	CancelOldest                              // 撤销两者中较早的订单的剩余量
+	DecrementBoth                             // 两者的剩余量同时减去较小者
+	CancelBoth                                // 同时撤销两者的剩余量
+)
+
+// ParseSelfTradePrevention 解析自成交防范方式：none、cancel-newest、cancel-oldest、decrement-both、cancel-both
+func ParseSelfTradePrevention(name string) (SelfTradePrevention, error) {
+	switch name {
+	case "none":
+		return AllowSelfTrade, nil
+	case "cancel-newest":
+		return CancelNewest, nil
+	case "cancel-oldest":
+		return CancelOldest, nil
+	case "decrement-both":
+		return DecrementBoth, nil
+	case "cancel-both":
+		return CancelBoth, nil
+	}
+	return 0, fmt.Errorf("无效的自成交防范方式: %s", name)
+}
+
+// AllocateFills 按价格优先、时间优先将成交量分配给可成交的订单，返回成交明细和被防范的自成交量。
+// 价格不劣于成交价的买单和卖单按优先级逐笔配对，不防范自成交时买卖双方各自分配matchVolume；
+// 配对的双方账号相同时按stp处理，被防范的量不再成交，成交价保持不变。被防范的量在撤销方式下为被撤订单的全部剩余量，
+// 同时撤销时为双方剩余量之和，同时扣减时为双方剩余量的较小者
+func AllocateFills(price float32, matchVolume int32, ticks instrument.TickSchedule, orders []*LiveOrder, stp SelfTradePrevention) (fills []Fill, prevented int32) {
+	if matchVolume <= 0 {
+		return nil, 0
+	}
+
+	priceInt := ticks.ToInt(price)
+	buys := make([]*LiveOrder, 0)
+	sells := make([]*LiveOrder, 0)
+	for _, o := range orders {
+		orderPrice := ticks.ToInt(o.Price)
+		if o.Direction == 0 && orderPrice >= priceInt {
+			buys = append(buys, o)
+		} else if o.Direction == 1 && orderPrice <= priceInt {
+			sells = append(sells, o)
+		}
+	}
+
+	if len(buys) == 0 || len(sells) == 0 {
+		return nil, 0
+	}
+	sortByPriority(buys, ticks)
+	sortByPriority(sells, ticks)
+
+	buyFilled := make([]int32, len(buys))
+	sellFilled := make([]int32, len(sells))
+	buyLeft, sellLeft := buys[0].Volume, sells[0].Volume
+	remaining := matchVolume
+	for i, j := 0, 0; remaining > 0 && i < len(buys) && j < len(sells); {
+		if stp != AllowSelfTrade && buys[i].Account != "" && buys[i].Account == sells[j].Account {
+			cancelBuy := buys[i].Seq > sells[j].Seq
+			switch stp {
+			case CancelOldest:
+				cancelBuy = !cancelBuy
+			case DecrementBoth:
+				volume := min(buyLeft, sellLeft)
+				prevented += volume
+				buyLeft -= volume
+				sellLeft -= volume
+			case CancelBoth:
+				prevented += buyLeft + sellLeft
+				buyLeft, sellLeft = 0, 0
+			}
+			if stp == CancelNewest || stp == CancelOldest {
+				if cancelBuy {
+					prevented += buyLeft
+					buyLeft = 0
+				} else {
+					prevented += sellLeft
+					sellLeft = 0
+				}
+			}
+		} else {
+			volume := min(buyLeft, sellLeft, remaining)
+			buyFilled[i] += volume
+			sellFilled[j] += volume
+			buyLeft -= volume
+			sellLeft -= volume
+			remaining -= volume
+		}
+
+		if buyLeft == 0 {
+			if i++; i < len(buys) {
+				buyLeft = buys[i].Volume
+			}
+		}
+		if sellLeft == 0 {
+			if j++; j < len(sells) {
+				sellLeft = sells[j].Volume
+			}
+		}
+	}
+
+	fills = make([]Fill, 0, len(buys)+len(sells))
+	fills = appendFills(fills, buys, buyFilled, price)
+	fills = appendFills(fills, sells, sellFilled, price)
+	return fills, prevented
+}
+
+// matchVolumeAt 以price撮合时的成交量，即价格不劣于price的买单总量与卖单总量的较小者
+func matchVolumeAt(price float32, ticks instrument.TickSchedule, orders []*LiveOrder) int32 {
+	priceInt := ticks.ToInt(price)
+	var buyVolume, sellVolume int64
+	for _, o := range orders {
+		orderPrice := ticks.ToInt(o.Price)
+		if o.Direction == 0 && orderPrice >= priceInt {
+			buyVolume += int64(o.Volume)
+		} else if o.Direction == 1 && orderPrice <= priceInt {
+			sellVolume += int64(o.Volume)
+		}
+	}
+	return int32(min(buyVolume, sellVolume, math.MaxInt32))
+}
+
+// sortByPriority 买单价格从高到低、卖单价格从低到高，同价按时间先后
+func sortByPriority(orders []*LiveOrder, ticks instrument.TickSchedule) {
+	sort.Slice(orders, func(i, j int) bool {
+		pi, pj := ticks.ToInt(orders[i].Price), ticks.ToInt(orders[j].Price)
+		if pi != pj {
+			return (pi > pj) == (orders[i].Direction == 0)
+		}
+		return orders[i].Seq < orders[j].Seq
+	})
+}
+
+// appendFills 按优先级顺序输出分配到成交量的订单
+func appendFills(fills []Fill, orders []*LiveOrder, filled []int32, price float32) []Fill {
+	for i, o := range orders {
+		if filled[i] == 0 {
+			continue
+		}
+		fills = append(fills, Fill{
+			OrderID:      o.OrderID,
+			Owner:        o.Owner,
+			InstrumentID: o.InstrumentID,
+			Direction:    o.Direction,
+			Price:        price,
+			Volume:       filled[i],
+		})
+	}
+	return fills
+}
@@ -0,0 +1,186 @@
+package order
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/utils"
+	"fmt"
+	"math"
+	"sort"
+)
+
+type (
+	// AuctionBook 单个合约的聚合委托簿，随订单的新增和撤销增量维护各价格档位
+	AuctionBook struct {
+		InstrumentID string
+		Tick         float32                 // 最低价格段的tick
+		Ticks        instrument.TickSchedule // 按价格分段的tick，档位价格为其中的档位序号
+		Scale        uint                    // 输出价格精度
+		OrderCount   int                     // 已接受的新增订单数
+		buyLevels    map[int64]int32
+		sellLevels   map[int64]int32
+		prices       []int64 // 有买量或卖量的价格档位（档位序号），升序
+	}
+
+	// Indicative 指示性集合竞价结果
+	Indicative struct {
+		InstrumentID string
+		Price        float32 // 指示性价格，无成交时为0；组合合约可以在0价成交，应以MatchVolume判断是否有成交
+		MatchVolume  int32   // 该价格上的成交量，无成交时为0
+		Imbalance    int32   // 该价格上累计买量与累计卖量之差，正为买方剩余，负为卖方剩余
+	}
+)
+
+func NewAuctionBook(instrumentID string) *AuctionBook {
+	ticks := (&Order{InstrumentID: instrumentID}).TickSchedule()
+	return &AuctionBook{
+		InstrumentID: instrumentID,
+		Tick:         ticks.Tick(0),
+		Ticks:        ticks,
+		Scale:        ticks.Scale(),
+		buyLevels:    make(map[int64]int32),
+		sellLevels:   make(map[int64]int32),
+	}
+}
+
+// RestoreAuctionBook 以已有的聚合档位重建委托簿，档位价格为ticks下的档位序号，
+// ticks应为写出档位时的价格分段，而不是合约当前参考数据中的分段
+func RestoreAuctionBook(instrumentID string, ticks instrument.TickSchedule, scale uint, orderCount int,
+	buyLevels, sellLevels map[int64]int32) *AuctionBook {
+	book := &AuctionBook{
+		InstrumentID: instrumentID,
+		Tick:         ticks.Tick(0),
+		Ticks:        ticks,
+		Scale:        scale,
+		OrderCount:   orderCount,
+		buyLevels:    make(map[int64]int32, len(buyLevels)),
+		sellLevels:   make(map[int64]int32, len(sellLevels)),
+	}
+	for priceInt, volume := range buyLevels {
+		book.addVolume(0, priceInt, volume)
+	}
+	for priceInt, volume := range sellLevels {
+		book.addVolume(1, priceInt, volume)
+	}
+	return book
+}
+
+// Levels 返回某一方向各价格档位（档位序号）的挂单量副本
+func (book *AuctionBook) Levels(direction int8) map[int64]int32 {
+	levels := make(map[int64]int32, len(book.levels(direction)))
+	for priceInt, volume := range book.levels(direction) {
+		levels[priceInt] = volume
+	}
+	return levels
+}
+
+// Add 将订单量计入对应价格档位
+func (book *AuctionBook) Add(order Order) {
+	book.addVolume(order.Direction, book.Ticks.ToInt(order.Price), order.Volume)
+	book.OrderCount++
+}
+
+// Cancel 从对应价格档位撤销订单量，撤销量超过档位剩余量时返回错误且不做修改
+func (book *AuctionBook) Cancel(order Order) error {
+	priceInt := book.Ticks.ToInt(order.Price)
+	if book.levels(order.Direction)[priceInt] < order.Volume {
+		return fmt.Errorf("合约%s价格%v的撤单量%d超过档位剩余量", book.InstrumentID, order.Price, order.Volume)
+	}
+	book.addVolume(order.Direction, priceInt, -order.Volume)
+	return nil
+}
+
+func (book *AuctionBook) levels(direction int8) map[int64]int32 {
+	if direction == 0 {
+		return book.buyLevels
+	}
+	return book.sellLevels
+}
+
+// addVolume 调整档位量，并维护有量档位的有序列表
+func (book *AuctionBook) addVolume(direction int8, priceInt int64, volume int32) {
+	levels := book.levels(direction)
+	before := book.buyLevels[priceInt] + book.sellLevels[priceInt]
+	levels[priceInt] += volume
+	if levels[priceInt] == 0 {
+		delete(levels, priceInt)
+	}
+	after := book.buyLevels[priceInt] + book.sellLevels[priceInt]
+
+	i := sort.Search(len(book.prices), func(i int) bool { return book.prices[i] >= priceInt })
+	if before == 0 && after > 0 {
+		book.prices = append(book.prices, 0)
+		copy(book.prices[i+1:], book.prices[i:])
+		book.prices[i] = priceInt
+	} else if before > 0 && after == 0 {
+		book.prices = append(book.prices[:i], book.prices[i+1:]...)
+	}
+}
+
+// bounds 返回最高买价和最低卖价在prices中的下标，任一方向没有挂单时ok为false
+func (book *AuctionBook) bounds() (high, low int, ok bool) {
+	high, low = -1, -1
+	for i := len(book.prices) - 1; i >= 0; i-- {
+		if book.buyLevels[book.prices[i]] > 0 {
+			high = i
+			break
+		}
+	}
+	for i, priceInt := range book.prices {
+		if book.sellLevels[priceInt] > 0 {
+			low = i
+			break
+		}
+	}
+	return high, low, high >= 0 && low >= 0 && high >= low
+}
+
+// Indicative 计算当前的指示性集合竞价结果，选价规则与CalculateAuctionPrice一致。
+// 只遍历有量的档位：相邻两个有量档位之间的各tick累计量相同，按“最高价格”取其中最高的一档参与比较
+func (book *AuctionBook) Indicative() Indicative {
+	result := Indicative{InstrumentID: book.InstrumentID}
+	high, low, ok := book.bounds()
+	if !ok {
+		return result
+	}
+
+	var accumBuy, accumSell int32
+	for i := low; i <= high; i++ {
+		accumSell += book.sellLevels[book.prices[i]]
+	}
+
+	var maxMatchVolume int32 = -1
+	var minRemainVolume int32 = math.MaxInt32
+	var bestPrice int64
+	var bestImbalance int32
+
+	consider := func(priceInt int64) {
+		matchVolume := utils.Min(accumBuy, accumSell)
+		remainVolume := utils.Abs(accumBuy - accumSell)
+		if betterMatch(matchVolume, remainVolume, priceInt, maxMatchVolume, minRemainVolume, bestPrice) {
+			maxMatchVolume = matchVolume
+			minRemainVolume = remainVolume
+			bestPrice = priceInt
+			bestImbalance = accumBuy - accumSell
+		}
+	}
+
+	for i := high; i >= low; i-- {
+		priceInt := book.prices[i]
+		// 与上一个更高档位之间的空档
+		if i < high && book.prices[i+1]-1 > priceInt {
+			consider(book.prices[i+1] - 1)
+		}
+		accumBuy += book.buyLevels[priceInt]
+		consider(priceInt)
+		accumSell -= book.sellLevels[priceInt]
+	}
+
+	if maxMatchVolume <= 0 {
+		return result
+	}
+
+	result.Price = book.Ticks.ToFloat(bestPrice)
+	result.MatchVolume = maxMatchVolume
+	result.Imbalance = bestImbalance
+	return result
+}
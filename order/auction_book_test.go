@@ -0,0 +1,61 @@
+package order
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestAuctionBookIndicative 随机新增和撤单，每步的指示性结果应与全量计算的分价表一致
+func TestAuctionBookIndicative(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 200; round++ {
+		book := NewAuctionBook("IF2412")
+		var live []Order
+
+		for step := 0; step < 30; step++ {
+			if len(live) > 0 && rng.Intn(4) == 0 {
+				// 撤销一笔存量订单
+				i := rng.Intn(len(live))
+				if err := book.Cancel(live[i]); err != nil {
+					t.Fatalf("Cancel() error = %v", err)
+				}
+				live = append(live[:i], live[i+1:]...)
+			} else {
+				order := Order{
+					InstrumentID: "IF2412",
+					Direction:    int8(rng.Intn(2)),
+					Price:        ToFloat(int64(19850+rng.Intn(40)), 0.2),
+					Volume:       int32(1 + rng.Intn(10)),
+				}
+				book.Add(order)
+				live = append(live, order)
+			}
+
+			got := book.Indicative()
+			ladder := CalculateAuctionLadder(live)
+			var imbalance int32
+			if ladder.Chosen >= 0 {
+				level := ladder.Levels[ladder.Chosen]
+				imbalance = level.AccumBuy - level.AccumSell
+			}
+			if got.Price != ladder.Price() || got.MatchVolume != ladder.MatchVolume() || got.Imbalance != imbalance {
+				t.Fatalf("第%d轮第%d步: Indicative() = %+v, 全量计算 price=%v match=%d imbalance=%d, orders=%+v",
+					round, step, got, ladder.Price(), ladder.MatchVolume(), imbalance, live)
+			}
+		}
+	}
+}
+
+func TestAuctionBookCancelTooMuch(t *testing.T) {
+	book := NewAuctionBook("IF2412")
+	book.Add(Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3})
+	if err := book.Cancel(Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 4}); err == nil {
+		t.Error("撤单量超过档位剩余量时应返回错误")
+	}
+	if err := book.Cancel(Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3}); err != nil {
+		t.Errorf("Cancel() error = %v", err)
+	}
+	if len(book.prices) != 0 {
+		t.Errorf("撤空后仍有档位: %v", book.prices)
+	}
+}
@@ -0,0 +1,185 @@
+package order
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/utils"
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// benchShape 基准测试的输入形态
+type benchShape struct {
+	name        string
+	instruments int   // 合约数
+	orders      int   // 订单总数
+	priceRange  int64 // 每个合约的价格区间（tick数）
+}
+
+var benchShapes = []benchShape{
+	{name: "many_instruments", instruments: 5000, orders: 100000, priceRange: 40},
+	{name: "few_instruments", instruments: 4, orders: 100000, priceRange: 40},
+	{name: "wide_range", instruments: 20, orders: 100000, priceRange: 20000},
+	{name: "heavy_duplicates", instruments: 20, orders: 100000, priceRange: 2},
+}
+
+// benchOrders 按形态生成固定种子的订单，各合约的订单交错出现，价格均在tick上。
+// 合约代码为各品种自2401起的连续合约月份，均为有效的年月
+func benchOrders(shape benchShape) []genOrder {
+	rng := rand.New(rand.NewSource(1))
+	codes := []string{"IF", "IC", "IM", "IH", "TS", "TF", "T", "TL"}
+	instruments := make([]string, shape.instruments)
+	centers := make([]int64, shape.instruments)
+	for i := range instruments {
+		months := i / len(codes)
+		instruments[i] = fmt.Sprintf("%s%02d%02d", codes[i%len(codes)], 24+months/12, months%12+1)
+		centers[i] = int64(rng.Intn(30000)) + shape.priceRange + 100
+	}
+
+	orders := make([]genOrder, shape.orders)
+	for i := range orders {
+		j := rng.Intn(shape.instruments)
+		orders[i] = genOrder{
+			instrumentID: instruments[j],
+			direction:    int8(rng.Intn(2)),
+			level:        centers[j] + rng.Int63n(shape.priceRange+1) - shape.priceRange/2,
+			volume:       int32(rng.Intn(100) + 1),
+		}
+	}
+	return orders
+}
+
+func benchLines(orders []genOrder) []string {
+	lines := make([]string, len(orders))
+	for i, o := range orders {
+		lines[i] = strings.Join(o.record(), ",")
+	}
+	return lines
+}
+
+// benchInstrumentOrders 解析后按合约分组
+func benchInstrumentOrders(b *testing.B, lines []string) [][]Order {
+	index := make(map[string]int)
+	grouped := make([][]Order, 0)
+	for _, line := range lines {
+		order, err := ParseOrder(utils.CustomSplit(line))
+		if err != nil {
+			b.Fatal(err)
+		}
+		i, ok := index[order.InstrumentID]
+		if !ok {
+			i = len(grouped)
+			index[order.InstrumentID] = i
+			grouped = append(grouped, nil)
+		}
+		grouped[i] = append(grouped[i], order)
+	}
+	return grouped
+}
+
+// TestBenchOrders 基准测试的输入须是有效的合约代码，且处理后各合约都有结果、存在成交
+func TestBenchOrders(t *testing.T) {
+	for _, shape := range benchShapes {
+		orders := benchOrders(shape)
+		seen := make(map[string]bool)
+		for _, o := range orders {
+			if !seen[o.instrumentID] {
+				seen[o.instrumentID] = true
+				if _, ok := instrument.Parse(o.instrumentID, instrument.Default.TradingDate()); !ok {
+					t.Fatalf("%s: 无效的合约代码 %s", shape.name, o.instrumentID)
+				}
+			}
+		}
+
+		results := NewOrderProcessor(WORKER_COUNT).Process(newTestStream(benchLines(orders)))
+		matched := 0
+		for _, result := range results {
+			if result.Matched {
+				matched++
+			}
+		}
+		if len(results) != len(seen) || matched == 0 {
+			t.Errorf("%s: %d个合约，结果%d个，成交%d个", shape.name, len(seen), len(results), matched)
+		}
+	}
+}
+
+// BenchmarkParse 逐行拆分并解析订单
+func BenchmarkParse(b *testing.B) {
+	for _, shape := range benchShapes {
+		b.Run(shape.name, func(b *testing.B) {
+			lines := benchLines(benchOrders(shape))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, line := range lines {
+					if _, err := ParseOrder(utils.CustomSplit(line)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkAggregate 按tick汇总各合约的买卖量
+func BenchmarkAggregate(b *testing.B) {
+	for _, shape := range benchShapes {
+		b.Run(shape.name, func(b *testing.B) {
+			grouped := benchInstrumentOrders(b, benchLines(benchOrders(shape)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, orders := range grouped {
+					aggregateOrders(orders, orders[0].TickSchedule())
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkCalculate 计算各合约的集合竞价价格（含汇总）
+func BenchmarkCalculate(b *testing.B) {
+	for _, shape := range benchShapes {
+		b.Run(shape.name, func(b *testing.B) {
+			grouped := benchInstrumentOrders(b, benchLines(benchOrders(shape)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, orders := range grouped {
+					CalculateAuctionPrice(orders)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkProcess 从CSV内容读取到输出结果的端到端处理，分别使用单线程和并行处理器
+func BenchmarkProcess(b *testing.B) {
+	for _, shape := range benchShapes {
+		content := []byte(strings.Join(benchLines(benchOrders(shape)), "\n"))
+		for _, numCPU := range []int{1, WORKER_COUNT} {
+			b.Run(shape.name+"/cpu="+strconv.Itoa(numCPU), func(b *testing.B) {
+				processor := NewOrderProcessor(numCPU)
+				b.SetBytes(int64(len(content)))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					stream := StreamReader(bytes.NewReader(content))
+					go func() {
+						for range stream.Error {
+						}
+					}()
+					if results := processor.Process(stream); len(results) == 0 {
+						b.Fatal("没有处理结果")
+					}
+					<-stream.Done
+					close(stream.Error)
+				}
+			})
+		}
+	}
+}
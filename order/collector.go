@@ -0,0 +1,144 @@
+package order
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/utils"
+	"context"
+	"log/slog"
+)
+
+type (
+	// orderCollector 按合约首次出现的顺序收集订单，边读取边按档位汇总，并记录各合约的输出精度
+	orderCollector struct {
+		scaleMode   ScaleMode
+		check       PreTradeCheck
+		implied     bool
+		tradable    bool
+		stp         SelfTradePrevention
+		instruments []string                    // 合约首次出现顺序
+		infos       map[string]*instrument.Info // 各合约的解析结果和参考数据，首次出现时解析
+		books       map[string]*instrumentBook  // 各合约已接受的订单
+	}
+
+	// instrumentBook 单个合约按档位汇总的订单
+	instrumentBook struct {
+		info   *instrument.Info
+		levels *PriceLevelMap
+		scale  uint         // 价格精度
+		orders int          // 订单数
+		live   []*LiveOrder // 开启自成交防范时逐笔保留的订单，Seq为输入顺序
+	}
+)
+
+func newOrderCollector(config processorConfig) *orderCollector {
+	return &orderCollector{
+		scaleMode:   config.scaleMode,
+		check:       config.check,
+		implied:     config.implied,
+		tradable:    config.tradable,
+		stp:         config.stp,
+		instruments: make([]string, 0),
+		infos:       make(map[string]*instrument.Info),
+		books:       make(map[string]*instrumentBook),
+	}
+}
+
+func newInstrumentBook(info *instrument.Info) *instrumentBook {
+	return &instrumentBook{info: info, levels: NewPriceLevelMap()}
+}
+
+// add 将订单计入所在档位
+func (book *instrumentBook) add(order Order) {
+	book.levels.add(order, book.info.Ticks)
+	book.orders++
+}
+
+// collect 读取订单流直至关闭，不可交易合约的订单和未通过事前检查的订单以RecordError上报
+func (c *orderCollector) collect(stream *OrderStream) {
+	ReadOrders(stream, func(order Order, record []string) {
+		if c.tradable {
+			if err := c.info(order.InstrumentID).Tradable(); err != nil {
+				OrdersRejected.Inc(rejectReason(err))
+				stream.Reject(STAGE_CONTRACT, order.InstrumentID, err)
+				return
+			}
+		}
+		if c.check != nil {
+			if err := c.check(order); err != nil {
+				OrdersRejected.Inc(rejectReason(err))
+				stream.Reject(STAGE_RISK, order.InstrumentID, err)
+				return
+			}
+		}
+		c.add(order, record[2])
+	})
+	if c.implied {
+		c.addImplied()
+	}
+}
+
+// addImplied 为两腿组合合约加入由单腿委托簿推导的隐含订单
+func (c *orderCollector) addImplied() {
+	for _, instrumentID := range c.instruments {
+		book := c.books[instrumentID]
+		if book.info.Kind != instrument.Spread || len(book.info.Spread.Legs) != 2 {
+			continue
+		}
+		for _, order := range impliedOrders(book.info, c.books[book.info.Spread.Legs[0]], c.books[book.info.Spread.Legs[1]]) {
+			book.add(order)
+		}
+	}
+}
+
+// info 返回合约的解析结果，首次查询时从instrument.Default取得并缓存
+func (c *orderCollector) info(instrumentID string) *instrument.Info {
+	info, ok := c.infos[instrumentID]
+	if !ok {
+		info = instrument.Default.Lookup(instrumentID)
+		c.infos[instrumentID] = info
+	}
+	return info
+}
+
+// add 添加一笔订单，priceText为原始价格文本，用于按输入精度输出
+func (c *orderCollector) add(order Order, priceText string) {
+	book, seen := c.books[order.InstrumentID]
+	if !seen {
+		c.instruments = append(c.instruments, order.InstrumentID)
+		reserveInstrumentLabel(order.InstrumentID)
+		book = newInstrumentBook(c.info(order.InstrumentID))
+		if c.scaleMode == ScaleByTick {
+			book.scale = book.info.Ticks.Scale()
+		}
+		c.books[order.InstrumentID] = book
+	}
+	if c.scaleMode == ScaleByInput {
+		book.scale = utils.Max(book.scale, PriceScale(priceText))
+	}
+	book.add(order)
+	if c.stp != AllowSelfTrade && order.Volume > 0 {
+		book.live = append(book.live, &LiveOrder{Order: order, Seq: uint64(book.orders)})
+	}
+}
+
+// result 计算第i个合约的集合竞价结果，计算指标记入m
+func (c *orderCollector) result(i int, m *calcMetrics) ProcessResult {
+	instrumentID := c.instruments[i]
+	book := c.books[instrumentID]
+	price, matched := auctionPrice(instrumentID, book.orders, book.levels, book.info.Ticks, m)
+	result := ProcessResult{
+		InstrumentID: instrumentID,
+		Price:        price,
+		Scale:        book.scale,
+		Matched:      matched,
+	}
+	if matched && c.stp != AllowSelfTrade {
+		ticks := book.info.Ticks
+		_, result.PreventedVolume = AllocateFills(price, matchVolumeAt(price, ticks, book.live), ticks, book.live, c.stp)
+	}
+	if slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		slog.Debug("集合竞价价格", "instrument", instrumentID, "stage", STAGE_CALCULATE,
+			"orders", book.orders, "price", result.FormatPrice())
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package order
+
+import (
+	"AuctionMatch/instrument"
+	"log/slog"
+	"math"
+)
+
+// bestLevel 一侧最优价格档位及该档位的总量
+type bestLevel struct {
+	price  float32
+	volume int32
+	ok     bool
+}
+
+// bestLevels 返回单腿合约的最高买价档位和最低卖价档位，book为nil表示该腿没有订单
+func bestLevels(book *instrumentBook) (bid, ask bestLevel) {
+	if book == nil {
+		return bid, ask
+	}
+	ticks, levels := book.info.Ticks, book.levels
+	if !math.IsInf(float64(levels.highestBid), 0) {
+		priceInt := ticks.ToInt(levels.highestBid)
+		bid = bestLevel{price: ticks.ToFloat(priceInt), volume: levels.buyLevels[priceInt], ok: true}
+	}
+	if !math.IsInf(float64(levels.lowestAsk), 0) {
+		priceInt := ticks.ToInt(levels.lowestAsk)
+		ask = bestLevel{price: ticks.ToFloat(priceInt), volume: levels.sellLevels[priceInt], ok: true}
+	}
+	return bid, ask
+}
+
+// impliedOrders 由两腿的委托簿推导组合合约的隐含买卖单：隐含买价为第一腿最高买价减第二腿最低卖价，
+// 隐含卖价为第一腿最低卖价减第二腿最高买价，数量取两腿最优档位的较小者。组合合约的tick与单腿不同时，
+// 隐含买价向下、隐含卖价向上取到组合合约的档位，使隐含单的价格不优于两腿实际可成交的价差。
+// 只推导两腿组合的最优档位。隐含单是指示性的：不从单腿委托簿中扣除，同一单腿数量会同时计入单腿和组合合约的集合竞价，
+// 因此组合合约的结果不代表能与单腿同时成交的数量
+func impliedOrders(spread *instrument.Info, first, second *instrumentBook) []Order {
+	instrumentID, ticks := spread.ID, spread.Ticks
+	firstBid, firstAsk := bestLevels(first)
+	secondBid, secondAsk := bestLevels(second)
+
+	implied := make([]Order, 0, 2)
+	if firstBid.ok && secondAsk.ok {
+		implied = append(implied, Order{
+			InstrumentID: instrumentID,
+			Direction:    0,
+			Price:        ticks.ToFloat(ticks.Floor(priceDiff(firstBid.price, secondAsk.price))),
+			Volume:       min(firstBid.volume, secondAsk.volume),
+		})
+	}
+	if firstAsk.ok && secondBid.ok {
+		implied = append(implied, Order{
+			InstrumentID: instrumentID,
+			Direction:    1,
+			Price:        ticks.ToFloat(ticks.Ceil(priceDiff(firstAsk.price, secondBid.price))),
+			Volume:       min(firstAsk.volume, secondBid.volume),
+		})
+	}
+	if len(implied) > 0 {
+		slog.Debug("推导隐含组合订单", "instrument", instrumentID, "stage", STAGE_AGGREGATE, "orders", len(implied))
+	}
+	return implied
+}
+
+// priceDiff 按万分之一单位计算两腿价差，避免float32相减的误差使价差落到相邻档位
+func priceDiff(first, second float32) float32 {
+	units := math.Round(float64(first)*instrument.PRICE_UNIT) - math.Round(float64(second)*instrument.PRICE_UNIT)
+	return float32(units / instrument.PRICE_UNIT)
+}
@@ -0,0 +1,306 @@
+package order
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/order/internal/reference"
+	"AuctionMatch/refdata"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	diffSeed  = flag.Int64("diff.seed", envSeed(), "差分测试的随机种子，默认取环境变量DIFF_SEED或1，0表示按当前时间生成")
+	diffCases = flag.Int("diff.cases", 300, "差分测试的随机用例数")
+)
+
+// DIFF_BANDED_PRODUCTS 差分测试中使用的按价格分段的品种，生成的订单集中在各价格段起点附近
+var DIFF_BANDED_PRODUCTS = []refdata.Product{
+	{Code: "ZX", Tick: 0.2, TickBands: []refdata.TickBand{{From: 10, Tick: 1}, {From: 100, Tick: 5}}},
+	{Code: "ZY", Tick: 0.05, TickBands: []refdata.TickBand{{From: 5, Tick: 0.5}, {From: 200, Tick: 10}}},
+}
+
+// envSeed 返回环境变量DIFF_SEED指定的种子，未设置或无法解析时为1，使CI中每次运行的用例相同
+func envSeed() int64 {
+	if seed, err := strconv.ParseInt(os.Getenv("DIFF_SEED"), 10, 64); err == nil {
+		return seed
+	}
+	return 1
+}
+
+// genOrder 差分测试生成的订单，价格以档位序号表示
+type genOrder struct {
+	instrumentID string
+	direction    int8
+	level        int64
+	volume       int32
+}
+
+// record 转为输入行的字段，价格按合约的价格分段换算并按最大小数位数格式化
+func (o genOrder) record() []string {
+	ticks := (&Order{InstrumentID: o.instrumentID}).TickSchedule()
+	price := float64(ticks.ToFloat(o.level))
+	return []string{o.instrumentID, strconv.Itoa(int(o.direction)),
+		strconv.FormatFloat(price, 'f', int(ticks.Scale()), 64), strconv.Itoa(int(o.volume))}
+}
+
+func formatGenOrders(orders []genOrder) string {
+	lines := make([]string, len(orders))
+	for i, o := range orders {
+		lines[i] = strings.Join(o.record(), ",")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// generateOrders 随机生成一批订单，覆盖全部品种、按价格分段的品种、未知品种、单边、不交叉和大量同价的情况
+func generateOrders(rng *rand.Rand) []genOrder {
+	products := refdata.Default.Products()
+	codes := make([]string, 0, len(products)+len(DIFF_BANDED_PRODUCTS)+1)
+	for _, product := range products {
+		if len(product.TickBands) > 0 {
+			continue // 其他测试登记的分段品种不参与生成，保证同一种子生成的用例与测试顺序无关
+		}
+		codes = append(codes, product.Code)
+	}
+	for _, product := range DIFF_BANDED_PRODUCTS {
+		codes = append(codes, product.Code)
+	}
+	codes = append(codes, "ZZ") // 未知品种使用默认tick
+
+	orders := make([]genOrder, 0)
+	used := make(map[string]bool)
+	for n := rng.Intn(6) + 1; n > 0; n-- {
+		instrumentID := codes[rng.Intn(len(codes))] + strconv.Itoa(2400+rng.Intn(12)+1)
+		if used[instrumentID] {
+			continue // 同一合约的价格需集中在一个区间内，否则参考实现的分价表过大
+		}
+		used[instrumentID] = true
+		center := int64(rng.Intn(50000) + 100)
+		if product, ok := refdata.Default.Product(instrument.ProductCode(instrumentID)); ok && len(product.TickBands) > 0 {
+			// 围绕某个价格段的起点，使分价表跨越价格段
+			from := product.TickBands[rng.Intn(len(product.TickBands))].From
+			center = (&Order{InstrumentID: instrumentID}).TickSchedule().ToInt(from) + int64(rng.Intn(41)-20)
+		}
+		spread := int64(rng.Intn(40) + 1)
+		for m := rng.Intn(30) + 1; m > 0; m-- {
+			direction := int8(rng.Intn(2))
+			if rng.Intn(10) == 0 {
+				direction = 0 // 偶尔只有买单
+			}
+			offset := rng.Int63n(2*spread+1) - spread
+			if direction == 0 && rng.Intn(8) == 0 {
+				offset -= 2 * spread // 偶尔不交叉
+			}
+			order := genOrder{
+				instrumentID: instrumentID,
+				direction:    direction,
+				level:        center + offset,
+				volume:       int32(rng.Intn(20) + 1),
+			}
+			// 随机插入，使各合约的订单交错出现
+			i := rng.Intn(len(orders) + 1)
+			orders = append(orders[:i], append([]genOrder{order}, orders[i:]...)...)
+		}
+	}
+	return orders
+}
+
+// referencePrices 按参考实现计算各合约的集合竞价价格（档位序号），无成交的合约不在结果中
+func referencePrices(orders []genOrder) map[string]int64 {
+	byInstrument := make(map[string][]reference.Order)
+	for _, o := range orders {
+		record := o.record()
+		price, _ := strconv.ParseFloat(record[2], 64)
+		byInstrument[o.instrumentID] = append(byInstrument[o.instrumentID],
+			reference.Order{Direction: o.direction, Price: price, Volume: int64(o.volume)})
+	}
+
+	prices := make(map[string]int64)
+	for instrumentID, refOrders := range byInstrument {
+		ticks := (&Order{InstrumentID: instrumentID}).TickSchedule()
+		bands := []reference.Band{{From: 0, Tick: refFloat(ticks.Tick(0))}}
+		if product, ok := refdata.Default.Product(instrument.ProductCode(instrumentID)); ok {
+			for _, band := range product.TickBands {
+				bands = append(bands, reference.Band{From: refFloat(band.From), Tick: refFloat(band.Tick)})
+			}
+		}
+
+		var price float64
+		var ok bool
+		if len(bands) == 1 {
+			price, ok = reference.AuctionPrice(refOrders, bands[0].Tick)
+		} else {
+			price, ok = reference.BandedAuctionPrice(refOrders, bands)
+		}
+		if ok {
+			prices[instrumentID] = ticks.ToInt(float32(price))
+		}
+	}
+	return prices
+}
+
+// refFloat 按float32的最短十进制表示转为float64，避免0.2变为0.20000000298
+func refFloat(value float32) float64 {
+	result, _ := strconv.ParseFloat(strconv.FormatFloat(float64(value), 'f', -1, 32), 64)
+	return result
+}
+
+// diffCalculate 比较CalculateAuctionPrice与参考实现，不一致时返回描述
+func diffCalculate(orders []genOrder) string {
+	byInstrument := make(map[string][]Order)
+	for _, o := range orders {
+		order, err := ParseOrder(o.record())
+		if err != nil {
+			return err.Error()
+		}
+		byInstrument[o.instrumentID] = append(byInstrument[o.instrumentID], order)
+	}
+
+	want := referencePrices(orders)
+	for instrumentID, instrumentOrders := range byInstrument {
+		price, matched := CalculateAuction(instrumentOrders)
+		got := formatLevel(price, matched, instrumentOrders[0].TickSchedule())
+		if wantLevel := formatWantLevel(want, instrumentID); got != wantLevel {
+			return fmt.Sprintf("CalculateAuction(%s) = %s, 参考实现 = %s", instrumentID, got, wantLevel)
+		}
+	}
+	return ""
+}
+
+// diffProcessor 比较OrderProcessor与参考实现，同时检查输出顺序为合约首次出现的顺序
+func diffProcessor(numCPU int) func(orders []genOrder) string {
+	return func(orders []genOrder) string {
+		lines := strings.Split(formatGenOrders(orders), "\n")
+		results := NewOrderProcessor(numCPU).Process(newTestStream(lines))
+
+		want := referencePrices(orders)
+		var instruments []string
+		for _, o := range orders {
+			if !slices.Contains(instruments, o.instrumentID) {
+				instruments = append(instruments, o.instrumentID)
+			}
+		}
+		if len(results) != len(instruments) {
+			return fmt.Sprintf("processor(%d) 返回%d个合约, want %d", numCPU, len(results), len(instruments))
+		}
+		for i, result := range results {
+			if result.InstrumentID != instruments[i] {
+				return fmt.Sprintf("processor(%d) 第%d个合约为%s, want %s", numCPU, i, result.InstrumentID, instruments[i])
+			}
+			ticks := (&Order{InstrumentID: result.InstrumentID}).TickSchedule()
+			got := formatLevel(result.Price, result.Matched, ticks)
+			if wantLevel := formatWantLevel(want, result.InstrumentID); got != wantLevel {
+				return fmt.Sprintf("processor(%d) %s = %s, 参考实现 = %s", numCPU, result.InstrumentID, got, wantLevel)
+			}
+		}
+		return ""
+	}
+}
+
+// formatLevel 将结果描述为"档位n"或"无成交"，0价成交与无成交不同
+func formatLevel(price float32, matched bool, ticks instrument.TickSchedule) string {
+	if !matched {
+		return "无成交"
+	}
+	return fmt.Sprintf("档位%d", ticks.ToInt(price))
+}
+
+func formatWantLevel(want map[string]int64, instrumentID string) string {
+	level, ok := want[instrumentID]
+	if !ok {
+		return "无成交"
+	}
+	return fmt.Sprintf("档位%d", level)
+}
+
+// shrinkOrders 在保持失败的前提下反复删除订单、减小数量，返回局部最小的失败用例
+func shrinkOrders(orders []genOrder, fails func([]genOrder) bool) []genOrder {
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(orders); i++ {
+			candidate := append(append([]genOrder(nil), orders[:i]...), orders[i+1:]...)
+			if fails(candidate) {
+				orders, changed = candidate, true
+				i--
+			}
+		}
+		for i := range orders {
+			for orders[i].volume > 1 {
+				candidate := append([]genOrder(nil), orders...)
+				candidate[i].volume /= 2
+				if !fails(candidate) {
+					break
+				}
+				orders, changed = candidate, true
+			}
+		}
+	}
+	return orders
+}
+
+// TestDifferential 随机生成订单，与README逐步实现的参考版本对比CalculateAuctionPrice和两种处理器，
+// 不一致时收缩为最小用例输出。复现失败用例：go test ./order -run TestDifferential -diff.seed=<seed>，
+// 也可通过环境变量DIFF_SEED指定种子
+func TestDifferential(t *testing.T) {
+	for _, product := range DIFF_BANDED_PRODUCTS {
+		refdata.Default.Put(product)
+	}
+	seed := *diffSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	checks := []struct {
+		name string
+		diff func([]genOrder) string
+	}{
+		{name: "CalculateAuctionPrice", diff: diffCalculate},
+		{name: "SingleProcessor", diff: diffProcessor(1)},
+		{name: "ParallelProcessor", diff: diffProcessor(4)},
+	}
+
+	for i := 0; i < *diffCases; i++ {
+		orders := generateOrders(rng)
+		for _, check := range checks {
+			if check.diff(orders) == "" {
+				continue
+			}
+			minimal := shrinkOrders(orders, func(candidate []genOrder) bool {
+				return check.diff(candidate) != ""
+			})
+			t.Fatalf("%s 与参考实现不一致（seed=%d，第%d个用例）: %s\n最小用例:\n%s",
+				check.name, seed, i, check.diff(minimal), formatGenOrders(minimal))
+		}
+	}
+}
+
+// TestShrinkOrders 测试收缩能找到最小失败用例
+func TestShrinkOrders(t *testing.T) {
+	orders := []genOrder{
+		{instrumentID: "IF2412", direction: 0, level: 100, volume: 8},
+		{instrumentID: "IF2412", direction: 1, level: 99, volume: 5},
+		{instrumentID: "TS2412", direction: 0, level: 50, volume: 3},
+		{instrumentID: "IF2412", direction: 1, level: 101, volume: 7},
+	}
+	// 模拟一个只在存在IF2412卖单且数量大于2时出现的缺陷
+	fails := func(candidate []genOrder) bool {
+		for _, o := range candidate {
+			if o.instrumentID == "IF2412" && o.direction == 1 && o.volume > 2 {
+				return true
+			}
+		}
+		return false
+	}
+
+	got := shrinkOrders(orders, fails)
+	if len(got) != 1 || got[0].direction != 1 || got[0].volume != 3 {
+		t.Errorf("shrinkOrders() = %+v, want 单笔数量为3的卖单", got)
+	}
+}
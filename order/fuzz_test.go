@@ -0,0 +1,218 @@
+package order
+
+import (
+	"AuctionMatch/utils"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fuzzSeedLines 种子输入，取自README中的示例和三类示例文件（常规小样本、邮件中的新选价规则、只有卖单）的典型行
+var fuzzSeedLines = []string{
+	"IF2412,0,3973.4,3",
+	"IF2412,1,3973.0,2",
+	"TS2412,0,101.234,1",
+	"T2503,1,105.005,20",
+	"TL2503,0,118.01,5",
+	"IC2412,1,5801.6,4,08:59:59.999",
+	"IF2412,0,3973.4,3,,A001",
+	"IF2412,1,3973.0,2,09:00:00,A001",
+	"IF2412,0,3973.4,-3",
+	"IF2412,2,3973.4,3",
+	"IF2412,0,,3",
+	"IF2412,0,3973.4",
+	",,,",
+	"IF2412,0,3973.4,3,25:00:00",
+	"IF2412,0,NaN,3",
+	"IF2412,1,1e39,3",
+	"IF2412,0,3973.4,4294967297",
+}
+
+// formatRecord 按ParseOrder接受的格式输出订单，时间戳保留全部纳秒位，有账号时时间戳可为空
+func formatRecord(order Order) []string {
+	record := []string{order.InstrumentID, strconv.Itoa(int(order.Direction)),
+		strconv.FormatFloat(float64(order.Price), 'g', -1, 32), strconv.Itoa(int(order.Volume))}
+	if order.HasTime || order.Account != "" {
+		var timestamp string
+		if order.HasTime {
+			t := time.Duration(order.Time)
+			timestamp = fmt.Sprintf("%02d:%02d:%02d.%09d",
+				int(t.Hours()), int(t.Minutes())%60, int(t.Seconds())%60, order.Time%int64(time.Second))
+		}
+		record = append(record, timestamp)
+	}
+	if order.Account != "" {
+		record = append(record, order.Account)
+	}
+	return record
+}
+
+// FuzzParseOrder CustomSplit和ParseOrder不应panic，有效记录格式化后重新解析应得到相同的订单
+func FuzzParseOrder(f *testing.F) {
+	for _, line := range fuzzSeedLines {
+		f.Add(line)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		record := utils.CustomSplit(line)
+		if joined := strings.Join(record, ","); joined != line {
+			t.Fatalf("CustomSplit(%q) 拼接后为 %q", line, joined)
+		}
+		if !IsValidRecord(record) {
+			return
+		}
+
+		order, err := ParseOrder(record)
+		if err != nil {
+			return
+		}
+		reparsed, err := ParseOrder(formatRecord(order))
+		if err != nil {
+			t.Fatalf("ParseOrder(%q) = %+v，格式化后无法重新解析: %v", line, order, err)
+		}
+		if reparsed != order {
+			t.Fatalf("ParseOrder(%q) = %+v，重新解析得到 %+v", line, order, reparsed)
+		}
+	})
+}
+
+// decodeFuzzOrders 将模糊输入解码为同一合约的订单：首字节选择品种，之后每4字节为一笔订单，
+// 依次为方向、相对基准价的tick偏移（2字节）和数量
+func decodeFuzzOrders(data []byte) []Order {
+	if len(data) == 0 {
+		return nil
+	}
+	codes := []string{"IF", "TS", "TF", "T", "TL", "ZZ"}
+	instrumentID := codes[int(data[0])%len(codes)] + "2412"
+	tick := (&Order{InstrumentID: instrumentID}).GetTick()
+
+	orders := make([]Order, 0, len(data)/4)
+	for rest := data[1:]; len(rest) >= 4; rest = rest[4:] {
+		offset := int64(int16(binary.BigEndian.Uint16(rest[1:3])))
+		orders = append(orders, Order{
+			InstrumentID: instrumentID,
+			Direction:    int8(rest[0] % 2),
+			Price:        ToFloat(40000+offset, tick),
+			Volume:       int32(rest[3]) + 1,
+		})
+	}
+	return orders
+}
+
+// FuzzCalculateAuctionPrice 检查集合竞价价格的不变量：位于[最低卖价, 最高买价]之间、在tick整数倍上、
+// 当且仅当买卖价格交叉时有成交价
+func FuzzCalculateAuctionPrice(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 2, 2, 1, 0, 0, 1})
+	f.Add([]byte{1, 0, 0, 5, 0, 1, 0, 3, 9, 1, 255, 255, 4})
+	f.Add([]byte{3, 1, 0, 0, 1, 1, 0, 1, 1})
+	f.Add([]byte{5, 0, 0, 0, 3, 0, 0, 1, 3, 1, 0, 0, 3, 1, 0, 1, 3})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		orders := decodeFuzzOrders(data)
+		price, matched := CalculateAuction(orders)
+		if len(orders) == 0 {
+			if price != 0 || matched {
+				t.Fatalf("没有订单时应无成交，得到 %v, %v", price, matched)
+			}
+			return
+		}
+
+		tick := orders[0].GetTick()
+		highestBid, lowestAsk := int64(-1), int64(-1)
+		for _, order := range orders {
+			priceInt := ToInt(order.Price, tick)
+			if order.Direction == 0 && (highestBid == -1 || priceInt > highestBid) {
+				highestBid = priceInt
+			}
+			if order.Direction == 1 && (lowestAsk == -1 || priceInt < lowestAsk) {
+				lowestAsk = priceInt
+			}
+		}
+		crossed := highestBid != -1 && lowestAsk != -1 && highestBid >= lowestAsk
+
+		if matched != crossed {
+			t.Fatalf("价格 %v，是否成交 %v，买卖是否交叉 %v", price, matched, crossed)
+		}
+		if !matched {
+			return
+		}
+		priceInt := ToInt(price, tick)
+		if ToFloat(priceInt, tick) != price {
+			t.Fatalf("价格 %v 不在tick %v 的整数倍上", price, tick)
+		}
+		if priceInt < lowestAsk || priceInt > highestBid {
+			t.Fatalf("价格 %d ticks 不在 [%d, %d] 之间", priceInt, lowestAsk, highestBid)
+		}
+	})
+}
+
+// FuzzProcess 对任意输入文件运行StreamOrders→Process，两种处理器的结果应一致，
+// 且与逐行解析后直接调用CalculateAuctionPrice的结果一致
+func FuzzProcess(f *testing.F) {
+	f.Add(strings.Join(fuzzSeedLines, "\n"))
+	f.Add("IF2412,0,3973.4,3\nTS2412,0,101.234,1\nIF2412,1,3973.0,2\n")
+	f.Add("TF2503,1,102.005,3\nTF2503,1,102.010,1\n")
+	f.Add("\n\r\nIF2412,0,3973.4,3\r\n   \nIF2412,1,3973.2,3")
+
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, content string) {
+		path := filepath.Join(dir, "orders.csv")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var results [][]ProcessResult
+		for _, numCPU := range []int{1, 4} {
+			stream := StreamOrders(path)
+			go func() {
+				for range stream.Error {
+				}
+			}()
+			results = append(results, NewOrderProcessor(numCPU).Process(stream))
+			<-stream.Done
+			close(stream.Error)
+			if stream.Err != nil {
+				return // 超长行等无法读取的输入
+			}
+		}
+		if !reflect.DeepEqual(results[0], results[1]) {
+			t.Fatalf("SingleProcessor = %+v, ParallelProcessor = %+v", results[0], results[1])
+		}
+
+		var instruments []string
+		byInstrument := make(map[string][]Order)
+		for _, line := range strings.Split(content, "\n") {
+			record := utils.CustomSplit(strings.TrimSpace(line))
+			if !IsValidRecord(record) {
+				continue
+			}
+			order, err := ParseOrder(record)
+			if err != nil {
+				continue
+			}
+			if _, seen := byInstrument[order.InstrumentID]; !seen {
+				instruments = append(instruments, order.InstrumentID)
+			}
+			byInstrument[order.InstrumentID] = append(byInstrument[order.InstrumentID], order)
+		}
+
+		if len(results[0]) != len(instruments) {
+			t.Fatalf("Process() 返回%d个合约, want %d", len(results[0]), len(instruments))
+		}
+		for i, result := range results[0] {
+			if result.InstrumentID != instruments[i] {
+				t.Fatalf("第%d个合约为%q, want %q", i, result.InstrumentID, instruments[i])
+			}
+			want := CalculateAuctionPrice(byInstrument[result.InstrumentID])
+			if result.Price != want {
+				t.Fatalf("%s 价格为 %v, want %v", result.InstrumentID, result.Price, want)
+			}
+		}
+	})
+}
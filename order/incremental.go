@@ -0,0 +1,98 @@
+package order
+
+import "fmt"
+
+// IncrementalAuction 增量集合竞价计算器，按合约维护聚合委托簿，
+// 每次新增或撤单后即可得到该合约最新的指示性价格
+type IncrementalAuction struct {
+	config      processorConfig
+	instruments []string // 合约首次出现顺序
+	books       map[string]*AuctionBook
+}
+
+func NewIncrementalAuction(opts ...ProcessorOption) *IncrementalAuction {
+	return &IncrementalAuction{
+		config:      newProcessorConfig(opts),
+		instruments: make([]string, 0),
+		books:       make(map[string]*AuctionBook),
+	}
+}
+
+// book 返回合约的委托簿，不存在时创建
+func (a *IncrementalAuction) book(instrumentID string) *AuctionBook {
+	book, ok := a.books[instrumentID]
+	if !ok {
+		book = NewAuctionBook(instrumentID)
+		if a.config.scaleMode == ScaleByInput {
+			book.Scale = 0
+		}
+		a.books[instrumentID] = book
+		a.instruments = append(a.instruments, instrumentID)
+	}
+	return book
+}
+
+// Add 新增订单并返回该合约最新的指示性结果，priceText为原始价格文本，按输入精度输出时使用
+func (a *IncrementalAuction) Add(order Order, priceText string) Indicative {
+	book := a.book(order.InstrumentID)
+	if a.config.scaleMode == ScaleByInput {
+		book.Scale = max(book.Scale, PriceScale(priceText))
+	}
+	book.Add(order)
+	return book.Indicative()
+}
+
+// Cancel 撤销订单量并返回该合约最新的指示性结果
+func (a *IncrementalAuction) Cancel(order Order) (Indicative, error) {
+	book, ok := a.books[order.InstrumentID]
+	if !ok {
+		return Indicative{InstrumentID: order.InstrumentID}, fmt.Errorf("未知合约: %s", order.InstrumentID)
+	}
+	if err := book.Cancel(order); err != nil {
+		return book.Indicative(), err
+	}
+	return book.Indicative(), nil
+}
+
+// Apply 应用一行订单事件：volume为负数时按该价格撤销相应数量，否则新增
+func (a *IncrementalAuction) Apply(order Order, priceText string) (Indicative, error) {
+	if order.Volume < 0 {
+		order.Volume = -order.Volume
+		return a.Cancel(order)
+	}
+	return a.Add(order, priceText), nil
+}
+
+// Instruments 按首次出现顺序返回合约
+func (a *IncrementalAuction) Instruments() []string {
+	return append([]string(nil), a.instruments...)
+}
+
+// Restore 放入已恢复的委托簿，合约已存在时替换并保留原有顺序，否则追加到末尾
+func (a *IncrementalAuction) Restore(book *AuctionBook) {
+	if _, ok := a.books[book.InstrumentID]; !ok {
+		a.instruments = append(a.instruments, book.InstrumentID)
+	}
+	a.books[book.InstrumentID] = book
+}
+
+// Book 返回合约的委托簿，未出现过的合约返回nil
+func (a *IncrementalAuction) Book(instrumentID string) *AuctionBook {
+	return a.books[instrumentID]
+}
+
+// Results 按合约首次出现顺序返回当前的集合竞价结果
+func (a *IncrementalAuction) Results() []ProcessResult {
+	results := make([]ProcessResult, len(a.instruments))
+	for i, instrumentID := range a.instruments {
+		book := a.books[instrumentID]
+		indicative := book.Indicative()
+		results[i] = ProcessResult{
+			InstrumentID: instrumentID,
+			Price:        indicative.Price,
+			Scale:        book.Scale,
+			Matched:      indicative.MatchVolume > 0,
+		}
+	}
+	return results
+}
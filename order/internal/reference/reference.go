@@ -0,0 +1,224 @@
+// Package reference 按README中的九个步骤逐字实现集合竞价选价，只追求直观正确，不考虑性能，
+// 仅供测试中与order包的实现做差分对比。
+package reference
+
+import "math"
+
+type (
+	// Order 参考实现使用的订单
+	Order struct {
+		Direction int8 // 0:买, 1:卖
+		Price     float64
+		Volume    int64
+	}
+
+	// Band 价格段，价格不低于From时最小变动价位为Tick
+	Band struct {
+		From float64
+		Tick float64
+	}
+
+	// Level 分价表中的一个价格档位
+	Level struct {
+		Price        float64
+		BuyVolume    int64
+		SellVolume   int64
+		AccumBuy     int64 // 价格不低于本档位的买入量之和
+		AccumSell    int64 // 价格不高于本档位的卖出量之和
+		MatchVolume  int64
+		RemainVolume int64
+	}
+)
+
+// AuctionPrice 计算集合竞价价格，tick为合约的最小变动价位，无成交时ok为false
+func AuctionPrice(orders []Order, tick float64) (price float64, ok bool) {
+	return choose(Ladder(orders, tick))
+}
+
+// BandedAuctionPrice 按价格分段计算集合竞价价格，bands按起点升序且第一段起点为0，无成交时ok为false
+func BandedAuctionPrice(orders []Order, bands []Band) (price float64, ok bool) {
+	return choose(BandedLadder(orders, bands))
+}
+
+// choose 按步骤7到9从分价表中选出价格
+func choose(levels []Level) (price float64, ok bool) {
+	if len(levels) == 0 {
+		return 0, false
+	}
+
+	// 7. 最大成交量
+	var maxMatch int64
+	for _, level := range levels {
+		if level.MatchVolume > maxMatch {
+			maxMatch = level.MatchVolume
+		}
+	}
+	if maxMatch == 0 {
+		return 0, false
+	}
+
+	// 8. 最大成交量的档位中最小的剩余量
+	minRemain := int64(math.MaxInt64)
+	for _, level := range levels {
+		if level.MatchVolume == maxMatch && level.RemainVolume < minRemain {
+			minRemain = level.RemainVolume
+		}
+	}
+
+	// 9. 仍有多个档位时取最高价格
+	found := false
+	for _, level := range levels {
+		if level.MatchVolume == maxMatch && level.RemainVolume == minRemain {
+			if !found || level.Price > price {
+				price = level.Price
+				found = true
+			}
+		}
+	}
+	return price, true
+}
+
+// Ladder 构造从最低卖价到最高买价的完整分价表，没有成交机会时返回nil
+func Ladder(orders []Order, tick float64) []Level {
+	// 1. 没有买单或卖单，则无成交机会
+	var buys, sells []Order
+	for _, order := range orders {
+		if order.Direction == 0 {
+			buys = append(buys, order)
+		} else {
+			sells = append(sells, order)
+		}
+	}
+	if len(buys) == 0 || len(sells) == 0 {
+		return nil
+	}
+
+	// 2. 最高买价低于最低卖价，则无成交机会
+	highestBid := buys[0].Price
+	for _, order := range buys {
+		highestBid = math.Max(highestBid, order.Price)
+	}
+	lowestAsk := sells[0].Price
+	for _, order := range sells {
+		lowestAsk = math.Min(lowestAsk, order.Price)
+	}
+	if ticks(highestBid, tick) < ticks(lowestAsk, tick) {
+		return nil
+	}
+
+	// 3. 按tick构造分价表，下标0为最低卖价
+	low := ticks(lowestAsk, tick)
+	size := ticks(highestBid, tick) - low + 1
+	levels := make([]Level, size)
+	for i := range levels {
+		levels[i].Price = float64(low+int64(i)) * tick
+	}
+
+	// 4. 汇总各档位的买卖量，超出分价表的订单不可能成交
+	for _, order := range orders {
+		i := ticks(order.Price, tick) - low
+		if i < 0 || i >= size {
+			continue
+		}
+		if order.Direction == 0 {
+			levels[i].BuyVolume += order.Volume
+		} else {
+			levels[i].SellVolume += order.Volume
+		}
+	}
+	accumulate(levels)
+	return levels
+}
+
+// BandedLadder 构造按价格分段的分价表：从最低卖价开始，每次加上当前价格所在价格段的tick，直到超过最高买价。
+// 订单价格须位于所在价格段的tick上，没有成交机会时返回nil
+func BandedLadder(orders []Order, bands []Band) []Level {
+	// 1. 没有买单或卖单，则无成交机会
+	var highestBid, lowestAsk int64
+	var hasBuy, hasSell bool
+	for _, order := range orders {
+		price := units(order.Price)
+		if order.Direction == 0 {
+			if !hasBuy || price > highestBid {
+				highestBid = price
+			}
+			hasBuy = true
+		} else {
+			if !hasSell || price < lowestAsk {
+				lowestAsk = price
+			}
+			hasSell = true
+		}
+	}
+	// 2. 最高买价低于最低卖价，则无成交机会
+	if !hasBuy || !hasSell || highestBid < lowestAsk {
+		return nil
+	}
+
+	// 3. 逐档构造分价表，下标0为最低卖价
+	var levels []Level
+	index := make(map[int64]int)
+	for price := lowestAsk; price <= highestBid; price += bandTick(price, bands) {
+		index[price] = len(levels)
+		levels = append(levels, Level{Price: float64(price) / UNIT})
+	}
+
+	// 4. 汇总各档位的买卖量，超出分价表的订单不可能成交
+	for _, order := range orders {
+		i, ok := index[units(order.Price)]
+		if !ok {
+			continue
+		}
+		if order.Direction == 0 {
+			levels[i].BuyVolume += order.Volume
+		} else {
+			levels[i].SellVolume += order.Volume
+		}
+	}
+	accumulate(levels)
+	return levels
+}
+
+// accumulate 按步骤5、6计算各档位的累计量、成交量和剩余量
+func accumulate(levels []Level) {
+	// 5. 累计买量包含所有更高价的买量，累计卖量包含所有更低价的卖量
+	for i := range levels {
+		for j := range levels {
+			if j >= i {
+				levels[i].AccumBuy += levels[j].BuyVolume
+			}
+			if j <= i {
+				levels[i].AccumSell += levels[j].SellVolume
+			}
+		}
+	}
+
+	// 6. 成交量为两者较小者，剩余量为两者差的绝对值
+	for i := range levels {
+		levels[i].MatchVolume = min(levels[i].AccumBuy, levels[i].AccumSell)
+		levels[i].RemainVolume = max(levels[i].AccumBuy, levels[i].AccumSell) - levels[i].MatchVolume
+	}
+}
+
+// ticks 将价格换算为tick数，价格应位于tick的整数倍上
+func ticks(price, tick float64) int64 {
+	return int64(math.Round(price / tick))
+}
+
+// UNIT 分段分价表中价格放大为万分之一单位的整数，避免逐档累加时的浮点误差
+const UNIT = 10000
+
+func units(price float64) int64 {
+	return int64(math.Round(price * UNIT))
+}
+
+// bandTick 返回价格（万分之一单位）所在价格段的tick，低于第一段起点的价格使用第一段的tick
+func bandTick(price int64, bands []Band) int64 {
+	tick := units(bands[0].Tick)
+	for _, band := range bands {
+		if price >= units(band.From) {
+			tick = units(band.Tick)
+		}
+	}
+	return tick
+}
@@ -0,0 +1,83 @@
+package order
+
+import (
+	"AuctionMatch/instrument"
+)
+
+type (
+	// LadderLevel 分价表中一个价格档位的统计
+	LadderLevel struct {
+		Price        float32
+		BuyVolume    int32 // 该价格的买单量
+		SellVolume   int32 // 该价格的卖单量
+		AccumBuy     int32 // 累计买入量（价格不低于该档位的买单）
+		AccumSell    int32 // 累计卖出量（价格不高于该档位的卖单）
+		MatchVolume  int32 // 成交量
+		RemainVolume int32 // 剩余量
+	}
+
+	// AuctionLadder 合约的完整分价表
+	AuctionLadder struct {
+		InstrumentID string
+		Tick         float32 // 最低价格段的tick，各价格段见合约的TickSchedule
+		Scale        uint
+		Levels       []LadderLevel // 从最高买价到最低卖价排列，价格不交叉时为空
+		Chosen       int           // 集合竞价价格所在档位下标，无成交时为-1
+	}
+)
+
+// Price 返回集合竞价价格，无成交时返回0
+func (ladder *AuctionLadder) Price() float32 {
+	if ladder.Chosen < 0 {
+		return 0
+	}
+	return ladder.Levels[ladder.Chosen].Price
+}
+
+// MatchVolume 返回集合竞价成交量
+func (ladder *AuctionLadder) MatchVolume() int32 {
+	if ladder.Chosen < 0 {
+		return 0
+	}
+	return ladder.Levels[ladder.Chosen].MatchVolume
+}
+
+// CalculateAuctionLadder 计算合约完整的分价表，并标记集合竞价价格所在档位
+// 选价规则与CalculateAuctionPrice一致
+func CalculateAuctionLadder(orders []Order) AuctionLadder {
+	if len(orders) == 0 {
+		return AuctionLadder{Chosen: -1}
+	}
+	ticks := orders[0].TickSchedule()
+	return auctionLadder(orders[0].InstrumentID, aggregateOrders(orders, ticks), ticks)
+}
+
+// auctionLadder 由已按档位汇总的买卖量计算分价表
+func auctionLadder(instrumentID string, priceMap *PriceLevelMap, ticks instrument.TickSchedule) AuctionLadder {
+	ladder := AuctionLadder{InstrumentID: instrumentID, Chosen: -1}
+	ladder.Tick = ticks.Tick(0)
+	ladder.Scale = ticks.Scale()
+
+	if !priceMap.crossed() {
+		return ladder
+	}
+
+	pricePoints := priceMap.pricePoints(ticks)
+	ladder.Levels = make([]LadderLevel, len(pricePoints))
+	ladder.Chosen = uncross(pricePoints, ticks, ladder.Levels)
+	return ladder
+}
+
+// CollectLadders 读取订单流，按合约首次出现顺序返回各合约的分价表
+func CollectLadders(stream *OrderStream, opts ...ProcessorOption) []AuctionLadder {
+	collector := newOrderCollector(newProcessorConfig(opts))
+	collector.collect(stream)
+
+	ladders := make([]AuctionLadder, len(collector.instruments))
+	for i, instrumentID := range collector.instruments {
+		book := collector.books[instrumentID]
+		ladders[i] = auctionLadder(instrumentID, book.levels, book.info.Ticks)
+		ladders[i].Scale = book.scale
+	}
+	return ladders
+}
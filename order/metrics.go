@@ -0,0 +1,141 @@
+package order
+
+import (
+	"AuctionMatch/metrics"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// 撮合流程的运行指标，注册在metrics.Default中。默认不以合约代码作标签：serve模式下合约代码来自客户端，
+// 按合约区分会使指标序列无限增长，需要时以EnableInstrumentMetrics开启并限定合约数。
+// 逐行计数在读取结束后一次累加，各合约的计算指标在worker内累计、每批合并一次，避免逐行逐合约加锁
+var (
+	LinesRead = metrics.NewCounter("auction_lines_read_total",
+		"读取的非空输入行数")
+	OrdersRejected = metrics.NewCounter("auction_orders_rejected_total",
+		"被拒绝的输入行数，reason为fields、无效的字段名、expired、not_listed或风控规则名", "reason")
+	OrdersAccepted = metrics.NewCounter("auction_orders_total",
+		"解析成功的订单数")
+	StageDuration = metrics.NewHistogram("auction_stage_duration_seconds",
+		"各处理阶段耗时：ingest读取输入，parse解析订单并按档位汇总，aggregate汇总CalculateAuctionPrice的订单，calculate计算价格",
+		metrics.DURATION_BUCKETS, "stage")
+	CalcDuration = metrics.NewHistogram("auction_calc_duration_seconds",
+		"单个合约集合竞价计算耗时，观测次数即计算的合约数", metrics.DURATION_BUCKETS)
+	LadderSize = metrics.NewHistogram("auction_ladder_levels",
+		"单个合约分价表的档位数", metrics.SIZE_BUCKETS)
+
+	InstrumentOrders = metrics.NewCounter("auction_instrument_orders_total",
+		"各合约参与计算的订单数，需EnableInstrumentMetrics开启", "instrument")
+	InstrumentCalcDuration = metrics.NewHistogram("auction_instrument_calc_duration_seconds",
+		"各合约集合竞价计算耗时，需EnableInstrumentMetrics开启", metrics.DURATION_BUCKETS, "instrument")
+	InstrumentLadderSize = metrics.NewHistogram("auction_instrument_ladder_levels",
+		"各合约分价表的档位数，需EnableInstrumentMetrics开启", metrics.SIZE_BUCKETS, "instrument")
+)
+
+// instrumentLabels 按合约区分指标时的标签限制，为nil表示不区分
+var instrumentLabels atomic.Pointer[metrics.LabelCap]
+
+const (
+	STAGE_INGEST    = "ingest"
+	STAGE_PARSE     = "parse"
+	STAGE_CONTRACT  = "contract" // 合约到期和挂牌检查，仅用于错误上下文
+	STAGE_RISK      = "risk"     // 事前风控检查，仅用于错误上下文
+	STAGE_AGGREGATE = "aggregate"
+	STAGE_CALCULATE = "calculate"
+	STAGE_APPLY     = "apply"  // 增量委托簿应用订单事件，仅用于错误上下文
+	STAGE_REPLAY    = "replay" // 按时间戳回放，仅用于错误上下文
+
+	REJECT_FIELDS = "fields" // 字段数不符
+)
+
+// EnableInstrumentMetrics 开启按合约区分的指标，输入中最先出现的limit个合约单独计数，其余归入metrics.OTHER_LABEL；
+// limit不大于0时关闭
+func EnableInstrumentMetrics(limit int) {
+	if limit <= 0 {
+		instrumentLabels.Store(nil)
+		return
+	}
+	instrumentLabels.Store(metrics.NewLabelCap(limit))
+}
+
+// reserveInstrumentLabel 合约首次出现时占用标签名额，使单独计数的合约按输入顺序确定，而不取决于并行计算的先后
+func reserveInstrumentLabel(instrumentID string) {
+	if labels := instrumentLabels.Load(); labels != nil {
+		labels.Value(instrumentID)
+	}
+}
+
+// calcMetrics 一个worker内累计的计算指标，flush时一次合并，不可并发使用
+type calcMetrics struct {
+	duration, ladder, stage *metrics.HistogramBatch
+	instrumentDuration      *metrics.HistogramBatch
+	instrumentLadder        *metrics.HistogramBatch
+	instrumentOrders        map[string]int
+	labels                  *metrics.LabelCap
+}
+
+func newCalcMetrics() *calcMetrics {
+	m := &calcMetrics{
+		duration: CalcDuration.NewBatch(),
+		ladder:   LadderSize.NewBatch(),
+		stage:    StageDuration.NewBatch(),
+		labels:   instrumentLabels.Load(),
+	}
+	if m.labels != nil {
+		m.instrumentDuration = InstrumentCalcDuration.NewBatch()
+		m.instrumentLadder = InstrumentLadderSize.NewBatch()
+		m.instrumentOrders = make(map[string]int)
+	}
+	return m
+}
+
+// observe 记录一个合约的计算耗时、分价表档位数和订单数，crossed表示买卖价交叉、计入calculate阶段
+func (m *calcMetrics) observe(instrumentID string, orders, levels int, seconds float64, crossed bool) {
+	m.duration.Observe(seconds)
+	m.ladder.Observe(float64(levels))
+	if crossed {
+		m.stage.Observe(seconds, STAGE_CALCULATE)
+	}
+	if m.labels == nil {
+		return
+	}
+	label := m.labels.Value(instrumentID)
+	m.instrumentDuration.Observe(seconds, label)
+	m.instrumentLadder.Observe(float64(levels), label)
+	m.instrumentOrders[label] += orders
+}
+
+// flush 将累计的指标合并到全局指标
+func (m *calcMetrics) flush() {
+	m.duration.Flush()
+	m.ladder.Flush()
+	m.stage.Flush()
+	if m.labels == nil {
+		return
+	}
+	m.instrumentDuration.Flush()
+	m.instrumentLadder.Flush()
+	for label, orders := range m.instrumentOrders {
+		InstrumentOrders.Add(float64(orders), label)
+	}
+	clear(m.instrumentOrders)
+}
+
+// observeStage 记录从start起的阶段耗时
+func observeStage(stage string, start time.Time) {
+	StageDuration.Observe(time.Since(start).Seconds(), stage)
+}
+
+// rejectReason 拒绝的原因，用作rejected指标的标签：解析失败为字段名，其他错误可实现Reason方法给出原因
+func rejectReason(err error) string {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Field
+	}
+	var reasoned interface{ Reason() string }
+	if errors.As(err, &reasoned) {
+		return reasoned.Reason()
+	}
+	return "unknown"
+}
@@ -1,7 +1,10 @@
 package order
 
 import (
-	"AuctionMatch/consts"
+	"AuctionMatch/instrument"
+	"math"
+	"strconv"
+	"strings"
 	"sync"
 )
 
@@ -12,6 +15,8 @@ type (
 		Error    chan error
 		Done     chan struct{}
 		ChunkNum uint
+		Err      error // 打开或读取输入失败的原因，Done关闭后可读
+		Line     int   // ReadOrders当前处理的行号（从1开始），供回调上报错误时使用
 	}
 	// Order 订单
 	Order struct {
@@ -19,6 +24,9 @@ type (
 		Direction    int8 // 0:买, 1:卖
 		Price        float32
 		Volume       int32
+		HasTime      bool   // 输入中是否带有时间戳，用于区分没有时间戳和00:00:00
+		Time         int64  // 报单时刻，当日零点起的纳秒数，HasTime为false时为0
+		Account      string // 资金账号/客户号，用于自成交防范，为空表示不参与
 	}
 	// PriceLevel 价格档位信息
 	PriceLevel struct {
@@ -37,15 +45,16 @@ type (
 	PriceLevelMap struct {
 		buyLevels  map[int64]int32 // 买单价格档位
 		sellLevels map[int64]int32 // 卖单价格档位
-		highestBid float32         // 最高买单价格
-		lowestAsk  float32         // 最低卖单价格
+		highestBid float32         // 最高买单价格，没有买单时为-Inf
+		lowestAsk  float32         // 最低卖单价格，没有卖单时为+Inf
 		sync.RWMutex
 	}
 )
 
 const (
-	PRICE_TICK   = 0.2 // 价格最小变动单位
-	WORKER_COUNT = 4   // 并发工作协程数
+	PRICE_TICK   = instrument.DEFAULT_TICK // 参考数据中没有品种时的价格最小变动单位
+	PRICE_UNIT   = instrument.PRICE_UNIT   // 价格整数化的放大倍数
+	WORKER_COUNT = 4                       // 并发工作协程数
 )
 
 func NewOrderStream() *OrderStream {
@@ -60,26 +69,45 @@ func NewPriceLevelMap() *PriceLevelMap {
 	return &PriceLevelMap{
 		buyLevels:  make(map[int64]int32),
 		sellLevels: make(map[int64]int32),
-		highestBid: -1,
-		lowestAsk:  -1,
+		highestBid: float32(math.Inf(-1)),
+		lowestAsk:  float32(math.Inf(1)),
 	}
 }
 
+// Instrument 返回合约的解析结果和参考数据，同一合约只解析一次，见instrument.Default
+func (order *Order) Instrument() *instrument.Info {
+	return instrument.Default.Lookup(order.InstrumentID)
+}
+
+// ProductCode 返回合约的品种代码（例如从"IF2306"、"IO2306-C-3900"中提取"IF"、"IO"），组合合约取第一腿的品种代码
+func (order *Order) ProductCode() string {
+	return order.Instrument().Product
+}
+
+// TickSchedule 返回合约按价格分段的tick
+func (order *Order) TickSchedule() instrument.TickSchedule {
+	return order.Instrument().Ticks
+}
+
+// GetTick 返回订单价格所在价格段的tick
 func (order *Order) GetTick() float32 {
-	// 从合约ID中提取品种代码（例如从"IF2306"中提取"IF"）
-	var productCode string
-	for i, c := range order.InstrumentID {
-		if i >= 2 || !((c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')) {
-			break
-		}
-		productCode += string(c)
-	}
+	return order.TickSchedule().Tick(order.Price)
+}
 
-	// 从CFE_PRODUCT_TICK中获取对应的tick值
-	if tick, ok := consts.CFE_PRODUCT_TICK[productCode]; ok {
-		return tick
-	}
+// GetScale 返回合约各价格段tick中最大的小数位数
+func (order *Order) GetScale() uint {
+	return order.TickSchedule().Scale()
+}
 
-	// 如果找不到对应的tick值，返回默认值0.2
-	return PRICE_TICK
+// ScaleOfTick 计算tick的小数位数，例如0.2为1位、0.005为3位
+func ScaleOfTick(tick float32) uint {
+	return PriceScale(strconv.FormatFloat(float64(tick), 'f', -1, 32))
+}
+
+// PriceScale 计算价格文本中的小数位数
+func PriceScale(price string) uint {
+	if dotIndex := strings.Index(price, "."); dotIndex != -1 {
+		return uint(len(price) - dotIndex - 1)
+	}
+	return 0
 }
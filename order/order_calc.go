@@ -1,102 +1,183 @@
 package order
 
 import (
+	"AuctionMatch/instrument"
 	"AuctionMatch/utils"
 	"math"
+	"time"
 )
 
 type PricePoint struct {
-	price      int64 // 价格（以tick为单位）
+	price      int64 // 价格（档位序号，见TickSchedule）
 	buyVolume  int32 // 该价格的买单量
 	sellVolume int32 // 该价格的卖单量
 }
 
 // 工具函数
+// tick放大为万分之一单位的整数参与计算，避免float32乘法带来的误差。按四舍五入取档位：
+// 在tick上的价格由float32表示时可能略小于tick的整数倍（如0.195），截断会落到低一档；不在tick上的价格取最近的档位
 func ToInt(price float32, tick float32) int64 {
-	return int64(price*10000) / int64(tick*10000)
+	return int64(math.Round(float64(price) * PRICE_UNIT / float64(tickUnits(tick))))
 }
 
 func ToFloat(priceInt int64, tick float32) float32 {
-	return float32(priceInt) * tick
+	return float32(float64(priceInt*tickUnits(tick)) / PRICE_UNIT)
 }
 
-// 集合竞价计算函数
-func CalculateAuctionPrice(orders []Order) float32 {
-	if len(orders) == 0 {
-		return 0
-	}
+func tickUnits(tick float32) int64 {
+	return int64(math.Round(float64(tick) * PRICE_UNIT))
+}
 
+// aggregateOrders 按档位汇总买卖量，并记录最高买价和最低卖价
+func aggregateOrders(orders []Order, ticks instrument.TickSchedule) *PriceLevelMap {
 	priceMap := NewPriceLevelMap()
-	tick := orders[0].GetTick()
-
 	for _, order := range orders {
-		// 转为tick数
-		priceInt := ToInt(order.Price, tick)
-
-		if order.Direction == 0 { // 买单
-			priceMap.buyLevels[priceInt] += order.Volume
-			// 维护最高买价
-			if priceMap.highestBid == -1 || order.Price > priceMap.highestBid {
-				priceMap.highestBid = order.Price
-			}
-		} else { // 卖单
-			priceMap.sellLevels[priceInt] += order.Volume
-			// 维护最低卖价
-			if priceMap.lowestAsk == -1 || order.Price < priceMap.lowestAsk {
-				priceMap.lowestAsk = order.Price
-			}
-		}
+		priceMap.add(order, ticks)
 	}
+	return priceMap
+}
 
-	lowestPriceInt := ToInt(priceMap.lowestAsk, tick)
-	highestPriceInt := ToInt(priceMap.highestBid, tick)
+// add 将一笔订单计入所在档位
+func (priceMap *PriceLevelMap) add(order Order, ticks instrument.TickSchedule) {
+	// 转为档位序号
+	priceInt := ticks.ToInt(order.Price)
 
-	// 如果最高买价低于最低卖价，则没有成交
-	if priceMap.highestBid < priceMap.lowestAsk ||
-		priceMap.highestBid == -1 ||
-		priceMap.lowestAsk == -1 {
-		return 0
+	if order.Direction == 0 { // 买单
+		priceMap.buyLevels[priceInt] += order.Volume
+		// 维护最高买价（组合合约的价格可以为负）
+		if order.Price > priceMap.highestBid {
+			priceMap.highestBid = order.Price
+		}
+	} else { // 卖单
+		priceMap.sellLevels[priceInt] += order.Volume
+		// 维护最低卖价
+		if order.Price < priceMap.lowestAsk {
+			priceMap.lowestAsk = order.Price
+		}
 	}
+}
 
-	var maxMatchVolume int32 = -1
-	var minRemainVolume int32 = math.MaxInt32
-	var bestPrice int64
+// crossed 判断买卖价格是否交叉，任一方没有订单时不交叉
+func (priceMap *PriceLevelMap) crossed() bool {
+	return priceMap.highestBid >= priceMap.lowestAsk
+}
 
-	var accumBuy int32 = 0
-	var accumSell int32 = 0
+// pricePoints 构造完整的分价表（从最高买到最低卖），跨价格段时相邻档位的价差随所在段的tick变化
+func (priceMap *PriceLevelMap) pricePoints(ticks instrument.TickSchedule) []PricePoint {
+	lowestPriceInt := ticks.ToInt(priceMap.lowestAsk)
+	highestPriceInt := ticks.ToInt(priceMap.highestBid)
 
-	// 构造完整的分价表
-	pricePoints := make([]PricePoint, 0)
+	pricePoints := make([]PricePoint, 0, highestPriceInt-lowestPriceInt+1)
 	for priceInt := highestPriceInt; priceInt >= lowestPriceInt; priceInt-- {
 		pricePoints = append(pricePoints, PricePoint{
 			price:      priceInt,
 			buyVolume:  priceMap.buyLevels[priceInt],
 			sellVolume: priceMap.sellLevels[priceInt],
 		})
-		accumSell += priceMap.sellLevels[priceInt]
 	}
+	return pricePoints
+}
 
-	// 从高到低遍历所有价格点
+// betterMatch 按“最大成交量、最小剩余量、最高价格”判断当前档位是否优于已选档位
+func betterMatch(matchVolume, remainVolume int32, price int64,
+	maxMatchVolume, minRemainVolume int32, bestPrice int64) bool {
+	return matchVolume > maxMatchVolume ||
+		(matchVolume == maxMatchVolume && remainVolume < minRemainVolume) ||
+		(matchVolume == maxMatchVolume && remainVolume == minRemainVolume && price > bestPrice)
+}
+
+// 集合竞价计算函数，同时记录该合约的计算耗时和分价表档位数，无成交时返回0；
+// 组合合约可以在0价成交，需要区分时使用CalculateAuction
+func CalculateAuctionPrice(orders []Order) float32 {
+	price, _ := CalculateAuction(orders)
+	return price
+}
+
+// CalculateAuction 计算集合竞价价格，matched表示是否有成交
+func CalculateAuction(orders []Order) (price float32, matched bool) {
+	m := newCalcMetrics()
+	defer m.flush()
+	return calculateAuction(orders, m)
+}
+
+// calculateAuction 同CalculateAuction，计算指标记入m，供一批合约共用
+func calculateAuction(orders []Order, m *calcMetrics) (price float32, matched bool) {
+	if len(orders) == 0 {
+		return 0, false
+	}
+	ticks := orders[0].TickSchedule()
+	start := time.Now()
+	priceMap := aggregateOrders(orders, ticks)
+	observeStage(STAGE_AGGREGATE, start)
+	return auctionPrice(orders[0].InstrumentID, len(orders), priceMap, ticks, m)
+}
+
+// auctionPrice 由已按档位汇总的买卖量计算集合竞价价格，matched表示是否有成交；
+// 计算指标记入m，orders为参与计算的订单数
+func auctionPrice(instrumentID string, orders int, priceMap *PriceLevelMap, ticks instrument.TickSchedule,
+	m *calcMetrics) (price float32, matched bool) {
+	start := time.Now()
+
+	// 如果最高买价低于最低卖价，则没有成交
+	if !priceMap.crossed() {
+		m.observe(instrumentID, orders, 0, time.Since(start).Seconds(), false)
+		return 0, false
+	}
+
+	// 构造完整的分价表
+	pricePoints := priceMap.pricePoints(ticks)
+	chosen := uncross(pricePoints, ticks, nil)
+	m.observe(instrumentID, orders, len(pricePoints), time.Since(start).Seconds(), true)
+	if chosen < 0 {
+		return 0, false
+	}
+	return ticks.ToFloat(pricePoints[chosen].price), true
+}
+
+// uncross 从最高买价到最低卖价遍历分价表，按“最大成交量、最小剩余量、最高价格”选出集合竞价档位，
+// 返回其下标，无成交时为-1。levels不为nil时同时填入各档位的统计，长度须与pricePoints相同
+func uncross(pricePoints []PricePoint, ticks instrument.TickSchedule, levels []LadderLevel) int {
+	var accumSell int32 = 0
 	for _, pp := range pricePoints {
+		accumSell += pp.sellVolume
+	}
+
+	chosen := -1
+	var maxMatchVolume int32 = -1
+	var minRemainVolume int32 = math.MaxInt32
+	var bestPrice int64
+	var accumBuy int32 = 0
+
+	// 从高到低遍历所有价格点
+	for i, pp := range pricePoints {
 		accumBuy += pp.buyVolume
 
 		matchVolume := utils.Min(accumBuy, accumSell)
 		remainVolume := utils.Abs(accumBuy - accumSell)
+		if levels != nil {
+			levels[i] = LadderLevel{
+				Price:        ticks.ToFloat(pp.price),
+				BuyVolume:    pp.buyVolume,
+				SellVolume:   pp.sellVolume,
+				AccumBuy:     accumBuy,
+				AccumSell:    accumSell,
+				MatchVolume:  matchVolume,
+				RemainVolume: remainVolume,
+			}
+		}
 
-		if matchVolume > maxMatchVolume ||
-			(matchVolume == maxMatchVolume && remainVolume < minRemainVolume) ||
-			(matchVolume == maxMatchVolume && remainVolume == minRemainVolume && pp.price > bestPrice) {
+		if betterMatch(matchVolume, remainVolume, pp.price, maxMatchVolume, minRemainVolume, bestPrice) {
 			maxMatchVolume = matchVolume
 			minRemainVolume = remainVolume
 			bestPrice = pp.price
+			chosen = i
 		}
 
 		accumSell -= pp.sellVolume
 	}
 
 	if maxMatchVolume <= 0 {
-		return 0
+		return -1
 	}
-
-	return ToFloat(bestPrice, tick)
+	return chosen
 }
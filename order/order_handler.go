@@ -1,11 +1,17 @@
 package order
 
 import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/utils"
 	"bufio"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // 处理器接口
@@ -14,41 +20,173 @@ type (
 		InstrumentID string
 		Price        float32
 		Scale        uint // 精度
+		Matched      bool // 是否有成交；组合合约可以在0价成交，不能以价格为0判断无成交
+
+		PreventedVolume int32 // 被自成交防范撤销或扣减的量，未开启自成交防范时为0，见AllocateFills
 	}
 	OrderProcessor interface {
 		Process(stream *OrderStream) []ProcessResult
 	}
+
+	// ScaleMode 输出价格精度的确定方式
+	ScaleMode int
+
+	// ProcessorOption 处理器可选配置
+	ProcessorOption func(*processorConfig)
+
+	// PreTradeCheck 订单进入委托簿前的检查，返回error时拒绝该订单
+	PreTradeCheck func(order Order) error
+
+	processorConfig struct {
+		scaleMode ScaleMode
+		check     PreTradeCheck
+		implied   bool
+		tradable  bool
+		stp       SelfTradePrevention
+	}
+
+	// ParseError 订单字段解析失败
+	ParseError struct {
+		Field string // 字段名：instrument、direction、price、volume、time
+		Value string // 原始文本
+	}
+
+	// RecordError 输入行处理失败，附带行号、合约和所处阶段，供日志输出上下文
+	RecordError struct {
+		Line         int
+		InstrumentID string
+		Stage        string // 见STAGE_*
+		Err          error
+	}
+)
+
+const (
+	ScaleByTick  ScaleMode = iota // 按合约tick的小数位数输出
+	ScaleByInput                  // 按输入中该合约出现过的最大小数位数输出
 )
 
+// WithScaleMode 设置输出价格精度的确定方式，默认按tick
+func WithScaleMode(mode ScaleMode) ProcessorOption {
+	return func(c *processorConfig) {
+		c.scaleMode = mode
+	}
+}
+
+// FormatPrice 按精度格式化价格，无成交时为空串
+func (r ProcessResult) FormatPrice() string {
+	if !r.Matched {
+		return ""
+	}
+	return strconv.FormatFloat(float64(r.Price), 'f', int(r.Scale), 32)
+}
+
+// WithPreTradeCheck 在解析之后、汇总之前按输入顺序逐笔检查订单，被拒绝的订单不参与集合竞价
+func WithPreTradeCheck(check PreTradeCheck) ProcessorOption {
+	return func(c *processorConfig) {
+		c.check = check
+	}
+}
+
+// WithImpliedSpreads 由单腿委托簿推导两腿组合合约的隐含买卖单，加入组合合约的集合竞价；
+// 只对输入中出现过的组合合约推导。隐含单是指示性的，不从单腿委托簿中扣除，见impliedOrders
+func WithImpliedSpreads() ProcessorOption {
+	return func(c *processorConfig) {
+		c.implied = true
+	}
+}
+
+// WithTradableCheck 按instrument.Default的交易日和交易日历拒绝已到期或尚未挂牌的合约的订单，
+// 在事前风控检查之前进行
+func WithTradableCheck() ProcessorOption {
+	return func(c *processorConfig) {
+		c.tradable = true
+	}
+}
+
+// WithSelfTradeCheck 按stp在成交价上逐笔分配成交，报告各合约被防范的自成交量（ProcessResult.PreventedVolume），
+// 成交价不变。开启后逐笔保留订单，volume为负数的撤单事件不参与分配；与CallSession的WithSelfTradePrevention对应
+func WithSelfTradeCheck(stp SelfTradePrevention) ProcessorOption {
+	return func(c *processorConfig) {
+		c.stp = stp
+	}
+}
+
+func newProcessorConfig(opts []ProcessorOption) processorConfig {
+	var config processorConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
 // 创建处理器工厂函数
-func NewOrderProcessor(numCPU int) OrderProcessor {
+func NewOrderProcessor(numCPU int, opts ...ProcessorOption) OrderProcessor {
+	config := newProcessorConfig(opts)
+
 	if numCPU <= 1 {
-		return &SingleProcessor{}
+		return &SingleProcessor{config: config}
 	} else {
-		return &ParallelProcessor{numWorkers: numCPU}
+		return &ParallelProcessor{numWorkers: numCPU, config: config}
 	}
 }
 
-// 辅助函数：验证记录的有效性
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("无效的%s值: %s", e.Field, e.Value)
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("第%d行(%s)出错: %v", e.Line, e.Stage, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Reject 以当前行号上报一行处理失败，只能在ReadOrders的回调中调用
+func (stream *OrderStream) Reject(stage, instrumentID string, err error) {
+	stream.Error <- &RecordError{Line: stream.Line, InstrumentID: instrumentID, Stage: stage, Err: err}
+}
+
+// 辅助函数：验证记录的有效性，第5列时间戳和第6列账号可选
 func IsValidRecord(record []string) bool {
-	return len(record) == 4
+	return len(record) >= 4 && len(record) <= 6
 }
 
 // 辅助函数：解析订单数据
 func ParseOrder(record []string) (Order, error) {
+	if instrument.IsCombination(record[0]) {
+		if _, ok := instrument.ParseCombination(record[0]); !ok {
+			return Order{}, &ParseError{Field: "instrument", Value: record[0]}
+		}
+	}
+
 	direction, err := strconv.Atoi(record[1])
 	if err != nil || (direction != 0 && direction != 1) {
-		return Order{}, fmt.Errorf("无效的direction值: %s", record[1])
+		return Order{}, &ParseError{Field: "direction", Value: record[1]}
 	}
 
-	price, err := strconv.ParseFloat(record[2], 64)
-	if err != nil {
-		return Order{}, fmt.Errorf("无效的price值: %s", record[2])
+	// 价格须为有限值，且转为float32后不溢出
+	price, err := strconv.ParseFloat(record[2], 32)
+	if err != nil || math.IsNaN(price) || math.IsInf(price, 0) {
+		return Order{}, &ParseError{Field: "price", Value: record[2]}
 	}
 
-	volume, err := strconv.Atoi(record[3])
+	volume, err := strconv.ParseInt(record[3], 10, 32)
 	if err != nil {
-		return Order{}, fmt.Errorf("无效的volume值: %s", record[3])
+		return Order{}, &ParseError{Field: "volume", Value: record[3]}
+	}
+
+	var timestamp int64
+	hasTime := len(record) > 4 && record[4] != ""
+	if hasTime {
+		if timestamp, err = ParseTimeOfDay(record[4]); err != nil {
+			return Order{}, &ParseError{Field: "time", Value: record[4]}
+		}
+	}
+
+	var account string
+	if len(record) > 5 {
+		account = record[5]
 	}
 
 	return Order{
@@ -56,34 +194,87 @@ func ParseOrder(record []string) (Order, error) {
 		Direction:    int8(direction),
 		Price:        float32(price),
 		Volume:       int32(volume),
+		HasTime:      hasTime,
+		Time:         timestamp,
+		Account:      account,
 	}, nil
 }
 
+// ReadOrders 读取订单流直至关闭，对每笔解析成功的订单回调handle，record为原始字段；
+// 空行和字段数不符的行被忽略，解析失败的行以RecordError通过stream.Error上报
+func ReadOrders(stream *OrderStream, handle func(order Order, record []string)) {
+	start := time.Now()
+	defer observeStage(STAGE_PARSE, start)
+
+	accepted := 0
+	defer func() {
+		OrdersAccepted.Add(float64(accepted))
+	}()
+	for line := range stream.Orders {
+		stream.Line++
+		if line == "" {
+			continue
+		}
+		record := utils.CustomSplit(line)
+		if !IsValidRecord(record) {
+			OrdersRejected.Inc(REJECT_FIELDS)
+			slog.Debug("字段数不符，忽略该行", "line", stream.Line, "stage", STAGE_PARSE, "fields", len(record))
+			continue
+		}
+		order, err := ParseOrder(record)
+		if err != nil {
+			OrdersRejected.Inc(rejectReason(err))
+			stream.Reject(STAGE_PARSE, record[0], fmt.Errorf("解析订单出错: %w", err))
+			continue
+		}
+		accepted++
+		handle(order, record)
+	}
+
+	slog.Debug("订单流读取结束", "stage", STAGE_PARSE, "lines", stream.Line, "elapsed", time.Since(start))
+}
+
 // streamOrders 流式读取CSV文件
 func StreamOrders(filename string) *OrderStream {
+	file, err := os.Open(filename)
+	if err != nil {
+		stream := NewOrderStream()
+		stream.Err = fmt.Errorf("无法打开文件: %v", err)
+		close(stream.Orders)
+		close(stream.Done)
+		return stream
+	}
+	return StreamReader(file)
+}
+
+// StreamReader 流式读取CSV内容，每行去除首尾空白后发送（空行也发送以保持行号），读取结束后若r实现了io.Closer则将其关闭
+func StreamReader(r io.Reader) *OrderStream {
 	stream := NewOrderStream()
 
 	go func() {
 		defer close(stream.Orders)
 		// defer close(stream.Error)
 		defer close(stream.Done)
+		if closer, ok := r.(io.Closer); ok {
+			defer closer.Close()
+		}
 
-		file, _ := os.Open(filename)
-		// if err != nil {
-		// 	stream.Error <- fmt.Errorf("无法打开文件: %v", err)
-		// 	return
-		// }
-		defer file.Close()
+		defer observeStage(STAGE_INGEST, time.Now())
 
-		scanner := bufio.NewScanner(file)
+		lines := 0
+		defer func() {
+			LinesRead.Add(float64(lines))
+		}()
+		scanner := bufio.NewScanner(r)
 		for scanner.Scan() {
 			line := strings.TrimSpace(scanner.Text())
-			if line == "" {
-				continue
+			if line != "" {
+				lines++
 			}
 			// 发送订单到channel
 			stream.Orders <- line
 		}
+		stream.Err = scanner.Err()
 	}()
 
 	return stream
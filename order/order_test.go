@@ -1,6 +1,16 @@
 package order
 
-import "testing"
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/metrics"
+	"AuctionMatch/refdata"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestGetTick(t *testing.T) {
 	// 定义测试用例结构体
@@ -15,6 +25,10 @@ func TestGetTick(t *testing.T) {
 		{input: "IH2306", want: 0.2},
 		{input: "IC2306", want: 0.2},
 		{input: "TS2306", want: 0.002},
+		{input: "IO2306-C-3900", want: 0.2},
+		{input: "m2409-P-3000", want: 0.5},
+		{input: "SR501C5000", want: 0.5},
+		{input: "m2409", want: 1},
 	}
 
 	// 运行测试用例
@@ -25,3 +39,464 @@ func TestGetTick(t *testing.T) {
 		}
 	}
 }
+
+func TestScaleOfTick(t *testing.T) {
+	tests := []struct {
+		tick float32
+		want uint
+	}{
+		{tick: 0.2, want: 1},
+		{tick: 0.002, want: 3},
+		{tick: 0.005, want: 3},
+		{tick: 0.01, want: 2},
+		{tick: 1, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := ScaleOfTick(tt.tick); got != tt.want {
+			t.Errorf("ScaleOfTick(%v) = %v, want %v", tt.tick, got, tt.want)
+		}
+	}
+}
+
+// TestToInt 价格按四舍五入换算为tick数：在tick上的价格由float32表示时可能略小于tick的整数倍，
+// 截断会落到低一档；不在tick上的价格取最近的档位，与instrument.TickSchedule一致
+func TestToInt(t *testing.T) {
+	tests := []struct {
+		price string
+		tick  float32
+		want  int64
+	}{
+		{price: "0.195", tick: 0.005, want: 39}, // 截断为38
+		{price: "26845", tick: 1, want: 26845},  // 截断为26844
+		{price: "13422.5", tick: 0.5, want: 26845},
+		{price: "26844.6", tick: 0.2, want: 134223},
+		{price: "3973.4", tick: 0.2, want: 19867},
+		{price: "3973.25", tick: 0.2, want: 19866}, // 不在tick上，取最近的3973.2
+		{price: "3973.35", tick: 0.2, want: 19867}, // 不在tick上，取最近的3973.4
+		{price: "-12.4", tick: 1, want: -12},
+	}
+
+	for _, tt := range tests {
+		order, err := ParseOrder([]string{"ZZ2412", "0", tt.price, "1"})
+		if err != nil {
+			t.Fatalf("ParseOrder(%s) error = %v", tt.price, err)
+		}
+		if got := ToInt(order.Price, tt.tick); got != tt.want {
+			t.Errorf("ToInt(%s, %v) = %d, want %d", tt.price, tt.tick, got, tt.want)
+		}
+		if got := instrument.NewTickSchedule(tt.tick, nil).ToInt(order.Price); got != tt.want {
+			t.Errorf("TickSchedule(%v).ToInt(%s) = %d, want %d", tt.tick, tt.price, got, tt.want)
+		}
+	}
+}
+
+// newTestStream 用给定的行构造订单流
+func newTestStream(lines []string) *OrderStream {
+	stream := NewOrderStream()
+	go func() {
+		defer close(stream.Orders)
+		defer close(stream.Done)
+		for _, line := range lines {
+			stream.Orders <- line
+		}
+	}()
+	go func() {
+		for range stream.Error {
+		}
+	}()
+	return stream
+}
+
+func TestProcessScale(t *testing.T) {
+	lines := []string{
+		"IF2412,0,3973,3",
+		"TS2412,0,101.234,1",
+		"IF2412,1,3973.40,2",
+		"IF2412,0,3973.4,1",
+		"TS2412,1,101.23,1",
+	}
+
+	tests := []struct {
+		name string
+		mode ScaleMode
+		want []ProcessResult
+	}{
+		{
+			name: "按tick",
+			mode: ScaleByTick,
+			want: []ProcessResult{
+				{InstrumentID: "IF2412", Price: 3973.4, Scale: 1, Matched: true},
+				{InstrumentID: "TS2412", Price: 101.234, Scale: 3, Matched: true},
+			},
+		},
+		{
+			name: "按输入最大精度",
+			mode: ScaleByInput,
+			want: []ProcessResult{
+				{InstrumentID: "IF2412", Price: 3973.4, Scale: 2, Matched: true},
+				{InstrumentID: "TS2412", Price: 101.234, Scale: 3, Matched: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, numCPU := range []int{1, 4} {
+			t.Run(fmt.Sprintf("%s/cpu=%d", tt.name, numCPU), func(t *testing.T) {
+				processor := NewOrderProcessor(numCPU, WithScaleMode(tt.mode))
+				got := processor.Process(newTestStream(lines))
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Process() = %+v, want %+v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestCalculateAuctionLadder(t *testing.T) {
+	orders := []Order{
+		{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3},
+		{InstrumentID: "IF2412", Direction: 0, Price: 3973.2, Volume: 2},
+		{InstrumentID: "IF2412", Direction: 1, Price: 3973.0, Volume: 4},
+	}
+
+	ladder := CalculateAuctionLadder(orders)
+	want := []LadderLevel{
+		{Price: 3973.4, BuyVolume: 3, AccumBuy: 3, AccumSell: 4, MatchVolume: 3, RemainVolume: 1},
+		{Price: 3973.2, BuyVolume: 2, AccumBuy: 5, AccumSell: 4, MatchVolume: 4, RemainVolume: 1},
+		{Price: 3973.0, SellVolume: 4, AccumBuy: 5, AccumSell: 4, MatchVolume: 4, RemainVolume: 1},
+	}
+	if !reflect.DeepEqual(ladder.Levels, want) {
+		t.Errorf("Levels = %+v, want %+v", ladder.Levels, want)
+	}
+	if ladder.Chosen != 1 || ladder.Price() != CalculateAuctionPrice(orders) {
+		t.Errorf("Chosen = %d, Price() = %v, want 1, %v", ladder.Chosen, ladder.Price(), CalculateAuctionPrice(orders))
+	}
+
+	noCross := CalculateAuctionLadder(orders[:2])
+	if len(noCross.Levels) != 0 || noCross.Chosen != -1 {
+		t.Errorf("无卖单时 Levels = %+v, Chosen = %d", noCross.Levels, noCross.Chosen)
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{input: "08:58:30", want: "08:58:30", ok: true},
+		{input: "08:58:30.5", want: "08:58:30.500", ok: true},
+		{input: "21:00:00.123456789", want: "21:00:00.123", ok: true},
+		{input: "8:58", ok: false},
+		{input: "24:00:00", ok: false},
+		{input: "08:58:3", ok: false},
+	}
+
+	for _, tt := range tests {
+		nanos, err := ParseTimeOfDay(tt.input)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseTimeOfDay(%q) error = %v", tt.input, err)
+			continue
+		}
+		if tt.ok && FormatTimeOfDay(nanos) != tt.want {
+			t.Errorf("FormatTimeOfDay(ParseTimeOfDay(%q)) = %q, want %q", tt.input, FormatTimeOfDay(nanos), tt.want)
+		}
+	}
+}
+
+func TestReplayByCutoff(t *testing.T) {
+	lines := []string{
+		"IF2412,0,3973.4,3,08:55:01",
+		"TS2412,0,101.2,1,08:56:00",
+		"IF2412,1,3973.0,2,08:58:40",
+		"IF2412,1,3972.0,5,08:57:00",
+		"IF2412,1,3972.0,-5,08:58:45",
+		"IF2412,0,3973.4,1",
+		"TS2412,1,101.2,1,00:00:00",
+	}
+	timed := ReadTimedOrders(newTestStream(lines))
+	cutoff := func(s string) int64 {
+		nanos, _ := ParseTimeOfDay(s)
+		return nanos
+	}
+	// 00:00:00的订单是有效时间戳，排在最前；没有时间戳的订单被忽略
+	if first, last, ok := timed.Span(); !ok || first != 0 || last != cutoff("08:58:45") {
+		t.Errorf("Span() = %d, %d, %v", first, last, ok)
+	}
+
+	results, errs := timed.Replay([]int64{cutoff("08:59:00"), cutoff("08:56:30"), cutoff("08:58:30"), cutoff("08:58:30")})
+	if len(errs) != 0 {
+		t.Errorf("errs = %v", errs)
+	}
+	want := []CutoffResult{
+		{Cutoff: cutoff("08:56:30"), Results: []ProcessResult{
+			{InstrumentID: "TS2412", Price: 101.2, Scale: 3, Matched: true}, {InstrumentID: "IF2412", Scale: 1}}},
+		{Cutoff: cutoff("08:58:30"), Results: []ProcessResult{
+			{InstrumentID: "TS2412", Price: 101.2, Scale: 3, Matched: true}, {InstrumentID: "IF2412", Price: 3973.4, Scale: 1, Matched: true}}},
+		{Cutoff: cutoff("08:59:00"), Results: []ProcessResult{
+			{InstrumentID: "TS2412", Price: 101.2, Scale: 3, Matched: true}, {InstrumentID: "IF2412", Price: 3973.4, Scale: 1, Matched: true}}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Replay() = %+v, want %+v", results, want)
+	}
+
+	if got := EveryCutoff(cutoff("08:55:01"), cutoff("08:58:45"), int64(cutoff("00:01:00"))); len(got) != 4 ||
+		got[0] != cutoff("08:56:00") || got[3] != cutoff("08:59:00") {
+		t.Errorf("EveryCutoff() = %v", got)
+	}
+}
+
+func TestReadOrdersRecordError(t *testing.T) {
+	stream := StreamReader(strings.NewReader("IF2412,0,3973.4,3\n\nIF2412,1,x,2\nIF2412,1\nTS2412,2,101.2,1\n"))
+	var errs []error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range stream.Error {
+			errs = append(errs, err)
+		}
+	}()
+
+	count := 0
+	ReadOrders(stream, func(order Order, record []string) { count++ })
+	<-stream.Done
+	close(stream.Error)
+	<-done
+
+	want := []RecordError{
+		{Line: 3, InstrumentID: "IF2412", Stage: STAGE_PARSE},
+		{Line: 5, InstrumentID: "TS2412", Stage: STAGE_PARSE},
+	}
+	if count != 1 || len(errs) != len(want) {
+		t.Fatalf("count = %d, errs = %v", count, errs)
+	}
+	for i, err := range errs {
+		var recordErr *RecordError
+		if !errors.As(err, &recordErr) || recordErr.Line != want[i].Line ||
+			recordErr.InstrumentID != want[i].InstrumentID || recordErr.Stage != want[i].Stage {
+			t.Errorf("errs[%d] = %v, want %+v", i, err, want[i])
+		}
+	}
+	var parseErr *ParseError
+	if !errors.As(errs[1], &parseErr) || parseErr.Field != "direction" {
+		t.Errorf("errs[1] 应包含direction字段的ParseError: %v", errs[1])
+	}
+}
+
+func TestParseOrderCombination(t *testing.T) {
+	if _, err := ParseOrder([]string{"SP m2409", "0", "-12", "1"}); err == nil {
+		t.Errorf("ParseOrder() 应拒绝无法解析的组合合约")
+	}
+	if got := (&Order{InstrumentID: "SPD SR501&SR505"}).GetTick(); got != 1 {
+		t.Errorf("组合合约的tick = %v, want 第一腿的tick 1", got)
+	}
+}
+
+func TestProcessSpreads(t *testing.T) {
+	lines := []string{
+		"m2409,0,3000,5",
+		"SP m2409&m2501,1,-14,3",
+		"m2409,1,3005,2",
+		"m2501,0,3010,4",
+		"m2501,1,3012,6",
+		"SP m2409&m2501,0,-16,1",
+	}
+
+	tests := []struct {
+		name string
+		opts []ProcessorOption
+		want string
+	}{
+		{name: "无隐含订单", want: ""},
+		{name: "隐含订单", opts: []ProcessorOption{WithImpliedSpreads()}, want: "-12"},
+	}
+
+	for _, tt := range tests {
+		for _, numCPU := range []int{1, 4} {
+			t.Run(fmt.Sprintf("%s/cpu=%d", tt.name, numCPU), func(t *testing.T) {
+				got := NewOrderProcessor(numCPU, tt.opts...).Process(newTestStream(lines))
+				instruments := make([]string, len(got))
+				for i, result := range got {
+					instruments[i] = result.InstrumentID
+				}
+				if want := []string{"m2409", "SP m2409&m2501", "m2501"}; !reflect.DeepEqual(instruments, want) {
+					t.Fatalf("合约顺序 = %v, want %v", instruments, want)
+				}
+				if price := got[1].FormatPrice(); price != tt.want {
+					t.Errorf("价差价格 = %q, want %q", price, tt.want)
+				}
+			})
+		}
+	}
+
+	// 价差可以为负，按第一腿的tick撮合
+	orders := []Order{
+		{InstrumentID: "SP m2409&m2501", Direction: 0, Price: -12, Volume: 5},
+		{InstrumentID: "SP m2409&m2501", Direction: 1, Price: -15, Volume: 3},
+		{InstrumentID: "SP m2409&m2501", Direction: 1, Price: -10, Volume: 4},
+	}
+	if got := CalculateAuctionPrice(orders); got != -12 {
+		t.Errorf("CalculateAuctionPrice() = %v, want -12", got)
+	}
+}
+
+// testBook 按档位汇总同一合约的订单
+func testBook(orders []Order) *instrumentBook {
+	book := newInstrumentBook(orders[0].Instrument())
+	for _, order := range orders {
+		book.add(order)
+	}
+	return book
+}
+
+func TestImpliedOrders(t *testing.T) {
+	first := []Order{
+		{InstrumentID: "m2409", Direction: 0, Price: 3000, Volume: 5},
+		{InstrumentID: "m2409", Direction: 0, Price: 2999, Volume: 9},
+		{InstrumentID: "m2409", Direction: 1, Price: 3005, Volume: 2},
+	}
+	second := []Order{
+		{InstrumentID: "m2501", Direction: 0, Price: 3010, Volume: 4},
+		{InstrumentID: "m2501", Direction: 1, Price: 3012, Volume: 6},
+		{InstrumentID: "m2501", Direction: 1, Price: 3012, Volume: 1},
+	}
+
+	spread := instrument.Default.Lookup("SP m2409&m2501")
+	got := impliedOrders(spread, testBook(first), testBook(second))
+	want := []Order{
+		{InstrumentID: "SP m2409&m2501", Direction: 0, Price: -12, Volume: 5},
+		{InstrumentID: "SP m2409&m2501", Direction: 1, Price: -5, Volume: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("impliedOrders() = %+v, want %+v", got, want)
+	}
+	if got := impliedOrders(spread, testBook(first[:2]), testBook(second)); len(got) != 1 || got[0].Direction != 0 {
+		t.Errorf("第一腿没有卖单时 impliedOrders() = %+v, want 只有隐含买单", got)
+	}
+}
+
+func TestImpliedOrdersSpreadTick(t *testing.T) {
+	// 单腿tick为0.2，组合合约tick为1：隐含买价3.6向下取到3，隐含卖价5.4向上取到6，而不是取最近的4和5
+	first := []Order{
+		{InstrumentID: "IF2412", Direction: 0, Price: 3973.6, Volume: 3},
+		{InstrumentID: "IF2412", Direction: 1, Price: 3975.4, Volume: 2},
+	}
+	second := []Order{
+		{InstrumentID: "IF2501", Direction: 0, Price: 3970.0, Volume: 4},
+		{InstrumentID: "IF2501", Direction: 1, Price: 3970.0, Volume: 1},
+	}
+	spread := *instrument.Default.Lookup("SP IF2412&IF2501")
+	spread.Ticks = instrument.NewTickSchedule(1, nil)
+
+	got := impliedOrders(&spread, testBook(first), testBook(second))
+	want := []Order{
+		{InstrumentID: "SP IF2412&IF2501", Direction: 0, Price: 3, Volume: 1},
+		{InstrumentID: "SP IF2412&IF2501", Direction: 1, Price: 6, Volume: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("impliedOrders() = %+v, want %+v", got, want)
+	}
+
+	// 价差为负时同样买价向下、卖价向上
+	got = impliedOrders(&spread, testBook(second), testBook(first))
+	want = []Order{
+		{InstrumentID: "SP IF2412&IF2501", Direction: 0, Price: -6, Volume: 2},
+		{InstrumentID: "SP IF2412&IF2501", Direction: 1, Price: -3, Volume: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("价差为负时 impliedOrders() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCalculateAuctionPriceTickBands(t *testing.T) {
+	refdata.Default.Put(refdata.Product{Code: "ZB", Tick: 0.2, TickBands: []refdata.TickBand{{From: 10, Tick: 1}}})
+	orders := []Order{
+		{InstrumentID: "ZB2412", Direction: 0, Price: 11, Volume: 5},
+		{InstrumentID: "ZB2412", Direction: 0, Price: 9.8, Volume: 1},
+		{InstrumentID: "ZB2412", Direction: 1, Price: 9.6, Volume: 2},
+		{InstrumentID: "ZB2412", Direction: 1, Price: 10, Volume: 2},
+	}
+
+	if got := CalculateAuctionPrice(orders); got != 11 {
+		t.Errorf("CalculateAuctionPrice() = %v, want 11", got)
+	}
+	ladder := CalculateAuctionLadder(orders)
+	var prices []float32
+	for _, level := range ladder.Levels {
+		prices = append(prices, level.Price)
+	}
+	if want := []float32{11, 10, 9.8, 9.6}; !reflect.DeepEqual(prices, want) {
+		t.Errorf("分价表价格 = %v, want %v", prices, want)
+	}
+
+	book := NewAuctionBook("ZB2412")
+	for _, order := range orders {
+		book.Add(order)
+	}
+	if got := book.Indicative(); got.Price != 11 || got.MatchVolume != 4 {
+		t.Errorf("Indicative() = %+v, want 价格11、成交量4", got)
+	}
+}
+
+func TestProcessTradableCheck(t *testing.T) {
+	tradingDate := instrument.Default.TradingDate()
+	instrument.Default.SetTradingDate(time.Date(2024, 11, 20, 0, 0, 0, 0, time.Local))
+	defer instrument.Default.SetTradingDate(tradingDate)
+
+	input := "IF2411,0,3973.4,3\nIF2412,0,3973.4,3\nIF2502,1,3973.0,2\nIF2412,1,3973.0,2\nSP IF2411&IF2412,0,-12,1\nm2411,0,3000,1\n"
+	stream := StreamReader(strings.NewReader(input))
+	var errs []error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range stream.Error {
+			errs = append(errs, err)
+		}
+	}()
+	got := NewOrderProcessor(1, WithTradableCheck()).Process(stream)
+	<-stream.Done
+	close(stream.Error)
+	<-done
+
+	// 没有内置规则的m2411视为可交易
+	if len(got) != 2 || got[0].InstrumentID != "IF2412" || got[0].Price != 3973.4 || got[1].InstrumentID != "m2411" {
+		t.Errorf("Process() = %+v, want IF2412和m2411", got)
+	}
+	want := []string{instrument.REASON_EXPIRED, instrument.REASON_NOT_LISTED, instrument.REASON_EXPIRED}
+	if len(errs) != len(want) {
+		t.Fatalf("errs = %v, want %d个", errs, len(want))
+	}
+	for i, err := range errs {
+		var recordErr *RecordError
+		if !errors.As(err, &recordErr) || recordErr.Stage != STAGE_CONTRACT || rejectReason(err) != want[i] {
+			t.Errorf("errs[%d] = %v, want %s", i, err, want[i])
+		}
+	}
+}
+
+func TestInstrumentMetrics(t *testing.T) {
+	EnableInstrumentMetrics(1)
+	defer EnableInstrumentMetrics(0)
+
+	// 指标是全局累计的，按前后差值检查
+	beforeOrders, beforeOther := InstrumentOrders.Value("IF2412"), InstrumentOrders.Value(metrics.OTHER_LABEL)
+	beforeLadders, beforeLevels := InstrumentLadderSize.Count("IF2412")
+	beforeCalc, _ := CalcDuration.Count()
+	lines := []string{"IF2412,0,3973.4,3", "IF2412,1,3973.0,2", "TS2412,0,101.2,1", "T2412,1,101.2,4"}
+	NewOrderProcessor(4).Process(newTestStream(lines))
+
+	// 输入中第一个出现的合约单独计数，超出上限的合约归入other
+	if got := InstrumentOrders.Value("IF2412") - beforeOrders; got != 2 {
+		t.Errorf("InstrumentOrders(IF2412) = %v", got)
+	}
+	if got := InstrumentOrders.Value(metrics.OTHER_LABEL) - beforeOther; got != 2 {
+		t.Errorf("InstrumentOrders(other) = %v", got)
+	}
+	if count, levels := InstrumentLadderSize.Count("IF2412"); count-beforeLadders != 1 || levels-beforeLevels != 3 {
+		t.Errorf("InstrumentLadderSize(IF2412) = %d, %v", count-beforeLadders, levels-beforeLevels)
+	}
+	if count, _ := CalcDuration.Count(); count-beforeCalc != 3 {
+		t.Errorf("CalcDuration观测 %d 次", count-beforeCalc)
+	}
+}
@@ -1,54 +1,21 @@
 package order
 
 import (
-	"AuctionMatch/utils"
-	"fmt"
-	"strings"
 	"sync"
 )
 
 type ParallelProcessor struct {
 	numWorkers int
+	config     processorConfig
 }
 
 func (p *ParallelProcessor) Process(stream *OrderStream) []ProcessResult {
-	ordersByInstrument := make(map[string][]Order)
-	var instrumentsMutex sync.Mutex
-
-	// 跟踪合约出现顺序
-	instrumentOrder := make([]string, 0)
-	seenInstruments := make(map[string]uint) // 记录每个合约的价格精度
-	var scale uint = 0
-
 	// 收集订单
-	for line := range stream.Orders {
-		record := utils.CustomSplit(line)
-		if !IsValidRecord(record) {
-			continue
-		}
-		order, err := ParseOrder(record)
-		if err != nil {
-			stream.Error <- fmt.Errorf("解析订单出错: %v", err)
-			continue
-		}
-		instrumentsMutex.Lock()
-		if seenInstruments[order.InstrumentID] == 0 {
-			// 获取价格精度
-			if dotIndex := strings.Index(record[2], "."); dotIndex != -1 {
-				scale = uint(len(record[2]) - dotIndex - 1)
-			}
-			instrumentOrder = append(instrumentOrder, order.InstrumentID)
-			seenInstruments[order.InstrumentID] = scale
-			scale = 0
-		}
-		ordersByInstrument[order.InstrumentID] = append(
-			ordersByInstrument[order.InstrumentID],
-			order,
-		)
-		instrumentsMutex.Unlock()
-	}
+	collector := newOrderCollector(p.config)
+	collector.collect(stream)
 
-	results := make([]ProcessResult, len(instrumentOrder))
+	instrumentCount := len(collector.instruments)
+	results := make([]ProcessResult, instrumentCount)
 	var wg sync.WaitGroup
 
 	// 将工作分配给workers
@@ -57,17 +24,11 @@ func (p *ParallelProcessor) Process(stream *OrderStream) []ProcessResult {
 		go func(workerID int) {
 			defer wg.Done()
 
-			// 每个worker处理一部分instruments
-			for j := workerID; j < len(instrumentOrder); j += p.numWorkers {
-				instrumentID := instrumentOrder[j]
-				orders := ordersByInstrument[instrumentID]
-				price := CalculateAuctionPrice(orders)
-
-				results[j] = ProcessResult{
-					InstrumentID: instrumentID,
-					Price:        price,
-					Scale:        seenInstruments[instrumentID],
-				}
+			// 每个worker处理一部分instruments，计算指标在worker内累计后合并一次
+			m := newCalcMetrics()
+			defer m.flush()
+			for j := workerID; j < instrumentCount; j += p.numWorkers {
+				results[j] = collector.result(j, m)
 			}
 		}(i)
 	}
@@ -0,0 +1,86 @@
+package order
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+type (
+	// CutoffResult 假设集合竞价在Cutoff时刻结束时各合约的撮合结果
+	CutoffResult struct {
+		Cutoff  int64 // 当日零点起的纳秒数
+		Results []ProcessResult
+	}
+
+	// TimedOrders 按时间戳排序的订单，同一时刻的订单保持输入中的先后顺序
+	TimedOrders struct {
+		records []timedRecord
+	}
+
+	timedRecord struct {
+		order     Order
+		priceText string
+	}
+)
+
+// ReadTimedOrders 读取订单流并按时间戳稳定排序，没有时间戳的订单通过stream.Error上报并忽略
+func ReadTimedOrders(stream *OrderStream) *TimedOrders {
+	timed := &TimedOrders{records: make([]timedRecord, 0)}
+	ReadOrders(stream, func(order Order, record []string) {
+		if !order.HasTime {
+			stream.Reject(STAGE_REPLAY, order.InstrumentID, fmt.Errorf("订单缺少时间戳"))
+			return
+		}
+		timed.records = append(timed.records, timedRecord{order: order, priceText: record[2]})
+	})
+	sort.SliceStable(timed.records, func(i, j int) bool {
+		return timed.records[i].order.Time < timed.records[j].order.Time
+	})
+	return timed
+}
+
+// Span 返回最早和最晚的订单时刻，没有订单时ok为false
+func (t *TimedOrders) Span() (first, last int64, ok bool) {
+	if len(t.records) == 0 {
+		return 0, 0, false
+	}
+	return t.records[0].order.Time, t.records[len(t.records)-1].order.Time, true
+}
+
+// Replay 按时间顺序回放订单，对每个截止时刻给出只包含该时刻及之前订单的撮合结果，结果按截止时刻升序去重排列。
+// volume为负数表示撤单，撤单失败的事件被忽略并通过errs返回；结果中的合约按回放中首次出现的顺序排列
+func (t *TimedOrders) Replay(cutoffs []int64, opts ...ProcessorOption) (results []CutoffResult, errs []error) {
+	sorted := append([]int64(nil), cutoffs...)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	auction := NewIncrementalAuction(opts...)
+	results = make([]CutoffResult, 0, len(sorted))
+	next := 0
+	for _, cutoff := range sorted {
+		for ; next < len(t.records) && t.records[next].order.Time <= cutoff; next++ {
+			record := t.records[next]
+			if _, err := auction.Apply(record.order, record.priceText); err != nil {
+				errs = append(errs, fmt.Errorf("%s %v", FormatTimeOfDay(record.order.Time), err))
+			}
+		}
+		results = append(results, CutoffResult{Cutoff: cutoff, Results: auction.Results()})
+	}
+	return results, errs
+}
+
+// EveryCutoff 从start之后第一个interval整数倍的时刻起，每隔interval生成一个截止时刻，直至覆盖end
+func EveryCutoff(start, end, interval int64) []int64 {
+	if interval <= 0 || end < start {
+		return nil
+	}
+	cutoffs := make([]int64, 0)
+	for cutoff := (start/interval + 1) * interval; ; cutoff += interval {
+		cutoffs = append(cutoffs, cutoff)
+		if cutoff >= end {
+			break
+		}
+	}
+	return cutoffs
+}
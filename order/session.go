@@ -0,0 +1,261 @@
+package order
+
+import (
+	"AuctionMatch/instrument"
+	"errors"
+	"fmt"
+)
+
+type (
+	// InstrumentState 单个合约在集合竞价时段内的存量订单与聚合委托簿
+	InstrumentState struct {
+		Book   *AuctionBook
+		orders map[string]*LiveOrder
+	}
+
+	// CallSession 一个集合竞价时段，维护所有合约的存量订单，支持新增、撤单、改单和撮合
+	CallSession struct {
+		instruments []string // 合约首次出现顺序
+		states      map[string]*InstrumentState
+		orders      map[string]*LiveOrder // 按订单编号索引
+		seq         uint64
+		open        bool
+		stp         SelfTradePrevention
+	}
+
+	// SessionOption 集合竞价时段可选配置
+	SessionOption func(*CallSession)
+
+	// UncrossResult 单个合约的撮合结果及成交明细
+	UncrossResult struct {
+		ProcessResult
+		MatchVolume int32 // 实际成交量，自成交防范后可能小于分价表上的最大成交量
+		Fills       []Fill
+	}
+)
+
+var (
+	ErrSessionClosed  = errors.New("集合竞价时段未开放")
+	ErrDuplicateOrder = errors.New("订单编号重复")
+	ErrUnknownOrder   = errors.New("订单不存在")
+)
+
+// WithSelfTradePrevention 设置撮合时的自成交防范方式，默认不防范
+func WithSelfTradePrevention(stp SelfTradePrevention) SessionOption {
+	return func(s *CallSession) {
+		s.stp = stp
+	}
+}
+
+// NewCallSession 创建一个已开放的集合竞价时段
+func NewCallSession(opts ...SessionOption) *CallSession {
+	s := &CallSession{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.Open()
+	return s
+}
+
+// validPrice 单一合约的价格须为正，组合合约的价格为两腿价差，可以为0或负数
+func validPrice(instrumentID string, price float32) bool {
+	return price > 0 || instrument.Default.Lookup(instrumentID).Kind == instrument.Spread
+}
+
+// Open 清空全部状态并开放新的集合竞价时段
+func (s *CallSession) Open() {
+	s.instruments = make([]string, 0)
+	s.states = make(map[string]*InstrumentState)
+	s.orders = make(map[string]*LiveOrder)
+	s.open = true
+}
+
+// IsOpen 时段是否开放
+func (s *CallSession) IsOpen() bool {
+	return s.open
+}
+
+// Instruments 按首次出现顺序返回时段内的合约
+func (s *CallSession) Instruments() []string {
+	return append([]string(nil), s.instruments...)
+}
+
+// Lookup 按编号查询存量订单
+func (s *CallSession) Lookup(orderID string) (LiveOrder, bool) {
+	live, ok := s.orders[orderID]
+	if !ok {
+		return LiveOrder{}, false
+	}
+	return *live, true
+}
+
+// State 返回合约状态，未出现过的合约返回nil
+func (s *CallSession) State(instrumentID string) *InstrumentState {
+	return s.states[instrumentID]
+}
+
+// Orders 返回合约的存量订单
+func (state *InstrumentState) Orders() []*LiveOrder {
+	orders := make([]*LiveOrder, 0, len(state.orders))
+	for _, o := range state.orders {
+		orders = append(orders, o)
+	}
+	return orders
+}
+
+func (s *CallSession) state(instrumentID string) *InstrumentState {
+	state, ok := s.states[instrumentID]
+	if !ok {
+		state = &InstrumentState{
+			Book:   NewAuctionBook(instrumentID),
+			orders: make(map[string]*LiveOrder),
+		}
+		s.states[instrumentID] = state
+		s.instruments = append(s.instruments, instrumentID)
+	}
+	return state
+}
+
+// CheckSubmit 检查订单能否新增而不修改时段，返回Submit会返回的错误；供先写预写日志再生效的调用方使用
+func (s *CallSession) CheckSubmit(orderID string, order Order) error {
+	if !s.open {
+		return ErrSessionClosed
+	}
+	if _, ok := s.orders[orderID]; ok {
+		return ErrDuplicateOrder
+	}
+	if order.Volume <= 0 || !validPrice(order.InstrumentID, order.Price) {
+		return fmt.Errorf("无效的价格或数量: %v, %d", order.Price, order.Volume)
+	}
+	return nil
+}
+
+// Submit 新增订单
+func (s *CallSession) Submit(orderID, owner string, order Order) error {
+	if err := s.CheckSubmit(orderID, order); err != nil {
+		return err
+	}
+
+	s.seq++
+	live := &LiveOrder{Order: order, OrderID: orderID, Owner: owner, Seq: s.seq}
+	state := s.state(order.InstrumentID)
+	state.Book.Add(order)
+	state.orders[orderID] = live
+	s.orders[orderID] = live
+	return nil
+}
+
+// CheckCancel 检查订单能否撤销而不修改时段
+func (s *CallSession) CheckCancel(orderID string) error {
+	if !s.open {
+		return ErrSessionClosed
+	}
+	if _, ok := s.orders[orderID]; !ok {
+		return ErrUnknownOrder
+	}
+	return nil
+}
+
+// Cancel 撤销订单
+func (s *CallSession) Cancel(orderID string) error {
+	if err := s.CheckCancel(orderID); err != nil {
+		return err
+	}
+
+	live := s.orders[orderID]
+	state := s.states[live.InstrumentID]
+	if err := state.Book.Cancel(live.Order); err != nil {
+		return err
+	}
+	delete(state.orders, orderID)
+	delete(s.orders, orderID)
+	return nil
+}
+
+// CheckAmend 检查订单能否改单而不修改时段
+func (s *CallSession) CheckAmend(orderID string, price float32, volume int32) error {
+	if !s.open {
+		return ErrSessionClosed
+	}
+	live, ok := s.orders[orderID]
+	if !ok {
+		return ErrUnknownOrder
+	}
+	if volume <= 0 || !validPrice(live.InstrumentID, price) {
+		return fmt.Errorf("无效的价格或数量: %v, %d", price, volume)
+	}
+	return nil
+}
+
+// Amend 修改订单价格和数量；改价或增量会失去时间优先，减量保留原有优先级
+func (s *CallSession) Amend(orderID string, price float32, volume int32) error {
+	if err := s.CheckAmend(orderID, price, volume); err != nil {
+		return err
+	}
+
+	live := s.orders[orderID]
+	book := s.states[live.InstrumentID].Book
+	if err := book.Cancel(live.Order); err != nil {
+		return err
+	}
+	if book.Ticks.ToInt(price) != book.Ticks.ToInt(live.Price) || volume > live.Volume {
+		s.seq++
+		live.Seq = s.seq
+	}
+	live.Price = price
+	live.Volume = volume
+	book.addVolume(live.Direction, book.Ticks.ToInt(price), volume)
+	return nil
+}
+
+// Results 按合约首次出现顺序，以存量订单计算各合约的集合竞价价格
+func (s *CallSession) Results() []ProcessResult {
+	results := make([]ProcessResult, len(s.instruments))
+	m := newCalcMetrics()
+	defer m.flush()
+	for i, instrumentID := range s.instruments {
+		state := s.states[instrumentID]
+		orders := make([]Order, 0, len(state.orders))
+		for _, live := range state.orders {
+			orders = append(orders, live.Order)
+		}
+		price, matched := calculateAuction(orders, m)
+		results[i] = ProcessResult{
+			InstrumentID: instrumentID,
+			Price:        price,
+			Scale:        state.Book.Scale,
+			Matched:      matched,
+		}
+	}
+	return results
+}
+
+// Uncross 对所有合约执行集合竞价撮合并关闭时段，结果按合约首次出现顺序排列
+func (s *CallSession) Uncross() []UncrossResult {
+	results := make([]UncrossResult, len(s.instruments))
+	for i, instrumentID := range s.instruments {
+		state := s.states[instrumentID]
+		indicative := state.Book.Indicative()
+		fills, prevented := AllocateFills(indicative.Price, indicative.MatchVolume, state.Book.Ticks, state.Orders(), s.stp)
+		var matchVolume int32
+		for _, fill := range fills {
+			if fill.Direction == 0 {
+				matchVolume += fill.Volume
+			}
+		}
+		results[i] = UncrossResult{
+			ProcessResult: ProcessResult{
+				InstrumentID: instrumentID,
+				Price:        indicative.Price,
+				Scale:        state.Book.Scale,
+				Matched:      indicative.MatchVolume > 0,
+
+				PreventedVolume: prevented,
+			},
+			MatchVolume: matchVolume,
+			Fills:       fills,
+		}
+	}
+	s.open = false
+	return results
+}
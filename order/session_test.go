@@ -0,0 +1,221 @@
+package order
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCallSession(t *testing.T) {
+	s := NewCallSession()
+	submit := func(orderID string, direction int8, price float32, volume int32) {
+		t.Helper()
+		if err := s.Submit(orderID, "c1", Order{InstrumentID: "IF2412", Direction: direction, Price: price, Volume: volume}); err != nil {
+			t.Fatalf("Submit(%s) error = %v", orderID, err)
+		}
+	}
+
+	submit("b1", 0, 3973.2, 2)
+	submit("b2", 0, 3973.4, 2)
+	submit("b3", 0, 3973.2, 3)
+	submit("s1", 1, 3973.0, 4)
+	submit("s2", 1, 3973.6, 1)
+
+	if err := s.Submit("b1", "c1", Order{InstrumentID: "IF2412", Price: 1, Volume: 1}); err != ErrDuplicateOrder {
+		t.Errorf("重复订单编号 error = %v", err)
+	}
+	if err := s.Cancel("s2"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	// b1减量保留优先级，仍先于b3成交
+	if err := s.Amend("b1", 3973.2, 1); err != nil {
+		t.Fatalf("Amend() error = %v", err)
+	}
+
+	results := s.Uncross()
+	if len(results) != 1 || results[0].Price != 3973.2 || results[0].MatchVolume != 4 {
+		t.Fatalf("Uncross() = %+v", results)
+	}
+	want := []Fill{
+		{OrderID: "b2", Owner: "c1", InstrumentID: "IF2412", Direction: 0, Price: 3973.2, Volume: 2},
+		{OrderID: "b1", Owner: "c1", InstrumentID: "IF2412", Direction: 0, Price: 3973.2, Volume: 1},
+		{OrderID: "b3", Owner: "c1", InstrumentID: "IF2412", Direction: 0, Price: 3973.2, Volume: 1},
+		{OrderID: "s1", Owner: "c1", InstrumentID: "IF2412", Direction: 1, Price: 3973.2, Volume: 4},
+	}
+	if !reflect.DeepEqual(results[0].Fills, want) {
+		t.Errorf("Fills = %+v, want %+v", results[0].Fills, want)
+	}
+
+	if err := s.Cancel("b1"); err != ErrSessionClosed {
+		t.Errorf("撮合后撤单 error = %v", err)
+	}
+}
+
+// TestCallSessionSpreadPrice 组合合约的价差可以为0或负数，单一合约的价格须为正
+func TestCallSessionSpreadPrice(t *testing.T) {
+	s := NewCallSession()
+	spread := "SP m2409&m2501"
+	if err := s.Submit("b1", "c1", Order{InstrumentID: spread, Direction: 0, Price: 0, Volume: 5}); err != nil {
+		t.Errorf("价差为0 error = %v", err)
+	}
+	if err := s.Submit("s1", "c1", Order{InstrumentID: spread, Direction: 1, Price: -3, Volume: 5}); err != nil {
+		t.Errorf("价差为负 error = %v", err)
+	}
+	if err := s.Amend("b1", -2, 5); err != nil {
+		t.Errorf("改单为负价差 error = %v", err)
+	}
+	if err := s.Submit("b2", "c1", Order{InstrumentID: "m2409", Direction: 0, Price: 0, Volume: 1}); err == nil {
+		t.Errorf("单一合约价格为0应被拒绝")
+	}
+	if err := s.Submit("b3", "c1", Order{InstrumentID: "m2409", Direction: 0, Price: 3000, Volume: 1}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if err := s.Amend("b3", -1, 1); err == nil {
+		t.Errorf("单一合约改单为负价格应被拒绝")
+	}
+}
+
+// TestZeroPriceSpread 价差在0成交时各处结果应为有成交，价格输出为"0"而不是空
+func TestZeroPriceSpread(t *testing.T) {
+	spread := "SP m2409&m2501"
+	orders := []Order{
+		{InstrumentID: spread, Direction: 0, Price: 0, Volume: 3},
+		{InstrumentID: spread, Direction: 1, Price: 0, Volume: 2},
+	}
+
+	if price, matched := CalculateAuction(orders); price != 0 || !matched {
+		t.Errorf("CalculateAuction() = %v, %v, want 0, true", price, matched)
+	}
+
+	s := NewCallSession()
+	auction := NewIncrementalAuction()
+	for i, order := range orders {
+		if err := s.Submit(fmt.Sprintf("o%d", i), "c1", order); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		auction.Add(order, "0")
+	}
+	if got := auction.Book(spread).Indicative(); got.Price != 0 || got.MatchVolume != 2 {
+		t.Errorf("Indicative() = %+v, want 价格0、成交量2", got)
+	}
+	if results := auction.Results(); results[0].FormatPrice() != "0" {
+		t.Errorf("IncrementalAuction.Results() = %+v, want 价格0", results)
+	}
+	if results := s.Results(); results[0].FormatPrice() != "0" {
+		t.Errorf("CallSession.Results() = %+v, want 价格0", results)
+	}
+	if results := s.Uncross(); results[0].FormatPrice() != "0" || results[0].MatchVolume != 2 {
+		t.Errorf("Uncross() = %+v, want 价格0、成交量2", results)
+	}
+
+	lines := []string{spread + ",0,0,3", spread + ",1,0,2"}
+	for _, numCPU := range []int{1, 4} {
+		results := NewOrderProcessor(numCPU).Process(newTestStream(lines))
+		if len(results) != 1 || results[0].FormatPrice() != "0" {
+			t.Errorf("processor(%d) = %+v, want 价格0", numCPU, results)
+		}
+	}
+}
+
+// TestSelfTradePrevention 测试同账号买卖单在分配成交时的各种防范方式
+func TestSelfTradePrevention(t *testing.T) {
+	tests := []struct {
+		mode      string
+		fills     map[string]int32
+		match     int32
+		prevented int32
+	}{
+		{mode: "none", fills: map[string]int32{"b1": 4, "b2": 1, "s1": 2, "s2": 3}, match: 5},
+		// 较晚的s2被撤销，被防范的量为其全部剩余量3
+		{mode: "cancel-newest", fills: map[string]int32{"b1": 2, "s1": 2}, match: 2, prevented: 3},
+		{mode: "cancel-oldest", fills: map[string]int32{"b1": 2, "b2": 2, "s1": 2, "s2": 2}, match: 4, prevented: 2},
+		{mode: "decrement-both", fills: map[string]int32{"b1": 2, "b2": 1, "s1": 2, "s2": 1}, match: 3, prevented: 2},
+		// b1和s2的剩余量2和3同时撤销
+		{mode: "cancel-both", fills: map[string]int32{"b1": 2, "s1": 2}, match: 2, prevented: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			stp, err := ParseSelfTradePrevention(tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := NewCallSession(WithSelfTradePrevention(stp))
+			for _, o := range []struct {
+				id        string
+				direction int8
+				price     float32
+				volume    int32
+				account   string
+			}{
+				{"b1", 0, 3973.4, 4, "A"},
+				{"s1", 1, 3973.0, 2, "B"},
+				{"s2", 1, 3973.2, 3, "A"},
+				{"b2", 0, 3973.2, 2, ""},
+			} {
+				order := Order{InstrumentID: "IF2412", Direction: o.direction, Price: o.price, Volume: o.volume, Account: o.account}
+				if err := s.Submit(o.id, "c1", order); err != nil {
+					t.Fatalf("Submit(%s) error = %v", o.id, err)
+				}
+			}
+
+			result := s.Uncross()[0]
+			if result.Price != 3973.2 || result.MatchVolume != tt.match || result.PreventedVolume != tt.prevented {
+				t.Errorf("Uncross() = %v, 成交%d, 防范%d, want 3973.2, %d, %d",
+					result.Price, result.MatchVolume, result.PreventedVolume, tt.match, tt.prevented)
+			}
+			fills := make(map[string]int32)
+			for _, fill := range result.Fills {
+				fills[fill.OrderID] = fill.Volume
+			}
+			if !reflect.DeepEqual(fills, tt.fills) {
+				t.Errorf("Fills = %v, want %v", fills, tt.fills)
+			}
+
+			// 批量处理按输入顺序分配，被防范的量与CallSession一致
+			lines := []string{"IF2412,0,3973.4,4,,A", "IF2412,1,3973.0,2,,B", "IF2412,1,3973.2,3,,A", "IF2412,0,3973.2,2"}
+			for _, numCPU := range []int{1, 4} {
+				processed := NewOrderProcessor(numCPU, WithSelfTradeCheck(stp)).Process(newTestStream(lines))[0]
+				if processed.Price != 3973.2 || processed.PreventedVolume != tt.prevented {
+					t.Errorf("processor(%d) = %+v, want 防范%d", numCPU, processed, tt.prevented)
+				}
+			}
+		})
+	}
+
+	if _, err := ParseSelfTradePrevention("cancel-all"); err == nil {
+		t.Error("无效的防范方式应返回错误")
+	}
+}
+
+// TestAllocateFillsPrevented 多次相遇时被防范的量为各次撤销或扣减的剩余量之和
+func TestAllocateFillsPrevented(t *testing.T) {
+	orders := []*LiveOrder{
+		{Order: Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3, Account: "A"}, OrderID: "b1", Seq: 1},
+		{Order: Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.2, Volume: 2, Account: "A"}, OrderID: "b2", Seq: 2},
+		{Order: Order{InstrumentID: "IF2412", Direction: 1, Price: 3973.0, Volume: 4, Account: "A"}, OrderID: "s1", Seq: 3},
+		{Order: Order{InstrumentID: "IF2412", Direction: 1, Price: 3973.0, Volume: 5, Account: "B"}, OrderID: "s2", Seq: 4},
+	}
+	ticks := orders[0].TickSchedule()
+	for _, tt := range []struct {
+		stp       SelfTradePrevention
+		filled    int32
+		prevented int32
+	}{
+		{CancelNewest, 5, 4},  // 撤销s1的4手，b1、b2与s2成交
+		{CancelOldest, 0, 5},  // 先后撤销b1的3手和b2的2手
+		{DecrementBoth, 1, 4}, // b1、s1各扣3手，b2、s1各扣1手，b2剩余1手与s2成交
+		{CancelBoth, 2, 7},    // 撤销b1的3手和s1的4手，b2与s2成交
+	} {
+		fills, prevented := AllocateFills(3973.2, 5, ticks, orders, tt.stp)
+		var filled int32
+		for _, fill := range fills {
+			if fill.Direction == 0 {
+				filled += fill.Volume
+			}
+		}
+		if filled != tt.filled || prevented != tt.prevented {
+			t.Errorf("AllocateFills(stp=%d) 成交%d, 防范%d, want %d, %d", tt.stp, filled, prevented, tt.filled, tt.prevented)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package order
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimeOfDay 解析"HH:MM:SS[.fffffffff]"格式的时刻，返回当日零点起的纳秒数
+func ParseTimeOfDay(s string) (int64, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("无效的时间戳: %s", s)
+	}
+	hour, err1 := strconv.Atoi(parts[0])
+	minute, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("无效的时间戳: %s", s)
+	}
+
+	secondText, fraction, _ := strings.Cut(parts[2], ".")
+	second, err := strconv.Atoi(secondText)
+	if err != nil || len(secondText) != 2 || second < 0 || second > 59 || len(fraction) > 9 {
+		return 0, fmt.Errorf("无效的时间戳: %s", s)
+	}
+	var nanos int64
+	if fraction != "" {
+		n, err := strconv.ParseInt(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("无效的时间戳: %s", s)
+		}
+		nanos = n
+	}
+
+	return int64(hour)*int64(time.Hour) + int64(minute)*int64(time.Minute) +
+		int64(second)*int64(time.Second) + nanos, nil
+}
+
+// FormatTimeOfDay 将当日零点起的纳秒数格式化为"HH:MM:SS"，有不足一秒的部分时保留到毫秒
+func FormatTimeOfDay(nanos int64) string {
+	text := time.Unix(0, nanos).UTC().Format("15:04:05.000")
+	return strings.TrimSuffix(text, ".000")
+}
@@ -0,0 +1,223 @@
+package refdata
+
+import (
+	"AuctionMatch/consts"
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// Product 品种参考数据
+	Product struct {
+		Code           string
+		Tick           float32    // 最小变动价位，有价格段时为最低价格段（包括负价格）的tick
+		TickBands      []TickBand // 更高价格段的tick，按起点升序，为空表示全部价格使用Tick
+		MaxOrderVolume int32      // 每次最大下单数量，0表示未知
+		Multiplier     int32      // 合约乘数，0表示未知
+	}
+
+	// TickBand 价格不低于From时适用的最小变动价位，直到下一个价格段的起点
+	TickBand struct {
+		From float32
+		Tick float32
+	}
+
+	// Registry 线程安全的品种参考数据表
+	Registry struct {
+		products map[string]Product
+		version  uint64 // 每次修改加一，供按合约缓存参考数据的使用方判断是否失效
+		mu       sync.RWMutex
+	}
+)
+
+// Default 进程内默认使用的参考数据，初始为内置的中金所品种
+var Default = NewRegistry()
+
+// NewRegistry 创建包含内置品种的参考数据表：中金所全部品种，以及大商所、郑商所的部分期货和期权品种（仅tick）
+func NewRegistry() *Registry {
+	r := &Registry{products: make(map[string]Product)}
+	for code, tick := range consts.CFE_PRODUCT_TICK {
+		r.products[code] = Product{
+			Code:           code,
+			Tick:           tick,
+			MaxOrderVolume: consts.CFE_MAX_ORDER_VOLUME[code],
+			Multiplier:     consts.CFE_CONTRACT_MULTIPLIER[code],
+		}
+	}
+	for _, ticks := range []map[string]float32{consts.DCE_PRODUCT_TICK, consts.CZCE_PRODUCT_TICK, consts.OPTION_PRODUCT_TICK} {
+		for code, tick := range ticks {
+			r.products[code] = Product{Code: code, Tick: tick}
+		}
+	}
+	return r
+}
+
+// Product 查询品种参考数据
+func (r *Registry) Product(code string) (Product, bool) {
+	r.mu.RLock()
+	product, ok := r.products[code]
+	r.mu.RUnlock()
+	return product, ok
+}
+
+// Put 新增或覆盖品种参考数据
+func (r *Registry) Put(product Product) {
+	r.mu.Lock()
+	r.products[product.Code] = product
+	r.version++
+	r.mu.Unlock()
+}
+
+// Version 返回参考数据的版本号，每次修改后变化
+func (r *Registry) Version() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
+// Products 返回按品种代码排序的全部品种参考数据
+func (r *Registry) Products() []Product {
+	r.mu.RLock()
+	products := make([]Product, 0, len(r.products))
+	for _, product := range r.products {
+		products = append(products, product)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].Code < products[j].Code
+	})
+	return products
+}
+
+// Load 从CSV读取参考数据并合并到表中，格式为"product,tick[,maxOrderVolume[,multiplier]]"，
+// maxOrderVolume可留空，#开头的行为注释。tick按价格分段时写作"0.2;100:1;500:5"，
+// 即低于100为0.2、100起为1、500起为5。
+// 任一行格式错误时不做任何修改，成功时返回加载的品种数
+func (r *Registry) Load(reader io.Reader) (int, error) {
+	products, err := ParseProducts(reader)
+	if err != nil {
+		return 0, err
+	}
+	for _, product := range products {
+		r.Put(product)
+	}
+	return len(products), nil
+}
+
+// ParseProducts 解析CSV格式的参考数据
+func ParseProducts(reader io.Reader) ([]Product, error) {
+	products := make([]Product, 0)
+	scanner := bufio.NewScanner(reader)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		product, err := parseProduct(strings.Split(line, ","))
+		if err != nil {
+			return nil, fmt.Errorf("参考数据第%d行: %v", lineNum, err)
+		}
+		products = append(products, product)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func parseProduct(record []string) (Product, error) {
+	if len(record) < 2 || len(record) > 4 {
+		return Product{}, fmt.Errorf("字段数应为2到4，实际为%d", len(record))
+	}
+	code := strings.TrimSpace(record[0])
+	if code == "" {
+		return Product{}, fmt.Errorf("品种代码为空")
+	}
+	tick, bands, err := parseTick(strings.TrimSpace(record[1]))
+	if err != nil {
+		return Product{}, err
+	}
+	product := Product{Code: code, Tick: tick, TickBands: bands}
+	if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+		volume, err := strconv.ParseInt(strings.TrimSpace(record[2]), 10, 32)
+		if err != nil || volume <= 0 {
+			return Product{}, fmt.Errorf("无效的最大下单数量: %s", record[2])
+		}
+		product.MaxOrderVolume = int32(volume)
+	}
+	if len(record) > 3 {
+		multiplier, err := strconv.ParseInt(strings.TrimSpace(record[3]), 10, 32)
+		if err != nil || multiplier <= 0 {
+			return Product{}, fmt.Errorf("无效的合约乘数: %s", record[3])
+		}
+		product.Multiplier = int32(multiplier)
+	}
+	return product, nil
+}
+
+// TICK_UNIT 校验价格段边界时将价格放大为万分之一单位的整数
+const TICK_UNIT = 10000
+
+// parseTick 解析"tick[;from:tick...]"，tick不小于万分之一，各价格段的起点须递增，且与上一段起点的距离为上一段tick的整数倍
+func parseTick(text string) (float32, []TickBand, error) {
+	fields := strings.Split(text, ";")
+	tick, err := strconv.ParseFloat(fields[0], 32)
+	if err != nil || units(tick) <= 0 {
+		return 0, nil, fmt.Errorf("无效的tick值: %s", text)
+	}
+
+	var bands []TickBand
+	prevFrom, prevTick := 0.0, tick
+	for _, field := range fields[1:] {
+		fromText, tickText, ok := strings.Cut(field, ":")
+		from, fromErr := strconv.ParseFloat(fromText, 32)
+		bandTick, tickErr := strconv.ParseFloat(tickText, 32)
+		if !ok || fromErr != nil || tickErr != nil || units(bandTick) <= 0 || from <= prevFrom {
+			return 0, nil, fmt.Errorf("无效的tick价格段: %s", field)
+		}
+		if units(from-prevFrom)%units(prevTick) != 0 {
+			return 0, nil, fmt.Errorf("价格段起点%s不在上一段tick %v的整数倍上", fromText, prevTick)
+		}
+		bands = append(bands, TickBand{From: float32(from), Tick: float32(bandTick)})
+		prevFrom, prevTick = from, bandTick
+	}
+	return float32(tick), bands, nil
+}
+
+func units(price float64) int64 {
+	return int64(math.Round(price * TICK_UNIT))
+}
+
+// formatTick 按parseTick接受的格式输出tick
+func formatTick(product Product) string {
+	text := fmt.Sprintf("%v", product.Tick)
+	for _, band := range product.TickBands {
+		text += fmt.Sprintf(";%v:%v", band.From, band.Tick)
+	}
+	return text
+}
+
+// FormatProducts 按Load接受的格式输出参考数据，未知的最大下单数量和合约乘数省略或留空
+func FormatProducts(w io.Writer, products []Product) {
+	for _, product := range products {
+		line := fmt.Sprintf("%s,%s", product.Code, formatTick(product))
+		switch {
+		case product.Multiplier > 0 && product.MaxOrderVolume > 0:
+			line += fmt.Sprintf(",%d,%d", product.MaxOrderVolume, product.Multiplier)
+		case product.Multiplier > 0:
+			line += fmt.Sprintf(",,%d", product.Multiplier)
+		case product.MaxOrderVolume > 0:
+			line += fmt.Sprintf(",%d", product.MaxOrderVolume)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
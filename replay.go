@@ -0,0 +1,70 @@
+package main
+
+import (
+	"AuctionMatch/order"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const replayHeader = "cutoff,instrumentID,price\n"
+
+// runReplay 按时间戳回放订单，输出各截止时刻对应的集合竞价结果。
+// -cutoff 指定以逗号分隔的截止时刻，-every 按固定间隔生成截止时刻，两者可同时使用
+func runReplay(argv []string) {
+	args := mustParseArgs(argv, argSpec{options: []string{"-o", "-scale"}, extra: []string{"-cutoff", "-every"}})
+	stream := order.StreamOrders(args.inputFile)
+	waitErrors := logErrors(stream)
+	timed := order.ReadTimedOrders(stream)
+	waitErrors()
+
+	cutoffs, err := parseCutoffs(args.options["-cutoff"], args.options["-every"], timed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "参数错误: %v！使用 -h 查看帮助信息\n", err)
+		os.Exit(2)
+	}
+
+	results, errs := timed.Replay(cutoffs, order.WithScaleMode(args.scaleMode))
+	for _, err := range errs {
+		logError(err)
+	}
+
+	var output strings.Builder
+	output.WriteString(replayHeader)
+	for _, cutoff := range results {
+		for _, result := range cutoff.Results {
+			output.WriteString(order.FormatTimeOfDay(cutoff.Cutoff) + "," + result.InstrumentID + "," + result.FormatPrice() + "\n")
+		}
+	}
+	writeOutput(output.String(), args.outputFile)
+}
+
+// parseCutoffs 合并-cutoff列出的时刻和-every按间隔生成的时刻，都未指定时只取最后一笔订单的时刻
+func parseCutoffs(list, every string, timed *order.TimedOrders) ([]int64, error) {
+	cutoffs := make([]int64, 0)
+	if list != "" {
+		for _, text := range strings.Split(list, ",") {
+			cutoff, err := order.ParseTimeOfDay(strings.TrimSpace(text))
+			if err != nil {
+				return nil, err
+			}
+			cutoffs = append(cutoffs, cutoff)
+		}
+	}
+
+	first, last, ok := timed.Span()
+	if every != "" {
+		interval, err := time.ParseDuration(every)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("无效的-every取值: %s", every)
+		}
+		if ok {
+			cutoffs = append(cutoffs, order.EveryCutoff(first, last, int64(interval))...)
+		}
+	}
+	if len(cutoffs) == 0 && ok {
+		cutoffs = append(cutoffs, last)
+	}
+	return cutoffs, nil
+}
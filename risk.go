@@ -0,0 +1,51 @@
+package main
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/risk"
+	"fmt"
+	"io"
+	"time"
+)
+
+// newRiskChecker 按-risk、-limits、-positions和-trading-date参数创建事前风控检查，合约乘数取自instrument.Default。
+// 未指定规则文件和持仓限额表时返回nil，加载失败时退出
+func newRiskChecker(args cliArgs) *risk.Checker {
+	if args.riskFile == "" && args.limitsFile == "" {
+		return nil
+	}
+
+	var rules []risk.Rule
+	if args.riskFile != "" {
+		var err error
+		if rules, err = risk.LoadRules(args.riskFile); err != nil {
+			fatal("加载风控规则失败", err, "path", args.riskFile)
+		}
+	}
+	checker := risk.NewChecker(rules, instrument.Default)
+
+	if args.limitsFile != "" {
+		schedule, err := risk.LoadLimitSchedule(args.limitsFile)
+		if err != nil {
+			fatal("加载持仓限额表失败", err, "path", args.limitsFile)
+		}
+		var positions []risk.StartingPosition
+		if args.positionsFile != "" {
+			if positions, err = risk.LoadPositions(args.positionsFile); err != nil {
+				fatal("加载期初持仓失败", err, "path", args.positionsFile)
+			}
+		}
+		tradingDate := args.tradingDate
+		if tradingDate.IsZero() {
+			tradingDate = time.Now()
+		}
+		checker.SetPositionLimits(risk.NewPositionLimits(schedule, positions, tradingDate))
+	}
+	return checker
+}
+
+// printRiskSummary 输出各账号的风控汇总
+func printRiskSummary(w io.Writer, checker *risk.Checker) {
+	fmt.Fprintln(w, "风控汇总:")
+	risk.FormatSummaries(w, checker.Summaries())
+}
@@ -0,0 +1,266 @@
+package risk
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/order"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+type (
+	// Checker 按规则逐笔检查订单，只有全部规则通过的订单才计入持仓、名义金额和报单频率
+	Checker struct {
+		rules     []Rule
+		registry  *instrument.Registry
+		limits    *PositionLimits // 为nil时不检查持仓限额
+		positions map[positionKey]*position
+		notional  map[ruleAccount]float64
+		recent    map[ruleAccount][]int64 // 时间窗口内已接受订单的时间戳，升序
+		summaries map[string]*Summary
+		mu        sync.Mutex
+	}
+
+	positionKey struct {
+		account      string
+		instrumentID string
+	}
+
+	position struct {
+		buy  int64
+		sell int64
+	}
+
+	ruleAccount struct {
+		rule    int
+		account string
+	}
+
+	// Summary 单个账号的风控汇总
+	Summary struct {
+		Account  string
+		Accepted int
+		Rejected map[string]int // 按规则统计的拒绝笔数
+		Volume   int64          // 已接受订单的数量之和
+		Notional float64        // 已接受订单的名义金额之和
+	}
+
+	// RejectError 订单违反风控规则
+	RejectError struct {
+		Rule    Rule
+		Account string
+		Value   float64 // 接受该订单后的取值
+		Err     error   // 无法计算取值的原因，例如合约已过交割月份
+	}
+)
+
+// NewChecker 创建风控检查，registry提供计算名义金额的合约乘数，通常为instrument.Default
+func NewChecker(rules []Rule, registry *instrument.Registry) *Checker {
+	return &Checker{
+		rules:     rules,
+		registry:  registry,
+		positions: make(map[positionKey]*position),
+		notional:  make(map[ruleAccount]float64),
+		recent:    make(map[ruleAccount][]int64),
+		summaries: make(map[string]*Summary),
+	}
+}
+
+func (e *RejectError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("账号%s违反风控规则%s(%s): %v", displayAccount(e.Account), e.Rule.Kind, e.Rule.Scope, e.Err)
+	}
+	return fmt.Sprintf("账号%s违反风控规则%s(%s): %s超过上限%s", displayAccount(e.Account), e.Rule.Kind,
+		e.Rule.Scope, formatNumber(e.Value), formatNumber(e.Rule.Limit))
+}
+
+func (e *RejectError) Unwrap() error {
+	return e.Err
+}
+
+// Reason 拒绝原因为规则名
+func (e *RejectError) Reason() string {
+	return e.Rule.Kind
+}
+
+// SetPositionLimits 启用持仓限额检查，在规则文件中的规则之后进行
+func (c *Checker) SetPositionLimits(limits *PositionLimits) {
+	c.mu.Lock()
+	c.limits = limits
+	c.mu.Unlock()
+}
+
+// Check 检查一笔订单，通过时计入账号状态，违反任一规则时返回*RejectError。
+// 订单应按时间顺序提交，没有时间戳的订单在报单频率上视为同一时刻
+func (c *Checker) Check(o order.Order) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	summary, ok := c.summaries[o.Account]
+	if !ok {
+		summary = &Summary{Account: o.Account, Rejected: make(map[string]int)}
+		c.summaries[o.Account] = summary
+	}
+
+	notional := c.orderNotional(o)
+	productCode := o.ProductCode()
+	for i, rule := range c.rules {
+		if !rule.Matches(o.Account, o.InstrumentID, productCode) {
+			continue
+		}
+		if value := c.valueAfter(i, rule, o, notional); value > rule.Limit {
+			summary.Rejected[rule.Kind]++
+			return &RejectError{Rule: rule, Account: o.Account, Value: value}
+		}
+	}
+	if c.limits != nil {
+		// 组合合约的持仓计在各腿上，每条腿按各自的限额检查
+		for _, leg := range legOrders(o) {
+			var pending position
+			if p, ok := c.positions[positionKey{account: leg.Account, instrumentID: leg.InstrumentID}]; ok {
+				pending = *p
+			}
+			if err := c.limits.check(leg, pending); err != nil {
+				summary.Rejected[RULE_POSITION_LIMIT]++
+				return err
+			}
+		}
+	}
+
+	c.accept(o, notional, productCode)
+	summary.Accepted++
+	summary.Volume += int64(o.Volume)
+	summary.Notional += notional
+	return nil
+}
+
+// valueAfter 计算接受订单后规则所约束的取值
+func (c *Checker) valueAfter(i int, rule Rule, o order.Order, notional float64) float64 {
+	key := ruleAccount{rule: i, account: o.Account}
+	switch rule.Kind {
+	case RULE_MAX_ORDER_VOLUME:
+		return float64(o.Volume)
+	case RULE_MAX_POSITION:
+		p := c.positions[positionKey{account: o.Account, instrumentID: o.InstrumentID}]
+		if p == nil {
+			p = &position{}
+		}
+		if o.Direction == 0 {
+			return float64(p.buy + int64(o.Volume))
+		}
+		return float64(p.sell + int64(o.Volume))
+	case RULE_MAX_NOTIONAL:
+		return c.notional[key] + notional
+	case RULE_ORDER_RATE:
+		recent := c.recent[key]
+		expired := 0
+		for expired < len(recent) && recent[expired] <= o.Time-int64(rule.Window) {
+			expired++
+		}
+		c.recent[key] = recent[expired:]
+		return float64(len(recent) - expired + 1)
+	}
+	return 0
+}
+
+// accept 将通过检查的订单计入持仓、名义金额和报单频率，组合合约同时计入各腿的持仓
+func (c *Checker) accept(o order.Order, notional float64, productCode string) {
+	c.addPosition(o)
+	if instrument.IsCombination(o.InstrumentID) {
+		for _, leg := range legOrders(o) {
+			c.addPosition(leg)
+		}
+	}
+
+	for i, rule := range c.rules {
+		if !rule.Matches(o.Account, o.InstrumentID, productCode) {
+			continue
+		}
+		key := ruleAccount{rule: i, account: o.Account}
+		switch rule.Kind {
+		case RULE_MAX_NOTIONAL:
+			c.notional[key] += notional
+		case RULE_ORDER_RATE:
+			c.recent[key] = append(c.recent[key], o.Time)
+		}
+	}
+}
+
+// addPosition 将订单数量计入账号在该合约上的同方向委托
+func (c *Checker) addPosition(o order.Order) {
+	pk := positionKey{account: o.Account, instrumentID: o.InstrumentID}
+	p, ok := c.positions[pk]
+	if !ok {
+		p = &position{}
+		c.positions[pk] = p
+	}
+	if o.Direction == 0 {
+		p.buy += int64(o.Volume)
+	} else {
+		p.sell += int64(o.Volume)
+	}
+}
+
+// orderNotional 订单名义金额，合约乘数按合约表解析（商品期权使用期权品种的参考数据），未知时按1计算；
+// 组合合约的价差可以为负，按绝对值计算
+func (c *Checker) orderNotional(o order.Order) float64 {
+	multiplier := int32(1)
+	if info := c.registry.Lookup(o.InstrumentID); info.Multiplier > 0 {
+		multiplier = info.Multiplier
+	}
+	// 按价格的最短十进制表示换算，避免float32的表示误差进入金额
+	price, _ := strconv.ParseFloat(strconv.FormatFloat(float64(o.Price), 'g', -1, 32), 64)
+	return math.Abs(price) * float64(o.Volume) * float64(multiplier)
+}
+
+// Summaries 按账号排序返回各账号的风控汇总
+func (c *Checker) Summaries() []Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	summaries := make([]Summary, 0, len(c.summaries))
+	for _, summary := range c.summaries {
+		copied := *summary
+		copied.Rejected = make(map[string]int, len(summary.Rejected))
+		for kind, count := range summary.Rejected {
+			copied.Rejected[kind] = count
+		}
+		summaries = append(summaries, copied)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Account < summaries[j].Account
+	})
+	return summaries
+}
+
+// FormatSummaries 每个账号输出一行汇总：通过笔数、数量、名义金额和按规则统计的拒绝笔数
+func FormatSummaries(w io.Writer, summaries []Summary) {
+	for _, summary := range summaries {
+		fmt.Fprintf(w, "账号 %s: 通过 %d 笔（%d 手，名义金额 %s）", displayAccount(summary.Account),
+			summary.Accepted, summary.Volume, formatNumber(math.Round(summary.Notional*100)/100))
+		kinds := make([]string, 0, len(summary.Rejected))
+		for kind := range summary.Rejected {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			fmt.Fprintf(w, "，拒绝[%s] %d 笔", kind, summary.Rejected[kind])
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// displayAccount 空账号显示为"-"
+func displayAccount(account string) string {
+	if account == "" {
+		return "-"
+	}
+	return account
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
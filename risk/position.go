@@ -0,0 +1,256 @@
+package risk
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/order"
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 持仓限额的阶段，按交易日距合约交割月的月数划分
+const (
+	PHASE_GENERAL      = "general"      // 一般月份
+	PHASE_PRE_DELIVERY = "pre_delivery" // 交割月前一个月
+	PHASE_DELIVERY     = "delivery"     // 交割月份
+)
+
+// 账户类别
+const (
+	CATEGORY_SPECULATOR = "spec"  // 投机
+	CATEGORY_HEDGER     = "hedge" // 套期保值
+)
+
+type (
+	// PhaseLimits 各阶段的单边持仓上限（手），负数表示不限
+	PhaseLimits struct {
+		General     int64
+		PreDelivery int64
+		Delivery    int64
+	}
+
+	// StartingPosition 账号在合约上的期初持仓
+	StartingPosition struct {
+		Account      string
+		InstrumentID string
+		Long         int64
+		Short        int64
+		Category     string // CATEGORY_*，默认为投机
+	}
+
+	// PositionLimits 按品种、账户类别和交割月临近程度确定的持仓限额，以及各账号的期初持仓
+	PositionLimits struct {
+		schedule    map[string]map[string]PhaseLimits // 品种代码 -> 账户类别 -> 各阶段上限
+		positions   map[positionKey]StartingPosition
+		tradingDate time.Time
+	}
+)
+
+// NewPositionLimits 创建持仓限额检查，schedule为ParseLimitSchedule的结果
+func NewPositionLimits(schedule map[string]map[string]PhaseLimits, positions []StartingPosition, tradingDate time.Time) *PositionLimits {
+	limits := &PositionLimits{
+		schedule:    schedule,
+		positions:   make(map[positionKey]StartingPosition, len(positions)),
+		tradingDate: tradingDate,
+	}
+	for _, p := range positions {
+		limits.positions[positionKey{account: p.Account, instrumentID: p.InstrumentID}] = p
+	}
+	return limits
+}
+
+// Phase 返回合约在交易日所处的阶段，合约已过交割月或无法解析月份时返回错误
+func (l *PositionLimits) Phase(instrumentID string) (string, error) {
+	year, month, ok := instrument.ContractMonth(instrumentID, l.tradingDate)
+	if !ok {
+		return "", fmt.Errorf("无法从合约%s确定交割月份", instrumentID)
+	}
+	months := (year*12 + int(month)) - (l.tradingDate.Year()*12 + int(l.tradingDate.Month()))
+	switch {
+	case months < 0:
+		return "", fmt.Errorf("合约%s已过交割月份", instrumentID)
+	case months == 0:
+		return PHASE_DELIVERY, nil
+	case months == 1:
+		return PHASE_PRE_DELIVERY, nil
+	}
+	return PHASE_GENERAL, nil
+}
+
+// legOrders 将订单分解为计入持仓的各腿：单腿合约为订单本身；两腿组合合约买入为买第一腿、卖第二腿，卖出相反，
+// 数量与组合相同。其他组合合约没有约定各腿方向，不计入持仓
+func legOrders(o order.Order) []order.Order {
+	if !instrument.IsCombination(o.InstrumentID) {
+		return []order.Order{o}
+	}
+	combination, ok := instrument.ParseCombination(o.InstrumentID)
+	if !ok || len(combination.Legs) != 2 {
+		return nil
+	}
+	first, second := o, o
+	first.InstrumentID, second.InstrumentID = combination.Legs[0], combination.Legs[1]
+	second.Direction = 1 - o.Direction
+	return []order.Order{first, second}
+}
+
+// check 按最坏情况检查单腿订单：期初持仓加上同方向已接受和本笔委托全部成交（均视为开仓）后的单边持仓不超过限额。
+// pending为账号在该合约上已接受的委托数量，组合合约应先以legOrders分解
+func (l *PositionLimits) check(o order.Order, pending position) error {
+	byCategory, ok := l.schedule[o.ProductCode()]
+	if !ok {
+		return nil
+	}
+	key := positionKey{account: o.Account, instrumentID: o.InstrumentID}
+	start := l.positions[key]
+	category := start.Category
+	if category == "" {
+		category = CATEGORY_SPECULATOR
+	}
+	limits, ok := byCategory[category]
+	if !ok {
+		return nil
+	}
+
+	phase, err := l.Phase(o.InstrumentID)
+	if err != nil {
+		return &RejectError{Rule: Rule{Kind: RULE_POSITION_LIMIT, Account: o.Account, Scope: o.InstrumentID}, Account: o.Account, Err: err}
+	}
+	limit := limits.General
+	switch phase {
+	case PHASE_PRE_DELIVERY:
+		limit = limits.PreDelivery
+	case PHASE_DELIVERY:
+		limit = limits.Delivery
+	}
+	if limit < 0 {
+		return nil
+	}
+
+	worst := start.Short + pending.sell + int64(o.Volume)
+	if o.Direction == 0 {
+		worst = start.Long + pending.buy + int64(o.Volume)
+	}
+	if worst > limit {
+		return &RejectError{
+			Rule:    Rule{Kind: RULE_POSITION_LIMIT, Account: o.Account, Scope: o.InstrumentID + " " + phase, Limit: float64(limit)},
+			Account: o.Account,
+			Value:   float64(worst),
+		}
+	}
+	return nil
+}
+
+// LoadLimitSchedule 读取持仓限额表文件
+func LoadLimitSchedule(filename string) (map[string]map[string]PhaseLimits, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseLimitSchedule(file)
+}
+
+// ParseLimitSchedule 解析CSV格式的持仓限额表，每行为"product,category,general,pre_delivery,delivery"，
+// 上限留空表示不限，未列出的品种或类别不受限，#开头的行为注释，例如
+//
+//	T,spec,2000,600,300
+//	T,hedge,,,
+func ParseLimitSchedule(reader io.Reader) (map[string]map[string]PhaseLimits, error) {
+	schedule := make(map[string]map[string]PhaseLimits)
+	err := scanCSV(reader, "持仓限额表", func(record []string) error {
+		if len(record) != 5 {
+			return fmt.Errorf("字段数应为5，实际为%d", len(record))
+		}
+		product, category := record[0], record[1]
+		if product == "" {
+			return fmt.Errorf("品种代码为空")
+		}
+		if category != CATEGORY_SPECULATOR && category != CATEGORY_HEDGER {
+			return fmt.Errorf("无效的账户类别: %s", category)
+		}
+
+		values := make([]int64, 3)
+		for i, text := range record[2:] {
+			values[i] = -1
+			if text == "" {
+				continue
+			}
+			limit, err := strconv.ParseInt(text, 10, 64)
+			if err != nil || limit < 0 {
+				return fmt.Errorf("无效的持仓上限: %s", text)
+			}
+			values[i] = limit
+		}
+		if schedule[product] == nil {
+			schedule[product] = make(map[string]PhaseLimits)
+		}
+		schedule[product][category] = PhaseLimits{General: values[0], PreDelivery: values[1], Delivery: values[2]}
+		return nil
+	})
+	return schedule, err
+}
+
+// LoadPositions 读取期初持仓文件
+func LoadPositions(filename string) ([]StartingPosition, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParsePositions(file)
+}
+
+// ParsePositions 解析CSV格式的期初持仓，每行为"account,instrumentID,long,short[,spec|hedge]"，#开头的行为注释
+func ParsePositions(reader io.Reader) ([]StartingPosition, error) {
+	positions := make([]StartingPosition, 0)
+	err := scanCSV(reader, "期初持仓", func(record []string) error {
+		if len(record) != 4 && len(record) != 5 {
+			return fmt.Errorf("字段数应为4或5，实际为%d", len(record))
+		}
+		p := StartingPosition{Account: record[0], InstrumentID: record[1], Category: CATEGORY_SPECULATOR}
+		if p.InstrumentID == "" {
+			return fmt.Errorf("合约代码为空")
+		}
+		var err error
+		if p.Long, err = strconv.ParseInt(record[2], 10, 64); err != nil || p.Long < 0 {
+			return fmt.Errorf("无效的多头持仓: %s", record[2])
+		}
+		if p.Short, err = strconv.ParseInt(record[3], 10, 64); err != nil || p.Short < 0 {
+			return fmt.Errorf("无效的空头持仓: %s", record[3])
+		}
+		if len(record) == 5 {
+			if record[4] != CATEGORY_SPECULATOR && record[4] != CATEGORY_HEDGER {
+				return fmt.Errorf("无效的账户类别: %s", record[4])
+			}
+			p.Category = record[4]
+		}
+		positions = append(positions, p)
+		return nil
+	})
+	return positions, err
+}
+
+// scanCSV 逐行解析CSV，忽略空行和#开头的注释，字段去除首尾空白，出错时附带文件说明和行号
+func scanCSV(reader io.Reader, name string, parse func(record []string) error) error {
+	scanner := bufio.NewScanner(reader)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		record := strings.Split(line, ",")
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		if err := parse(record); err != nil {
+			return fmt.Errorf("%s第%d行: %v", name, lineNum, err)
+		}
+	}
+	return scanner.Err()
+}
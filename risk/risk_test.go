@@ -0,0 +1,183 @@
+package risk
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/order"
+	"AuctionMatch/refdata"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(`# 规则,账号,范围,上限[,窗口]
+max_order_volume,*,IF,10
+
+max_position, A001 ,IF2412,30
+order_rate,*,*,2,1s
+`))
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	want := []Rule{
+		{Kind: RULE_MAX_ORDER_VOLUME, Account: ANY, Scope: "IF", Limit: 10},
+		{Kind: RULE_MAX_POSITION, Account: "A001", Scope: "IF2412", Limit: 30},
+		{Kind: RULE_ORDER_RATE, Account: ANY, Scope: ANY, Limit: 2, Window: time.Second},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("ParseRules() = %+v, want %+v", rules, want)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("第%d条规则 = %+v, want %+v", i, rules[i], want[i])
+		}
+	}
+
+	for _, line := range []string{
+		"max_volume,*,*,10",
+		"max_position,,IF2412,10",
+		"max_notional,*,*,-1",
+		"order_rate,*,*,10",
+		"order_rate,*,*,10,0s",
+		"max_position,*,*,10,1s",
+		"max_position,*,*",
+	} {
+		if _, err := ParseRules(strings.NewReader(line)); err == nil {
+			t.Errorf("ParseRules(%q) 应返回错误", line)
+		}
+	}
+}
+
+func TestChecker(t *testing.T) {
+	rules := []Rule{
+		{Kind: RULE_MAX_ORDER_VOLUME, Account: ANY, Scope: "IF", Limit: 10},
+		{Kind: RULE_MAX_POSITION, Account: "A001", Scope: "IF2412", Limit: 12},
+		{Kind: RULE_MAX_NOTIONAL, Account: ANY, Scope: ANY, Limit: 27000000},
+		{Kind: RULE_ORDER_RATE, Account: "B002", Scope: ANY, Limit: 2, Window: time.Second},
+		{Kind: RULE_MAX_NOTIONAL, Account: "D004", Scope: ANY, Limit: 30000},
+	}
+	checker := NewChecker(rules, instrument.NewRegistry(refdata.NewRegistry(), time.Time{}))
+
+	second := int64(time.Second)
+	tests := []struct {
+		name  string
+		order order.Order
+		rule  string // 期望违反的规则，为空表示通过
+	}{
+		{"单笔数量超限", order.Order{InstrumentID: "IF2412", Direction: 0, Price: 4000, Volume: 11, Account: "A001"}, RULE_MAX_ORDER_VOLUME},
+		{"其他品种不受单笔数量限制", order.Order{InstrumentID: "T2503", Direction: 0, Price: 100, Volume: 11, Account: "A001"}, ""},
+		{"买方持仓", order.Order{InstrumentID: "IF2412", Direction: 0, Price: 4000, Volume: 8, Account: "A001"}, ""},
+		{"买方持仓超限", order.Order{InstrumentID: "IF2412", Direction: 0, Price: 4000, Volume: 5, Account: "A001"}, RULE_MAX_POSITION},
+		{"卖方单独计算", order.Order{InstrumentID: "IF2412", Direction: 1, Price: 4000, Volume: 5, Account: "A001"}, ""},
+		{"其他账号不受持仓限制", order.Order{InstrumentID: "IF2412", Direction: 0, Price: 4000, Volume: 10, Account: "C003"}, ""},
+		// A001已有 100×11×10000 + 4000×13×300 = 26600000，再加1000000超过27000000
+		{"名义金额超限", order.Order{InstrumentID: "T2503", Direction: 1, Price: 100, Volume: 1, Account: "A001"}, RULE_MAX_NOTIONAL},
+		{"频率1", order.Order{InstrumentID: "TS2412", Direction: 0, Price: 101, Volume: 1, Time: 9 * 3600 * second, Account: "B002"}, ""},
+		{"频率2", order.Order{InstrumentID: "TS2412", Direction: 0, Price: 101, Volume: 1, Time: 9*3600*second + second/2, Account: "B002"}, ""},
+		{"频率超限", order.Order{InstrumentID: "TS2412", Direction: 0, Price: 101, Volume: 1, Time: 9*3600*second + second*9/10, Account: "B002"}, RULE_ORDER_RATE},
+		{"窗口滑过后恢复", order.Order{InstrumentID: "TS2412", Direction: 0, Price: 101, Volume: 1, Time: 9*3600*second + second, Account: "B002"}, ""},
+		// 负价差按绝对值计算名义金额：0.2×10×10000 = 20000，第二笔累计40000超过30000
+		{"负价差组合", order.Order{InstrumentID: "SP T2412&T2503", Direction: 1, Price: -0.2, Volume: 10, Account: "D004"}, ""},
+		{"负价差组合名义金额超限", order.Order{InstrumentID: "SP T2412&T2503", Direction: 1, Price: -0.2, Volume: 10, Account: "D004"}, RULE_MAX_NOTIONAL},
+	}
+	for _, tt := range tests {
+		err := checker.Check(tt.order)
+		var reject *RejectError
+		switch {
+		case tt.rule == "" && err != nil:
+			t.Errorf("%s: Check() error = %v", tt.name, err)
+		case tt.rule != "" && (!errors.As(err, &reject) || reject.Reason() != tt.rule):
+			t.Errorf("%s: Check() error = %v, want 违反%s", tt.name, err, tt.rule)
+		}
+	}
+
+	summaries := checker.Summaries()
+	if len(summaries) != 4 || summaries[0].Account != "A001" || summaries[0].Accepted != 3 || summaries[0].Volume != 24 ||
+		summaries[0].Rejected[RULE_MAX_POSITION] != 1 || summaries[0].Rejected[RULE_MAX_NOTIONAL] != 1 ||
+		summaries[3].Account != "D004" || summaries[3].Notional != 20000 {
+		t.Fatalf("Summaries() = %+v", summaries)
+	}
+
+	var buf bytes.Buffer
+	FormatSummaries(&buf, summaries[:1])
+	want := "账号 A001: 通过 3 笔（24 手，名义金额 26600000），拒绝[max_notional] 1 笔，拒绝[max_order_volume] 1 笔，拒绝[max_position] 1 笔\n"
+	if buf.String() != want {
+		t.Errorf("FormatSummaries() = %q, want %q", buf.String(), want)
+	}
+}
+
+// TestCheckerOptionMultiplier 商品期权的名义金额使用期权品种参考数据中的合约乘数
+func TestCheckerOptionMultiplier(t *testing.T) {
+	products := refdata.NewRegistry()
+	products.Put(refdata.Product{Code: "m", Tick: 1, Multiplier: 10})
+	products.Put(refdata.Product{Code: "m" + instrument.OPTION_PRODUCT_SUFFIX, Tick: 0.5, Multiplier: 20})
+	rules := []Rule{{Kind: RULE_MAX_NOTIONAL, Account: ANY, Scope: ANY, Limit: 30000}}
+	checker := NewChecker(rules, instrument.NewRegistry(products, time.Date(2024, 8, 1, 0, 0, 0, 0, time.Local)))
+
+	if err := checker.Check(order.Order{InstrumentID: "m2409", Direction: 0, Price: 100, Volume: 20, Account: "E005"}); err != nil {
+		t.Errorf("期货 100×20×10 = 20000: Check() error = %v", err)
+	}
+	err := checker.Check(order.Order{InstrumentID: "m2409-C-3000", Direction: 0, Price: 100, Volume: 20, Account: "E006"})
+	var reject *RejectError
+	if !errors.As(err, &reject) || reject.Value != 40000 {
+		t.Errorf("期权 100×20×20 = 40000: Check() error = %v", err)
+	}
+}
+
+func TestPositionLimits(t *testing.T) {
+	schedule, err := ParseLimitSchedule(strings.NewReader(`# 品种,类别,一般月份,交割月前一个月,交割月份
+T,spec,2000,600,300
+IF,spec,,,10
+`))
+	if err != nil {
+		t.Fatalf("ParseLimitSchedule() error = %v", err)
+	}
+	positions, err := ParsePositions(strings.NewReader("A001,T2412,550,0\nH001,T2412,5000,0,hedge\n"))
+	if err != nil {
+		t.Fatalf("ParsePositions() error = %v", err)
+	}
+	checker := NewChecker(nil, instrument.NewRegistry(refdata.NewRegistry(), time.Time{}))
+	checker.SetPositionLimits(NewPositionLimits(schedule, positions, time.Date(2024, 11, 20, 0, 0, 0, 0, time.Local)))
+
+	tests := []struct {
+		name   string
+		order  order.Order
+		reject bool
+	}{
+		{"交割月前一个月", order.Order{InstrumentID: "T2412", Direction: 0, Price: 106, Volume: 40, Account: "A001"}, false},
+		{"期初持仓加委托超限", order.Order{InstrumentID: "T2412", Direction: 0, Price: 106, Volume: 20, Account: "A001"}, true},
+		{"空头单独计算", order.Order{InstrumentID: "T2412", Direction: 1, Price: 106, Volume: 600, Account: "A001"}, false},
+		{"套保账户不受限", order.Order{InstrumentID: "T2412", Direction: 0, Price: 106, Volume: 100, Account: "H001"}, false},
+		{"交割月份", order.Order{InstrumentID: "T2411", Direction: 0, Price: 106, Volume: 301, Account: "B002"}, true},
+		{"一般月份", order.Order{InstrumentID: "T2503", Direction: 0, Price: 106, Volume: 2000, Account: "B002"}, false},
+		{"已过交割月份", order.Order{InstrumentID: "T2410", Direction: 0, Price: 106, Volume: 1, Account: "B002"}, true},
+		{"一般月份不限", order.Order{InstrumentID: "IF2501", Direction: 0, Price: 3900, Volume: 50, Account: "B002"}, false},
+		{"品种不在限额表中", order.Order{InstrumentID: "TS2411", Direction: 0, Price: 101, Volume: 5000, Account: "B002"}, false},
+		// 买入组合即买T2412、卖T2503：A001的T2412多头已有550+40，再买11超过600
+		{"组合合约第一腿超限", order.Order{InstrumentID: "SP T2412&T2503", Direction: 0, Price: -0.2, Volume: 11, Account: "A001"}, true},
+		{"组合合约两腿未超限", order.Order{InstrumentID: "SP T2412&T2503", Direction: 0, Price: -0.2, Volume: 10, Account: "A001"}, false},
+		{"组合合约计入单腿", order.Order{InstrumentID: "T2412", Direction: 0, Price: 106, Volume: 1, Account: "A001"}, true},
+		// 卖出组合即卖T2412、买T2503：B002的T2503多头已有2000，再买1超过2000
+		{"组合合约第二腿超限", order.Order{InstrumentID: "SP T2412&T2503", Direction: 1, Price: -0.2, Volume: 1, Account: "B002"}, true},
+	}
+	for _, tt := range tests {
+		err := checker.Check(tt.order)
+		var reject *RejectError
+		if tt.reject != (err != nil) || (err != nil && (!errors.As(err, &reject) || reject.Reason() != RULE_POSITION_LIMIT)) {
+			t.Errorf("%s: Check() error = %v, want 拒绝=%v", tt.name, err, tt.reject)
+		}
+	}
+
+	for _, line := range []string{"T,spec,2000,600", "T,retail,1,1,1", "T,spec,-1,1,1"} {
+		if _, err := ParseLimitSchedule(strings.NewReader(line)); err == nil {
+			t.Errorf("ParseLimitSchedule(%q) 应返回错误", line)
+		}
+	}
+	for _, line := range []string{"A001,T2412,1", "A001,,1,1", "A001,T2412,x,1", "A001,T2412,1,1,retail"} {
+		if _, err := ParsePositions(strings.NewReader(line)); err == nil {
+			t.Errorf("ParsePositions(%q) 应返回错误", line)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package risk
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 规则种类
+const (
+	RULE_MAX_ORDER_VOLUME = "max_order_volume" // 单笔最大下单数量（手）
+	RULE_MAX_POSITION     = "max_position"     // 单方向累计委托数量上限，即全部成交后的最大持仓（手）
+	RULE_MAX_NOTIONAL     = "max_notional"     // 累计委托名义金额上限：价格×数量×合约乘数
+	RULE_ORDER_RATE       = "order_rate"       // 时间窗口内的最大报单笔数
+	RULE_POSITION_LIMIT   = "position_limit"   // 按交割月临近程度的持仓限额，由PositionLimits检查，不出现在规则文件中
+
+	ANY = "*" // 账号或范围的通配符
+)
+
+// Rule 一条风控规则。账号为*时对每个账号分别计算；范围可以是*、品种代码或合约代码
+type Rule struct {
+	Kind    string
+	Account string
+	Scope   string
+	Limit   float64
+	Window  time.Duration // 仅order_rate使用，按订单时间戳计算
+}
+
+// Matches 规则是否适用于该账号的该合约订单
+func (r Rule) Matches(account, instrumentID, productCode string) bool {
+	if r.Account != ANY && r.Account != account {
+		return false
+	}
+	return r.Scope == ANY || r.Scope == instrumentID || r.Scope == productCode
+}
+
+// LoadRules 读取规则文件
+func LoadRules(filename string) ([]Rule, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseRules(file)
+}
+
+// ParseRules 解析CSV格式的规则，每行为"rule,account,scope,limit[,window]"，#开头的行为注释，例如
+//
+//	max_order_volume,*,IF,10
+//	max_position,A001,IF2412,30
+//	max_notional,*,*,50000000
+//	order_rate,*,*,100,1s
+func ParseRules(reader io.Reader) ([]Rule, error) {
+	rules := make([]Rule, 0)
+	err := scanCSV(reader, "风控规则", func(record []string) error {
+		rule, err := parseRule(record)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+		return nil
+	})
+	return rules, err
+}
+
+func parseRule(record []string) (Rule, error) {
+	if len(record) != 4 && len(record) != 5 {
+		return Rule{}, fmt.Errorf("字段数应为4或5，实际为%d", len(record))
+	}
+
+	rule := Rule{Kind: record[0], Account: record[1], Scope: record[2]}
+	switch rule.Kind {
+	case RULE_MAX_ORDER_VOLUME, RULE_MAX_POSITION, RULE_MAX_NOTIONAL, RULE_ORDER_RATE:
+	default:
+		return Rule{}, fmt.Errorf("未知的规则: %s", rule.Kind)
+	}
+	if rule.Account == "" || rule.Scope == "" {
+		return Rule{}, fmt.Errorf("账号和范围不能为空，可使用%s", ANY)
+	}
+	limit, err := strconv.ParseFloat(record[3], 64)
+	if err != nil || limit < 0 {
+		return Rule{}, fmt.Errorf("无效的上限: %s", record[3])
+	}
+	rule.Limit = limit
+
+	if rule.Kind == RULE_ORDER_RATE {
+		if len(record) != 5 {
+			return Rule{}, fmt.Errorf("%s需要时间窗口", RULE_ORDER_RATE)
+		}
+		if rule.Window, err = time.ParseDuration(record[4]); err != nil || rule.Window <= 0 {
+			return Rule{}, fmt.Errorf("无效的时间窗口: %s", record[4])
+		}
+	} else if len(record) == 5 {
+		return Rule{}, fmt.Errorf("%s不支持时间窗口", rule.Kind)
+	}
+	return rule, nil
+}
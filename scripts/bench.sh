@@ -0,0 +1,17 @@
+#! /bin/bash
+# 运行基准测试并与基线对比，退化超过阈值（默认10%）时失败
+#   ./scripts/bench.sh              运行并写入 bench/new.txt，存在 bench/baseline.txt 时与之对比
+#   ./scripts/bench.sh baseline     运行并写入 bench/baseline.txt
+set -e
+cd "$(dirname "$0")/.."
+
+out=bench/new.txt
+if [ "$1" == "baseline" ]; then
+    out=bench/baseline.txt
+fi
+
+go test ./... -run '^$' -bench . -benchmem -count "${COUNT:-5}" | tee "$out"
+
+if [ "$out" == bench/new.txt ] && [ -f bench/baseline.txt ]; then
+    go run . benchcmp -threshold "${THRESHOLD:-10}" bench/baseline.txt bench/new.txt
+fi
@@ -1,6 +1,6 @@
 #! /bin/bash
 # build for linux
-go build -o auctionMatch main.go
+go build -o auctionMatch .
 
 # build for windows
-# GOOS=windows GOARCH=amd64 go build -o auctionMatch.exe main.go
+# GOOS=windows GOARCH=amd64 go build -o auctionMatch.exe .
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"AuctionMatch/journal"
+	"AuctionMatch/order"
+	"AuctionMatch/refdata"
+	"AuctionMatch/server"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+)
+
+// runServe 启动HTTP撮合服务
+func runServe(argv []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "监听地址")
+	maxBody := flags.Int64("max-body", server.DEFAULT_MAX_BODY_BYTES, "订单请求体大小上限（字节）")
+	refdataFile := flags.String("refdata", "", "启动时加载的参考数据文件（product,tick[,maxOrderVolume[,multiplier]]，tick可按价格分段，例如0.2;100:1）")
+	snapshotFile := flags.String("snapshot", "", "启动时恢复/v1/books聚合委托簿的快照文件")
+	applyLogFlags := addLogFlags(flags)
+	flags.Parse(argv)
+	applyLogFlags()
+
+	if *refdataFile != "" {
+		loadRefdata(*refdataFile)
+	}
+
+	handler := server.NewHTTPServer(runtime.NumCPU())
+	handler.MaxBodyBytes = *maxBody
+	if *snapshotFile != "" {
+		handler.RestoreBooks(readSnapshot(*snapshotFile))
+	}
+
+	slog.Info("HTTP服务监听", "addr", *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		fatal("HTTP服务退出", err)
+	}
+}
+
+// loadRefdata 加载参考数据文件到refdata.Default，失败时退出
+func loadRefdata(filename string) {
+	file, err := os.Open(filename)
+	if err != nil {
+		fatal("无法打开参考数据文件", err)
+	}
+	defer file.Close()
+
+	if _, err := refdata.Default.Load(file); err != nil {
+		fatal("加载参考数据失败", err)
+	}
+}
+
+// STP_USAGE 撮合服务-stp参数的说明
+const STP_USAGE = "同一账号买卖单相遇时的自成交防范方式: none、cancel-newest、cancel-oldest、decrement-both、cancel-both"
+
+// mustParseSelfTradePrevention 解析-stp参数，无效时退出
+func mustParseSelfTradePrevention(name string) order.SelfTradePrevention {
+	stp, err := order.ParseSelfTradePrevention(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return stp
+}
+
+// runTCP 启动长连接集合竞价仿真服务
+func runTCP(argv []string) {
+	flags := flag.NewFlagSet("tcp", flag.ExitOnError)
+	addr := flags.String("addr", ":9000", "监听地址")
+	refdataFile := flags.String("refdata", "", "启动时加载的参考数据文件（product,tick[,maxOrderVolume[,multiplier]]，tick可按价格分段，例如0.2;100:1）")
+	journalFile := flags.String("journal", "", "预写日志文件，启动时从中恢复集合竞价时段")
+	fsync := flags.String("fsync", "always", "预写日志落盘策略: always、interval、never")
+	fsyncInterval := flags.Duration("fsync-interval", journal.DEFAULT_SYNC_INTERVAL, "interval策略下的落盘间隔")
+	stpMode := flags.String("stp", "none", STP_USAGE)
+	adminToken := flags.String("admin-token", "", "管理命令（OPEN、UNCROSS）的认证口令，连接先发送AUTH <token>；为空时随机生成并输出到日志")
+	applyLogFlags := addLogFlags(flags)
+	flags.Parse(argv)
+	applyLogFlags()
+
+	if *refdataFile != "" {
+		loadRefdata(*refdataFile)
+	}
+	if *adminToken == "" {
+		*adminToken = randomToken()
+		slog.Info("已生成TCP管理口令", "token", *adminToken)
+	}
+
+	stp := mustParseSelfTradePrevention(*stpMode)
+	session := order.NewCallSession(order.WithSelfTradePrevention(stp))
+	var writer *journal.Writer
+	if *journalFile != "" {
+		policy, err := journal.ParseSyncPolicy(*fsync)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		session = recoverJournal(*journalFile, order.WithSelfTradePrevention(stp))
+		if writer, err = journal.OpenWriter(*journalFile, policy, *fsyncInterval); err != nil {
+			fatal("无法打开预写日志", err)
+		}
+		defer writer.Close()
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fatal("无法监听", err, "addr", *addr)
+	}
+
+	slog.Info("TCP服务监听", "addr", listener.Addr().String())
+	srv := server.NewJournaledTCPServer(session, writer)
+	srv.AdminToken = *adminToken
+	if err := srv.Serve(listener); err != nil {
+		fatal("TCP服务退出", err)
+	}
+}
+
+// randomToken 生成随机的管理口令
+func randomToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		fatal("无法生成管理口令", err)
+	}
+	return hex.EncodeToString(buf)
+}
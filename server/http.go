@@ -0,0 +1,308 @@
+package server
+
+import (
+	"AuctionMatch/metrics"
+	"AuctionMatch/order"
+	"AuctionMatch/refdata"
+	"AuctionMatch/snapshot"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	DEFAULT_MAX_BODY_BYTES = 64 << 20 // 默认请求体大小上限
+	MAX_REFDATA_BYTES      = 1 << 20  // 参考数据请求体大小上限
+)
+
+type (
+	// HTTPServer 以HTTP方式提供集合竞价撮合服务
+	HTTPServer struct {
+		NumCPU       int                       // 撮合使用的CPU数，传给order.NewOrderProcessor
+		MaxBodyBytes int64                     // 订单请求体大小上限
+		books        *order.IncrementalAuction // 跨请求累积的聚合委托簿
+		booksMu      sync.Mutex
+		mux          *http.ServeMux
+	}
+
+	// jsonOrder JSON格式的订单，价格保留原始文本以便按输入精度输出
+	jsonOrder struct {
+		InstrumentID string      `json:"instrumentID"`
+		Direction    int8        `json:"direction"`
+		Price        json.Number `json:"price"`
+		Volume       int32       `json:"volume"`
+	}
+
+	// auctionResult 单个合约的撮合结果，无成交时price为空串
+	auctionResult struct {
+		InstrumentID string `json:"instrumentID"`
+		Price        string `json:"price"`
+	}
+
+	// bookEvent 已解析的委托簿订单事件，应用失败时按行号上报
+	bookEvent struct {
+		order     order.Order
+		priceText string
+		line      int
+	}
+
+	auctionResponse struct {
+		Results []auctionResult `json:"results"`
+		Errors  []string        `json:"errors,omitempty"`
+	}
+
+	errorResponse struct {
+		Error string `json:"error"`
+	}
+)
+
+// NewHTTPServer 创建HTTP服务
+func NewHTTPServer(numCPU int) *HTTPServer {
+	s := &HTTPServer{
+		NumCPU:       numCPU,
+		MaxBodyBytes: DEFAULT_MAX_BODY_BYTES,
+		books:        order.NewIncrementalAuction(),
+		mux:          http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.Handle("/metrics", metrics.Default)
+	s.mux.HandleFunc("/v1/auction", s.handleAuction)
+	s.mux.HandleFunc("/v1/refdata", s.handleRefdata)
+	s.mux.HandleFunc("/v1/books", s.handleBooks)
+	s.mux.HandleFunc("/v1/books/snapshot", s.handleSnapshot)
+	return s
+}
+
+// RestoreBooks 以快照恢复的聚合委托簿替换当前状态
+func (s *HTTPServer) RestoreBooks(books *order.IncrementalAuction) {
+	s.booksMu.Lock()
+	s.books = books
+	s.booksMu.Unlock()
+}
+
+func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *HTTPServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "ok\n")
+}
+
+// handleAuction 接收一批CSV或JSON格式的订单，返回各合约的集合竞价价格。
+// 查询参数scale=input表示按输入精度输出，format=csv或Accept: text/csv时返回CSV
+func (s *HTTPServer) handleAuction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("仅支持POST"))
+		return
+	}
+
+	scaleMode := order.ScaleByTick
+	switch r.URL.Query().Get("scale") {
+	case "", "tick":
+	case "input":
+		scaleMode = order.ScaleByInput
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("无效的scale取值: %s", r.URL.Query().Get("scale")))
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, s.MaxBodyBytes)
+	var input io.Reader = body
+	if isJSON(r.Header.Get("Content-Type")) {
+		csv, err := jsonToCSV(body)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		input = strings.NewReader(csv)
+	}
+
+	stream := order.StreamReader(input)
+	waitErrors := collectErrors(stream)
+	results := order.NewOrderProcessor(s.NumCPU, order.WithScaleMode(scaleMode)).Process(stream)
+	errs := waitErrors()
+
+	if stream.Err != nil {
+		writeBodyError(w, stream.Err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" || strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		for _, result := range results {
+			io.WriteString(w, result.InstrumentID+","+result.FormatPrice()+"\n")
+		}
+		return
+	}
+
+	response := auctionResponse{Results: make([]auctionResult, len(results)), Errors: errs}
+	for i, result := range results {
+		response.Results[i] = auctionResult{InstrumentID: result.InstrumentID, Price: result.FormatPrice()}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleRefdata 查询或上传"product,tick[,maxOrderVolume[,multiplier]]"格式的参考数据（tick可按价格分段，见refdata.Registry.Load），上传的数据合并到进程内的refdata.Default
+func (s *HTTPServer) handleRefdata(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		refdata.FormatProducts(w, refdata.Default.Products())
+	case http.MethodPost, http.MethodPut:
+		count, err := refdata.Default.Load(http.MaxBytesReader(w, r.Body, MAX_REFDATA_BYTES))
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"loaded": count})
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的方法: %s", r.Method))
+	}
+}
+
+// handleBooks GET返回聚合委托簿当前的集合竞价价格；POST按行追加订单事件，volume为负数表示撤单。
+// POST先读完并解析整个请求体，再持有booksMu应用，上传缓慢的客户端不会阻塞其他请求；请求体读取失败时不应用任何事件
+func (s *HTTPServer) handleBooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		stream := order.StreamReader(http.MaxBytesReader(w, r.Body, s.MaxBodyBytes))
+		waitErrors := collectErrors(stream)
+		var events []bookEvent
+		order.ReadOrders(stream, func(o order.Order, record []string) {
+			events = append(events, bookEvent{order: o, priceText: record[2], line: stream.Line})
+		})
+		errs := waitErrors()
+		if stream.Err != nil {
+			writeBodyError(w, stream.Err)
+			return
+		}
+
+		s.booksMu.Lock()
+		for _, event := range events {
+			if _, err := s.books.Apply(event.order, event.priceText); err != nil {
+				errs = append(errs, (&order.RecordError{Line: event.line, InstrumentID: event.order.InstrumentID,
+					Stage: order.STAGE_APPLY, Err: err}).Error())
+			}
+		}
+		s.booksMu.Unlock()
+		s.writeBooks(w, errs)
+		return
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的方法: %s", r.Method))
+		return
+	}
+	s.writeBooks(w, nil)
+}
+
+func (s *HTTPServer) writeBooks(w http.ResponseWriter, errs []string) {
+	s.booksMu.Lock()
+	results := s.books.Results()
+	s.booksMu.Unlock()
+
+	response := auctionResponse{Results: make([]auctionResult, len(results)), Errors: errs}
+	for i, result := range results {
+		response.Results[i] = auctionResult{InstrumentID: result.InstrumentID, Price: result.FormatPrice()}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleSnapshot GET下载聚合委托簿快照，PUT以上传的快照替换当前状态
+func (s *HTTPServer) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var buf bytes.Buffer
+		s.booksMu.Lock()
+		err := snapshot.Write(&buf, s.books)
+		s.booksMu.Unlock()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(buf.Bytes())
+	case http.MethodPut:
+		books, err := snapshot.Read(http.MaxBytesReader(w, r.Body, s.MaxBodyBytes))
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		s.RestoreBooks(books)
+		s.writeBooks(w, nil)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的方法: %s", r.Method))
+	}
+}
+
+// collectErrors 在后台收集订单流中的错误，返回的函数在订单流处理完毕后调用，等待读取结束并返回全部错误
+func collectErrors(stream *order.OrderStream) func() []string {
+	errs := make([]string, 0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range stream.Error {
+			errs = append(errs, err.Error())
+		}
+	}()
+
+	return func() []string {
+		<-stream.Done
+		// 处理方和读取协程均已退出，不会再有错误写入
+		close(stream.Error)
+		<-done
+		return errs
+	}
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json"
+}
+
+// jsonToCSV 将JSON订单数组转换为CSV行，与文件输入走同一条解析路径。
+// 合约代码包含逗号或换行时会拆出额外的字段或订单，整个请求按400拒绝
+func jsonToCSV(body io.Reader) (string, error) {
+	var orders []jsonOrder
+	if err := json.NewDecoder(body).Decode(&orders); err != nil {
+		return "", err
+	}
+	var csv strings.Builder
+	for i, o := range orders {
+		if strings.ContainsAny(o.InstrumentID, ",\r\n") {
+			return "", fmt.Errorf("第%d个订单的instrumentID包含逗号或换行: %q", i+1, o.InstrumentID)
+		}
+		fmt.Fprintf(&csv, "%s,%d,%s,%d\n", o.InstrumentID, o.Direction, o.Price, o.Volume)
+	}
+	return csv.String(), nil
+}
+
+// writeBodyError 请求体超限时返回413，其他读取或解析错误返回400
+func writeBodyError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("请求体超过%d字节", maxBytesErr.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func post(t *testing.T, url, contentType, body string) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.Post(url, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+func TestHTTPServer(t *testing.T) {
+	handler := NewHTTPServer(2)
+	handler.MaxBodyBytes = 1024
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	t.Run("健康检查", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/healthz")
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("健康检查失败: %v %v", err, resp)
+		}
+		resp.Body.Close()
+	})
+
+	t.Run("运行指标", func(t *testing.T) {
+		post(t, ts.URL+"/v1/auction", "text/csv", "IF2412,0,3973.4,3\nIF2412,x,3973.0,2\n")
+		resp, err := http.Get(ts.URL + "/metrics")
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		for _, want := range []string{
+			"\nauction_orders_total ",
+			`auction_orders_rejected_total{reason="direction"}`,
+			`auction_stage_duration_seconds_count{stage="parse"}`,
+		} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("/metrics 缺少 %s:\n%s", want, data)
+			}
+		}
+	})
+
+	t.Run("CSV订单", func(t *testing.T) {
+		resp, body := post(t, ts.URL+"/v1/auction", "text/csv",
+			"IF2412,0,3973.4,3\nTS2412,0,101.234,1\nIF2412,1,3973.0,2\nbad,x,1,1\n")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("状态码 %d: %s", resp.StatusCode, body)
+		}
+		var got auctionResponse
+		if err := json.Unmarshal([]byte(body), &got); err != nil {
+			t.Fatalf("响应不是JSON: %v", err)
+		}
+		want := []auctionResult{{InstrumentID: "IF2412", Price: "3973.4"}, {InstrumentID: "TS2412", Price: ""}}
+		if !reflect.DeepEqual(got.Results, want) || len(got.Errors) != 1 {
+			t.Errorf("响应 = %+v, want results %+v 和1个错误", got, want)
+		}
+	})
+
+	t.Run("JSON订单按输入精度返回CSV", func(t *testing.T) {
+		resp, body := post(t, ts.URL+"/v1/auction?scale=input&format=csv", "application/json",
+			`[{"instrumentID":"IF2412","direction":0,"price":3973.40,"volume":3},
+			  {"instrumentID":"IF2412","direction":1,"price":3973.0,"volume":2}]`)
+		if resp.StatusCode != http.StatusOK || body != "IF2412,3973.40\n" {
+			t.Errorf("状态码 %d, 响应 %q", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("JSON订单的合约代码不能包含分隔符", func(t *testing.T) {
+		for _, id := range []string{"IF2412,1,1,1\nIF2412", "IF2412,0"} {
+			body, _ := json.Marshal([]jsonOrder{{InstrumentID: id, Direction: 0, Price: "3973.4", Volume: 3}})
+			resp, got := post(t, ts.URL+"/v1/auction", "application/json", string(body))
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("instrumentID %q: 状态码 %d, 响应 %s", id, resp.StatusCode, got)
+			}
+		}
+	})
+
+	t.Run("请求体超限", func(t *testing.T) {
+		resp, body := post(t, ts.URL+"/v1/auction", "text/csv", strings.Repeat("IF2412,0,3973.4,3\n", 100))
+		if resp.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Errorf("状态码 %d, 响应 %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("仅支持POST", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/v1/auction")
+		if err != nil || resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("GET /v1/auction: %v %v", err, resp)
+		}
+		resp.Body.Close()
+	})
+
+	t.Run("上传参考数据", func(t *testing.T) {
+		resp, body := post(t, ts.URL+"/v1/refdata", "text/csv", "# 测试品种\nZQ,0.5\n")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("状态码 %d: %s", resp.StatusCode, body)
+		}
+		_, body = post(t, ts.URL+"/v1/auction?format=csv", "text/csv", "ZQ2412,0,100.5,1\nZQ2412,1,100.5,1\n")
+		if body != "ZQ2412,100.5\n" {
+			t.Errorf("新品种撮合结果 %q", body)
+		}
+
+		resp, body = post(t, ts.URL+"/v1/refdata", "text/csv", "ZQ,abc\n")
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("格式错误的参考数据: 状态码 %d, 响应 %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("聚合委托簿与快照", func(t *testing.T) {
+		_, body := post(t, ts.URL+"/v1/books", "text/csv", "IF2412,0,3973.4,3\nIF2412,1,3973.0,2\n")
+		if !strings.Contains(body, `"price":"3973.4"`) {
+			t.Fatalf("追加订单后 %s", body)
+		}
+
+		resp, err := http.Get(ts.URL + "/v1/books/snapshot")
+		if err != nil {
+			t.Fatalf("下载快照失败: %v", err)
+		}
+		snap, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		// 撤掉卖单后无成交，再用快照恢复
+		_, body = post(t, ts.URL+"/v1/books", "text/csv", "IF2412,1,3973.0,-2\n")
+		if !strings.Contains(body, `"price":""`) {
+			t.Fatalf("撤单后 %s", body)
+		}
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/v1/books/snapshot", strings.NewReader(string(snap)))
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("恢复快照失败: %v %v", err, resp)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(data), `"price":"3973.4"`) {
+			t.Errorf("恢复快照后 %s", data)
+		}
+	})
+
+	t.Run("上传缓慢时不阻塞查询", func(t *testing.T) {
+		reader, writer := io.Pipe()
+		done := make(chan string)
+		go func() {
+			resp, err := http.Post(ts.URL+"/v1/books", "text/csv", reader)
+			if err != nil {
+				done <- err.Error()
+				return
+			}
+			data, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			done <- string(data)
+		}()
+		writer.Write([]byte("IF2501,0,3990.0,1\n"))
+
+		// 请求体尚未结束时查询和下载快照应立即返回
+		client := &http.Client{Timeout: 2 * time.Second}
+		for _, path := range []string{"/v1/books", "/v1/books/snapshot"} {
+			resp, err := client.Get(ts.URL + path)
+			if err != nil {
+				t.Fatalf("上传未结束时 GET %s: %v", path, err)
+			}
+			resp.Body.Close()
+		}
+
+		writer.Write([]byte("IF2501,1,3990.0,1\nIF2501,1,3990.0,-5\n"))
+		writer.Close()
+		body := <-done
+		if !strings.Contains(body, `"instrumentID":"IF2501","price":"3990.0"`) || !strings.Contains(body, "第3行(apply)") {
+			t.Errorf("上传结束后 %s", body)
+		}
+	})
+}
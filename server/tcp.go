@@ -0,0 +1,348 @@
+package server
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/journal"
+	"AuctionMatch/order"
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 行协议，字段以空格分隔；组合合约代码本身含一个空格（如SP IF2412&IF2503），在NEW、RESULT和FILL中占两个字段，
+// 以第二个字段含&识别：
+//
+//	客户端 -> 服务端
+//	  NEW <orderID> <instrumentID> <direction> <price> <volume> [account] 新增订单，account用于自成交防范
+//	  CXL <orderID>                                                       撤单
+//	  AMD <orderID> <price> <volume>                                      改单
+//	  SUB                                                                 订阅全部撮合结果和成交
+//	  AUTH <token>                                                        以TCPServer.AdminToken认证为管理连接
+//	  OPEN                                                                （管理）开放新的集合竞价时段
+//	  UNCROSS                                                             （管理）撮合所有合约并推送结果
+//	                                                                      未认证的连接发送管理命令时应答REJ - 无权限
+//	  PING / QUIT
+//	服务端 -> 客户端
+//	  ACK <orderID> / REJ <orderID|-> <原因>                              请求应答
+//	  RESULT <instrumentID> <price|-> <matchVolume> <preventedVolume>     撮合结果和被防范的自成交量（仅订阅者）
+//	  FILL <orderID> <instrumentID> <direction> <price> <volume>          成交（订阅者收到全部，其他连接只收到自己的）
+//	  END                                                                 一轮撮合推送结束
+//	  PONG
+
+const OUTBOX_SIZE = 4096 // 每个连接待发送消息的缓冲上限，积压超过时断开该连接
+
+type (
+	// TCPServer 长连接集合竞价仿真服务，所有连接共享同一个集合竞价时段
+	TCPServer struct {
+		AdminToken string // 管理命令的认证口令，须在Serve之前设置；为空时所有连接都不能执行管理命令
+		session    *order.CallSession
+		journal    *journal.Writer // 为nil时不记录
+		journalErr error           // 日志写入失败后拒绝所有后续事件
+		clients    map[*tcpClient]struct{}
+		epoch      string // 本进程的连接编号前缀，恢复日志后新连接不会与日志中的Owner重复
+		nextID     int
+		listener   net.Listener
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+	}
+
+	tcpClient struct {
+		id         string
+		conn       net.Conn
+		outbox     chan string
+		subscribed bool
+		admin      bool // 已通过AUTH认证
+	}
+)
+
+// ErrNotAdmin 未认证的连接发送管理命令
+var ErrNotAdmin = errors.New("无权限")
+
+func NewTCPServer(opts ...order.SessionOption) *TCPServer {
+	return NewJournaledTCPServer(order.NewCallSession(opts...), nil)
+}
+
+// NewJournaledTCPServer 以已有的集合竞价时段创建服务，通常是从预写日志恢复的时段。
+// 事件检查通过后先写入日志再在时段中生效，写入失败时事件不生效并应答REJ；w为nil时不记录
+func NewJournaledTCPServer(session *order.CallSession, w *journal.Writer) *TCPServer {
+	return &TCPServer{
+		session: session,
+		journal: w,
+		clients: make(map[*tcpClient]struct{}),
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+// Serve 在listener上接受连接，直至Close
+func (s *TCPServer) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.nextID++
+		client := &tcpClient{
+			id:     "c" + s.epoch + "-" + strconv.Itoa(s.nextID),
+			conn:   conn,
+			outbox: make(chan string, OUTBOX_SIZE),
+		}
+		s.clients[client] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(2)
+		go s.writeLoop(client)
+		go s.readLoop(client)
+	}
+}
+
+// Close 停止接受连接并断开所有客户端
+func (s *TCPServer) Close() error {
+	s.mu.Lock()
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for client := range s.clients {
+		s.dropLocked(client)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// dropLocked 断开客户端，调用方需持有s.mu
+func (s *TCPServer) dropLocked(client *tcpClient) {
+	if _, ok := s.clients[client]; !ok {
+		return
+	}
+	delete(s.clients, client)
+	close(client.outbox)
+	client.conn.Close()
+}
+
+// sendLocked 向客户端发送一行，积压过多时断开，调用方需持有s.mu
+func (s *TCPServer) sendLocked(client *tcpClient, line string) {
+	if _, ok := s.clients[client]; !ok {
+		return
+	}
+	select {
+	case client.outbox <- line:
+	default:
+		s.dropLocked(client)
+	}
+}
+
+func (s *TCPServer) writeLoop(client *tcpClient) {
+	defer s.wg.Done()
+	writer := bufio.NewWriter(client.conn)
+	for line := range client.outbox {
+		writer.WriteString(line)
+		writer.WriteByte('\n')
+		// 没有更多待发送消息时再刷新，减少系统调用
+		if len(client.outbox) == 0 {
+			if err := writer.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *TCPServer) readLoop(client *tcpClient) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		s.dropLocked(client)
+		s.mu.Unlock()
+	}()
+
+	scanner := bufio.NewScanner(client.conn)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.ToUpper(fields[0]) == "QUIT" {
+			return
+		}
+		s.mu.Lock()
+		s.handleLocked(client, fields)
+		s.mu.Unlock()
+	}
+}
+
+// journalLocked 事件已通过检查（err为nil）时先写入预写日志，写入成功后再应用到时段，调用方需持有s.mu
+func (s *TCPServer) journalLocked(err error, event journal.Event) error {
+	if err != nil {
+		return err
+	}
+	if err := s.appendLocked(event); err != nil {
+		return err
+	}
+	return journal.Apply(s.session, event)
+}
+
+// appendLocked 写入预写日志，失败后拒绝所有后续事件，调用方需持有s.mu
+func (s *TCPServer) appendLocked(event journal.Event) error {
+	if s.journal == nil {
+		return nil
+	}
+	if err := s.journal.Append(event); err != nil {
+		s.journalErr = fmt.Errorf("预写日志写入失败: %v", err)
+		return s.journalErr
+	}
+	return nil
+}
+
+// joinSpread 第i个字段与其后的字段组成组合合约代码时合并为一个字段
+func joinSpread(fields []string, i int) []string {
+	if len(fields) <= i+1 || !strings.Contains(fields[i+1], "&") {
+		return fields
+	}
+	instrumentID := fields[i] + " " + fields[i+1]
+	if _, ok := instrument.ParseCombination(instrumentID); !ok {
+		return fields
+	}
+	joined := make([]string, 0, len(fields)-1)
+	joined = append(joined, fields[:i]...)
+	joined = append(joined, instrumentID)
+	return append(joined, fields[i+2:]...)
+}
+
+// handleLocked 处理一条客户端消息，调用方需持有s.mu
+func (s *TCPServer) handleLocked(client *tcpClient, fields []string) {
+	command := strings.ToUpper(fields[0])
+	if s.journalErr != nil && command != "PING" && command != "SUB" {
+		s.sendLocked(client, "REJ - "+s.journalErr.Error())
+		return
+	}
+
+	switch command {
+	case "NEW":
+		fields = joinSpread(fields, 2)
+		if len(fields) != 6 && len(fields) != 7 {
+			s.sendLocked(client, "REJ - NEW需要5或6个参数")
+			return
+		}
+		o, err := order.ParseOrder(fields[2:6])
+		if len(fields) == 7 {
+			o.Account = fields[6]
+		}
+		if err == nil {
+			err = s.session.CheckSubmit(fields[1], o)
+		}
+		err = s.journalLocked(err, journal.Event{Type: journal.EventNew, OrderID: fields[1], Owner: client.id, Order: o})
+		s.replyLocked(client, fields[1], err)
+	case "CXL":
+		if len(fields) != 2 {
+			s.sendLocked(client, "REJ - CXL需要1个参数")
+			return
+		}
+		err := s.journalLocked(s.session.CheckCancel(fields[1]), journal.Event{Type: journal.EventCancel, OrderID: fields[1]})
+		s.replyLocked(client, fields[1], err)
+	case "AMD":
+		if len(fields) != 4 {
+			s.sendLocked(client, "REJ - AMD需要3个参数")
+			return
+		}
+		price, err := strconv.ParseFloat(fields[2], 32)
+		if err != nil {
+			s.replyLocked(client, fields[1], fmt.Errorf("无效的price值: %s", fields[2]))
+			return
+		}
+		volume, err := strconv.ParseInt(fields[3], 10, 32)
+		if err != nil {
+			s.replyLocked(client, fields[1], fmt.Errorf("无效的volume值: %s", fields[3]))
+			return
+		}
+		err = s.journalLocked(s.session.CheckAmend(fields[1], float32(price), int32(volume)), journal.Event{
+			Type:    journal.EventAmend,
+			OrderID: fields[1],
+			Order:   order.Order{Price: float32(price), Volume: int32(volume)},
+		})
+		s.replyLocked(client, fields[1], err)
+	case "SUB":
+		client.subscribed = true
+		s.sendLocked(client, "ACK -")
+	case "AUTH":
+		if len(fields) != 2 || s.AdminToken == "" ||
+			subtle.ConstantTimeCompare([]byte(fields[1]), []byte(s.AdminToken)) != 1 {
+			s.replyLocked(client, "-", ErrNotAdmin)
+			return
+		}
+		client.admin = true
+		s.sendLocked(client, "ACK -")
+	case "OPEN":
+		if !client.admin {
+			s.replyLocked(client, "-", ErrNotAdmin)
+			return
+		}
+		s.replyLocked(client, "-", s.journalLocked(nil, journal.Event{Type: journal.EventOpen}))
+	case "UNCROSS":
+		if !client.admin {
+			s.replyLocked(client, "-", ErrNotAdmin)
+			return
+		}
+		if !s.session.IsOpen() {
+			s.replyLocked(client, "-", order.ErrSessionClosed)
+			return
+		}
+		if err := s.appendLocked(journal.Event{Type: journal.EventUncross}); err != nil {
+			s.replyLocked(client, "-", err)
+			return
+		}
+		results := s.session.Uncross()
+		s.sendLocked(client, "ACK -")
+		s.publishLocked(results)
+	case "PING":
+		s.sendLocked(client, "PONG")
+	default:
+		s.sendLocked(client, "REJ - 未知命令: "+fields[0])
+	}
+}
+
+func (s *TCPServer) replyLocked(client *tcpClient, orderID string, err error) {
+	if err != nil {
+		s.sendLocked(client, "REJ "+orderID+" "+err.Error())
+		return
+	}
+	s.sendLocked(client, "ACK "+orderID)
+}
+
+// publishLocked 推送撮合结果：订阅者收到全部结果和成交，其他连接只收到自己订单的成交
+func (s *TCPServer) publishLocked(results []order.UncrossResult) {
+	for client := range s.clients {
+		for _, result := range results {
+			if client.subscribed {
+				price := result.FormatPrice()
+				if price == "" {
+					price = "-"
+				}
+				s.sendLocked(client, fmt.Sprintf("RESULT %s %s %d %d",
+					result.InstrumentID, price, result.MatchVolume, result.PreventedVolume))
+			}
+			for _, fill := range result.Fills {
+				if client.subscribed || fill.Owner == client.id {
+					s.sendLocked(client, fmt.Sprintf("FILL %s %s %d %s %d", fill.OrderID, fill.InstrumentID,
+						fill.Direction, strconv.FormatFloat(float64(fill.Price), 'f', int(result.Scale), 32), fill.Volume))
+				}
+			}
+		}
+		s.sendLocked(client, "END")
+	}
+}
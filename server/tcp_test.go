@@ -0,0 +1,272 @@
+package server
+
+import (
+	"AuctionMatch/journal"
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testConn struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *testConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	return &testConn{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// call 发送一行并读取n行回复
+func (c *testConn) call(line string, n int) []string {
+	c.t.Helper()
+	fmt.Fprintln(c.conn, line)
+	return c.read(n)
+}
+
+func (c *testConn) read(n int) []string {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	lines := make([]string, n)
+	for i := range lines {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("读取回复失败: %v", err)
+		}
+		lines[i] = line[:len(line)-1]
+	}
+	return lines
+}
+
+// TEST_ADMIN_TOKEN 测试服务的管理口令
+const TEST_ADMIN_TOKEN = "secret"
+
+func TestTCPServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	srv := NewTCPServer()
+	srv.AdminToken = TEST_ADMIN_TOKEN
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	trader := dial(t, listener.Addr().String())
+	monitor := dial(t, listener.Addr().String())
+	admin := dial(t, listener.Addr().String())
+
+	monitor.call("SUB", 1)
+	if got := admin.call("AUTH "+TEST_ADMIN_TOKEN, 1)[0]; got != "ACK -" {
+		t.Fatalf("AUTH => %q", got)
+	}
+	steps := []struct {
+		line string
+		want string
+	}{
+		{"NEW b1 IF2412 0 3973.4 3", "ACK b1"},
+		{"NEW b1 IF2412 0 3973.4 3", "REJ b1 订单编号重复"},
+		{"NEW s1 IF2412 1 3973.0 5", "ACK s1"},
+		{"AMD s1 3973.2 2", "ACK s1"},
+		{"NEW b2 IF2412 0 3972.0 1 A001", "ACK b2"},
+		{"NEW b4 IF2412 0 3972.0 1 A001 x", "REJ - NEW需要5或6个参数"},
+		{"CXL b2", "ACK b2"},
+		{"CXL b2", "REJ b2 订单不存在"},
+		{"PING", "PONG"},
+	}
+	for _, step := range steps {
+		if got := trader.call(step.line, 1)[0]; got != step.want {
+			t.Errorf("%s => %q, want %q", step.line, got, step.want)
+		}
+	}
+
+	if got := admin.call("UNCROSS", 2); !reflect.DeepEqual(got, []string{"ACK -", "END"}) {
+		t.Errorf("管理连接收到 %q", got)
+	}
+	if got := trader.read(3); !reflect.DeepEqual(got, []string{
+		"FILL b1 IF2412 0 3973.4 2",
+		"FILL s1 IF2412 1 3973.4 2",
+		"END",
+	}) {
+		t.Errorf("下单连接收到 %q", got)
+	}
+	if got := monitor.read(4); !reflect.DeepEqual(got, []string{
+		"RESULT IF2412 3973.4 2 0",
+		"FILL b1 IF2412 0 3973.4 2",
+		"FILL s1 IF2412 1 3973.4 2",
+		"END",
+	}) {
+		t.Errorf("订阅连接收到 %q", got)
+	}
+
+	if got := trader.call("NEW b3 IF2412 0 3973.4 1", 1)[0]; got != "REJ b3 集合竞价时段未开放" {
+		t.Errorf("时段关闭后下单 => %q", got)
+	}
+	admin.call("OPEN", 1)
+	if got := trader.call("NEW b3 IF2412 0 3973.4 1", 1)[0]; got != "ACK b3" {
+		t.Errorf("重新开放后下单 => %q", got)
+	}
+}
+
+// TestTCPServerSpread 组合合约代码中的空格不影响NEW的字段数
+func TestTCPServerSpread(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	srv := NewTCPServer()
+	srv.AdminToken = TEST_ADMIN_TOKEN
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	trader := dial(t, listener.Addr().String())
+	admin := dial(t, listener.Addr().String())
+	admin.call("AUTH "+TEST_ADMIN_TOKEN, 1)
+	admin.call("SUB", 1)
+	steps := []struct {
+		line string
+		want string
+	}{
+		{"NEW b1 SP IF2412&IF2503 0 -5.0 2", "ACK b1"},
+		{"NEW s1 SP IF2412&IF2503 1 -5.4 3 A001", "ACK s1"},
+		{"NEW b2 SP IF2412&IF2503 0 -5.0 2 A001 x", "REJ - NEW需要5或6个参数"},
+		{"NEW b3 sp IF2412&IF2503 0 -5.0 2", "REJ b3 无效的direction值: IF2412&IF2503"},
+	}
+	for _, step := range steps {
+		if got := trader.call(step.line, 1)[0]; got != step.want {
+			t.Errorf("%s => %q, want %q", step.line, got, step.want)
+		}
+	}
+
+	if got := admin.call("UNCROSS", 5); !reflect.DeepEqual(got, []string{
+		"ACK -",
+		"RESULT SP IF2412&IF2503 -5.0 2 0",
+		"FILL b1 SP IF2412&IF2503 0 -5.0 2",
+		"FILL s1 SP IF2412&IF2503 1 -5.0 2",
+		"END",
+	}) {
+		t.Errorf("管理连接收到 %q", got)
+	}
+}
+
+// startJournaled 从日志恢复时段并在随机端口启动带日志的服务
+func startJournaled(t *testing.T, path string) (*TCPServer, *journal.Writer, string) {
+	t.Helper()
+	recovery, err := journal.Recover(path)
+	if err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	session, err := journal.Rebuild(recovery.Events)
+	if err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	w, err := journal.OpenWriter(path, journal.SyncAlways, 0)
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	srv := NewJournaledTCPServer(session, w)
+	srv.AdminToken = TEST_ADMIN_TOKEN
+	go srv.Serve(listener)
+	return srv, w, listener.Addr().String()
+}
+
+// TestTCPServerRecoveredOwners 重启恢复后的第一个连接不应收到上一进程同序号连接的成交
+func TestTCPServerRecoveredOwners(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auction.wal")
+	srv, w, addr := startJournaled(t, path)
+	before := dial(t, addr)
+	if got := before.call("NEW b1 IF2412 0 3973.4 3", 1)[0]; got != "ACK b1" {
+		t.Fatalf("重启前下单 => %q", got)
+	}
+	srv.Close()
+	w.Close()
+
+	srv, w, addr = startJournaled(t, path)
+	defer w.Close()
+	defer srv.Close()
+	after := dial(t, addr)
+	if got := after.call("NEW s1 IF2412 1 3973.0 3", 1)[0]; got != "ACK s1" {
+		t.Fatalf("重启后下单 => %q", got)
+	}
+	after.call("AUTH "+TEST_ADMIN_TOKEN, 1)
+	if got := after.call("UNCROSS", 3); !reflect.DeepEqual(got, []string{"ACK -", "FILL s1 IF2412 1 3973.4 3", "END"}) {
+		t.Errorf("重启后的连接收到 %q", got)
+	}
+	if got := after.call("PING", 1)[0]; got != "PONG" {
+		t.Errorf("重启后的连接收到了其他连接的成交: %q", got)
+	}
+}
+
+// TestTCPServerAdmin 未认证或口令错误的连接不能开放时段或撮合
+func TestTCPServerAdmin(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	srv := NewTCPServer()
+	srv.AdminToken = TEST_ADMIN_TOKEN
+	go srv.Serve(listener)
+	defer srv.Close()
+
+	client := dial(t, listener.Addr().String())
+	client.call("NEW b1 IF2412 0 3973.4 3", 1)
+	client.call("NEW s1 IF2412 1 3973.0 3", 1)
+	for _, line := range []string{"UNCROSS", "OPEN", "AUTH wrong", "UNCROSS", "AUTH"} {
+		if got := client.call(line, 1)[0]; got != "REJ - 无权限" {
+			t.Errorf("普通连接 %s => %q, want REJ - 无权限", line, got)
+		}
+	}
+	srv.mu.Lock()
+	open := srv.session.IsOpen()
+	srv.mu.Unlock()
+	if !open {
+		t.Errorf("普通连接的UNCROSS不应关闭时段")
+	}
+
+	// 未设置口令时任何连接都不能认证
+	noAdmin := NewTCPServer()
+	noAdminListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	go noAdmin.Serve(noAdminListener)
+	defer noAdmin.Close()
+	if got := dial(t, noAdminListener.Addr().String()).call("AUTH ", 1)[0]; got != "REJ - 无权限" {
+		t.Errorf("未设置口令时 AUTH => %q", got)
+	}
+}
+
+// TestTCPServerJournalFailure 日志写入失败时事件不生效
+func TestTCPServerJournalFailure(t *testing.T) {
+	srv, w, addr := startJournaled(t, filepath.Join(t.TempDir(), "auction.wal"))
+	defer srv.Close()
+	trader := dial(t, addr)
+	if got := trader.call("NEW b1 IF2412 0 3973.4 3", 1)[0]; got != "ACK b1" {
+		t.Fatalf("下单 => %q", got)
+	}
+	w.Close()
+
+	for _, line := range []string{"NEW s1 IF2412 1 3973.0 3", "CXL b1", "UNCROSS"} {
+		if got := trader.call(line, 1)[0]; len(got) < 4 || got[:4] != "REJ " {
+			t.Errorf("日志写入失败后 %s => %q, want REJ", line, got)
+		}
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	results := srv.session.Results()
+	if !srv.session.IsOpen() || len(results) != 1 || results[0].Price != 0 || srv.session.CheckCancel("b1") != nil {
+		t.Errorf("日志写入失败的事件不应生效: open=%v, results=%+v", srv.session.IsOpen(), results)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"AuctionMatch/order"
+	"AuctionMatch/snapshot"
+	"os"
+)
+
+// runSnapshot 从快照恢复聚合委托簿并输出各合约的集合竞价价格
+func runSnapshot(argv []string) {
+	args := mustParseArgs(argv, argSpec{options: []string{"-o"}})
+	writeResults(readSnapshot(args.inputFile).Results(), args.outputFile)
+}
+
+// readSnapshot 读取快照，失败时退出
+func readSnapshot(path string, opts ...order.ProcessorOption) *order.IncrementalAuction {
+	file, err := os.Open(path)
+	if err != nil {
+		fatal("无法打开快照", err)
+	}
+	defer file.Close()
+
+	auction, err := snapshot.Read(file, opts...)
+	if err != nil {
+		fatal("读取快照失败", err)
+	}
+	return auction
+}
+
+// writeSnapshot 先写临时文件再改名，避免中途失败留下不完整的快照
+func writeSnapshot(path string, auction *order.IncrementalAuction) {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err == nil {
+		err = snapshot.Write(file, auction)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		fatal("写入快照失败", err)
+	}
+}
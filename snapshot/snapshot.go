@@ -0,0 +1,267 @@
+package snapshot
+
+import (
+	"AuctionMatch/instrument"
+	"AuctionMatch/order"
+	"AuctionMatch/refdata"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+)
+
+// 快照格式（小端）：
+//
+//	magic "AMSNAP" | version uint16 | payload长度uint32 | payload | payload的CRC32-C uint32
+//
+// version 2的payload：合约数uvarint，随后按首次出现顺序依次为每个合约：
+//
+//	instrumentID（uvarint长度+字节）| tick float32位 | 更高价格段数uvarint，（起点float32位，tick float32位）按起点升序 |
+//	scale uvarint | 订单数uvarint | 买档位数uvarint，（价格varint，量varint）按价格升序 | 卖档位同买档位
+//
+// 档位价格为写出时价格分段下的档位序号，恢复时按快照中的分段换算，不受参考数据之后变化的影响。
+// version 1没有更高价格段，仍可读取：tick与合约当前参考数据的最低价格段一致时沿用当前分段，否则按单一tick换算
+const (
+	MAGIC           = "AMSNAP"
+	VERSION         = 2
+	VERSION_1       = 1
+	MAX_PAYLOAD     = 1 << 30
+	MAX_INSTRUMENTS = 1 << 20
+	MAX_BANDS       = 1 << 10
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Write 将增量计算器中各合约的聚合委托簿写为快照
+func Write(w io.Writer, auction *order.IncrementalAuction) error {
+	instruments := auction.Instruments()
+	payload := binary.AppendUvarint(nil, uint64(len(instruments)))
+	for _, instrumentID := range instruments {
+		book := auction.Book(instrumentID)
+		payload = appendString(payload, instrumentID)
+		payload = appendTicks(payload, book.Ticks)
+		payload = binary.AppendUvarint(payload, uint64(book.Scale))
+		payload = binary.AppendUvarint(payload, uint64(book.OrderCount))
+		payload = appendLevels(payload, book.Levels(0))
+		payload = appendLevels(payload, book.Levels(1))
+	}
+
+	buf := bufio.NewWriter(w)
+	buf.WriteString(MAGIC)
+	binary.Write(buf, binary.LittleEndian, uint16(VERSION))
+	binary.Write(buf, binary.LittleEndian, uint32(len(payload)))
+	buf.Write(payload)
+	binary.Write(buf, binary.LittleEndian, crc32.Checksum(payload, crcTable))
+	return buf.Flush()
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendTicks 写入最低价格段的tick和更高的价格段
+func appendTicks(b []byte, ticks instrument.TickSchedule) []byte {
+	tick, bands := ticks.Bands()
+	b = binary.LittleEndian.AppendUint32(b, math.Float32bits(tick))
+	b = binary.AppendUvarint(b, uint64(len(bands)))
+	for _, band := range bands {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(band.From))
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(band.Tick))
+	}
+	return b
+}
+
+// appendLevels 按价格升序写入档位，保证同样的状态得到同样的字节
+func appendLevels(b []byte, levels map[int64]int32) []byte {
+	prices := make([]int64, 0, len(levels))
+	for priceInt := range levels {
+		prices = append(prices, priceInt)
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+
+	b = binary.AppendUvarint(b, uint64(len(prices)))
+	for _, priceInt := range prices {
+		b = binary.AppendVarint(b, priceInt)
+		b = binary.AppendVarint(b, int64(levels[priceInt]))
+	}
+	return b
+}
+
+// Read 读取快照并恢复增量计算器，opts与order.NewIncrementalAuction相同，用于恢复后继续回放
+func Read(r io.Reader, opts ...order.ProcessorOption) (*order.IncrementalAuction, error) {
+	header := make([]byte, len(MAGIC)+6)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("快照头不完整: %v", err)
+	}
+	if string(header[:len(MAGIC)]) != MAGIC {
+		return nil, errors.New("不是快照文件")
+	}
+	version := binary.LittleEndian.Uint16(header[len(MAGIC):])
+	if version != VERSION && version != VERSION_1 {
+		return nil, fmt.Errorf("不支持的快照版本: %d", version)
+	}
+	length := binary.LittleEndian.Uint32(header[len(MAGIC)+2:])
+	if length > MAX_PAYLOAD {
+		return nil, fmt.Errorf("快照长度无效: %d", length)
+	}
+
+	payload := make([]byte, length+4)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("快照内容不完整: %v", err)
+	}
+	payload, sum := payload[:length], binary.LittleEndian.Uint32(payload[length:])
+	if crc32.Checksum(payload, crcTable) != sum {
+		return nil, errors.New("快照校验和不匹配")
+	}
+
+	auction := order.NewIncrementalAuction(opts...)
+	if err := decodeBooks(bytes.NewReader(payload), version, auction); err != nil {
+		return nil, fmt.Errorf("快照内容损坏: %v", err)
+	}
+	return auction, nil
+}
+
+func decodeBooks(r *bytes.Reader, version uint16, auction *order.IncrementalAuction) error {
+	count, err := readCount(r, MAX_INSTRUMENTS)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		instrumentID, err := readString(r)
+		if err != nil {
+			return err
+		}
+		ticks, err := readTicks(r, version, instrumentID)
+		if err != nil {
+			return err
+		}
+		scale, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		orderCount, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		buyLevels, err := readLevels(r)
+		if err != nil {
+			return err
+		}
+		sellLevels, err := readLevels(r)
+		if err != nil {
+			return err
+		}
+		auction.Restore(order.RestoreAuctionBook(instrumentID, ticks, uint(scale), int(orderCount), buyLevels, sellLevels))
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("多余的%d字节", r.Len())
+	}
+	return nil
+}
+
+// readTicks 读取价格分段，校验规则与参考数据相同：tick为正，各段起点递增且与上一段起点的距离为上一段tick的整数倍
+func readTicks(r *bytes.Reader, version uint16, instrumentID string) (instrument.TickSchedule, error) {
+	tick, err := readFloat32(r)
+	if err != nil {
+		return instrument.TickSchedule{}, err
+	}
+	if tickUnits(tick) <= 0 {
+		return instrument.TickSchedule{}, fmt.Errorf("%s: 无效的tick %v", instrumentID, tick)
+	}
+	if version == VERSION_1 {
+		if current := (&order.Order{InstrumentID: instrumentID}).TickSchedule(); current.Tick(0) == tick {
+			return current, nil
+		}
+		return instrument.NewTickSchedule(tick, nil), nil
+	}
+
+	n, err := readCount(r, MAX_BANDS)
+	if err != nil {
+		return instrument.TickSchedule{}, err
+	}
+	bands := make([]refdata.TickBand, n)
+	prevFrom, prevTick := int64(0), tickUnits(tick)
+	for i := range bands {
+		if bands[i].From, err = readFloat32(r); err != nil {
+			return instrument.TickSchedule{}, err
+		}
+		if bands[i].Tick, err = readFloat32(r); err != nil {
+			return instrument.TickSchedule{}, err
+		}
+		from, bandTick := tickUnits(bands[i].From), tickUnits(bands[i].Tick)
+		if bandTick <= 0 || from <= prevFrom || (from-prevFrom)%prevTick != 0 {
+			return instrument.TickSchedule{}, fmt.Errorf("%s: 无效的价格段 %v:%v", instrumentID, bands[i].From, bands[i].Tick)
+		}
+		prevFrom, prevTick = from, bandTick
+	}
+	return instrument.NewTickSchedule(tick, bands), nil
+}
+
+func readFloat32(r *bytes.Reader) (float32, error) {
+	var bits uint32
+	if err := binary.Read(r, binary.LittleEndian, &bits); err != nil {
+		return 0, err
+	}
+	value := math.Float32frombits(bits)
+	if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+		return 0, fmt.Errorf("无效的价格: %v", value)
+	}
+	return value, nil
+}
+
+func tickUnits(price float32) int64 {
+	return int64(math.Round(float64(price) * instrument.PRICE_UNIT))
+}
+
+// readCount 读取数量并检查不超过剩余字节数，防止损坏的数据导致超大分配
+func readCount(r *bytes.Reader, limit int) (int, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(limit) || n > uint64(r.Len()) {
+		return 0, fmt.Errorf("数量越界: %d", n)
+	}
+	return int(n), nil
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := readCount(r, r.Len())
+	if err != nil {
+		return "", err
+	}
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+func readLevels(r *bytes.Reader) (map[int64]int32, error) {
+	n, err := readCount(r, r.Len())
+	if err != nil {
+		return nil, err
+	}
+	levels := make(map[int64]int32, n)
+	for i := 0; i < n; i++ {
+		priceInt, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		volume, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if volume <= 0 || volume > math.MaxInt32 {
+			return nil, fmt.Errorf("档位量无效: %d", volume)
+		}
+		levels[priceInt] = int32(volume)
+	}
+	return levels, nil
+}
@@ -0,0 +1,173 @@
+package snapshot
+
+import (
+	"AuctionMatch/order"
+	"AuctionMatch/refdata"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func randomOrders(rng *rand.Rand, n int) []order.Order {
+	instruments := []string{"IF2412", "TS2412", "IC2501", "T2503"}
+	orders := make([]order.Order, n)
+	for i := range orders {
+		instrumentID := instruments[rng.Intn(len(instruments))]
+		tick := (&order.Order{InstrumentID: instrumentID}).GetTick()
+		orders[i] = order.Order{
+			InstrumentID: instrumentID,
+			Direction:    int8(rng.Intn(2)),
+			Price:        order.ToFloat(int64(20000+rng.Intn(30)), tick),
+			Volume:       int32(1 + rng.Intn(10)),
+		}
+	}
+	return orders
+}
+
+func assertSameBooks(t *testing.T, got, want *order.IncrementalAuction) {
+	t.Helper()
+	if !reflect.DeepEqual(got.Instruments(), want.Instruments()) {
+		t.Fatalf("合约顺序 %v, want %v", got.Instruments(), want.Instruments())
+	}
+	if !reflect.DeepEqual(got.Results(), want.Results()) {
+		t.Errorf("Results() = %+v, want %+v", got.Results(), want.Results())
+	}
+	for _, instrumentID := range want.Instruments() {
+		g, w := got.Book(instrumentID), want.Book(instrumentID)
+		if g.Indicative() != w.Indicative() || g.OrderCount != w.OrderCount || g.Scale != w.Scale ||
+			!reflect.DeepEqual(g.Levels(0), w.Levels(0)) || !reflect.DeepEqual(g.Levels(1), w.Levels(1)) {
+			t.Errorf("%s: 恢复后的委托簿与原委托簿不一致", instrumentID)
+		}
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	orders := randomOrders(rng, 2000)
+	original := order.NewIncrementalAuction(order.WithScaleMode(order.ScaleByInput))
+	for _, o := range orders[:1000] {
+		original.Add(o, "1.0")
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, original); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data := buf.Bytes()
+	restored, err := Read(bytes.NewReader(data), order.WithScaleMode(order.ScaleByInput))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	assertSameBooks(t, restored, original)
+
+	// 恢复后的委托簿与原委托簿继续接受相同的订单，结果保持一致，且与全量计算一致
+	for _, o := range orders[1000:] {
+		original.Add(o, "1.00")
+		restored.Add(o, "1.00")
+	}
+	assertSameBooks(t, restored, original)
+	byInstrument := make(map[string][]order.Order)
+	for _, o := range orders {
+		byInstrument[o.InstrumentID] = append(byInstrument[o.InstrumentID], o)
+	}
+	for _, result := range restored.Results() {
+		if want := order.CalculateAuctionPrice(byInstrument[result.InstrumentID]); result.Price != want {
+			t.Errorf("%s: 恢复后价格 %v, 全量计算 %v", result.InstrumentID, result.Price, want)
+		}
+	}
+
+	// 相同状态写出相同的字节
+	var again bytes.Buffer
+	Write(&again, mustRead(t, data))
+	if !bytes.Equal(again.Bytes(), data) {
+		t.Error("同一状态的快照字节不一致")
+	}
+}
+
+// TestSnapshotTickBands 按价格分段的合约以快照中的分段恢复，不受参考数据之后变化的影响
+func TestSnapshotTickBands(t *testing.T) {
+	refdata.Default.Put(refdata.Product{Code: "ZS", Tick: 0.2, TickBands: []refdata.TickBand{{From: 10, Tick: 1}}})
+	auction := order.NewIncrementalAuction()
+	for _, o := range []order.Order{
+		{InstrumentID: "ZS2412", Direction: 0, Price: 12, Volume: 3},
+		{InstrumentID: "ZS2412", Direction: 0, Price: 9.8, Volume: 2},
+		{InstrumentID: "ZS2412", Direction: 1, Price: 11, Volume: 2},
+		{InstrumentID: "ZS2412", Direction: 1, Price: 9.6, Volume: 4},
+	} {
+		auction.Add(o, "")
+	}
+	want := auction.Results()
+	var buf bytes.Buffer
+	if err := Write(&buf, auction); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// 最低价格段的tick不变，更高价格段改变
+	refdata.Default.Put(refdata.Product{Code: "ZS", Tick: 0.2, TickBands: []refdata.TickBand{{From: 20, Tick: 0.5}}})
+	restored := mustRead(t, buf.Bytes())
+	if got := restored.Results(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Results() = %+v, want %+v", got, want)
+	}
+	if got := restored.Book("ZS2412").Ticks.ToFloat(restored.Book("ZS2412").Ticks.ToInt(12)); got != 12 {
+		t.Errorf("恢复后的价格分段换算12为 %v", got)
+	}
+}
+
+// TestReadVersion1 仍可读取没有更高价格段的version 1快照
+func TestReadVersion1(t *testing.T) {
+	auction := order.NewIncrementalAuction()
+	auction.Add(order.Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3}, "3973.4")
+	auction.Add(order.Order{InstrumentID: "IF2412", Direction: 1, Price: 3973.0, Volume: 2}, "3973.0")
+	var buf bytes.Buffer
+	Write(&buf, auction)
+
+	// 去掉tick之后的价格段数（0），改写版本、长度和校验和
+	header := len(MAGIC) + 6
+	payload := buf.Bytes()[header : buf.Len()-4]
+	tickEnd := 1 + 1 + len("IF2412") + 4
+	payload = append(append([]byte(nil), payload[:tickEnd]...), payload[tickEnd+1:]...)
+	data := append([]byte(MAGIC), 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint16(data[len(MAGIC):], VERSION_1)
+	binary.LittleEndian.PutUint32(data[len(MAGIC)+2:], uint32(len(payload)))
+	data = append(data, payload...)
+	data = binary.LittleEndian.AppendUint32(data, crc32.Checksum(payload, crcTable))
+
+	assertSameBooks(t, mustRead(t, data), auction)
+}
+
+func mustRead(t *testing.T, data []byte) *order.IncrementalAuction {
+	t.Helper()
+	auction, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	return auction
+}
+
+func TestReadRejectsCorruption(t *testing.T) {
+	auction := order.NewIncrementalAuction()
+	auction.Add(order.Order{InstrumentID: "IF2412", Direction: 0, Price: 3973.4, Volume: 3}, "3973.4")
+	var buf bytes.Buffer
+	Write(&buf, auction)
+	data := buf.Bytes()
+
+	tests := []struct {
+		name    string
+		corrupt func([]byte) []byte
+	}{
+		{"magic错误", func(b []byte) []byte { b[0] = 'X'; return b }},
+		{"版本不支持", func(b []byte) []byte { b[len(MAGIC)] = 9; return b }},
+		{"内容被篡改", func(b []byte) []byte { b[len(b)-6] ^= 0xff; return b }},
+		{"被截断", func(b []byte) []byte { return b[:len(b)-3] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(bytes.NewReader(tt.corrupt(append([]byte(nil), data...)))); err == nil {
+				t.Error("Read() 应返回错误")
+			}
+		})
+	}
+}
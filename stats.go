@@ -0,0 +1,42 @@
+package main
+
+import (
+	"AuctionMatch/order"
+	"fmt"
+	"io"
+	"time"
+)
+
+// STATS_INSTRUMENTS -stats逐合约统计的合约数上限，其余合约合并为一行other
+const STATS_INSTRUMENTS = 20
+
+// printStats 输出本次运行的统计摘要，数据来自order包的运行指标
+func printStats(w io.Writer, elapsed time.Duration) {
+	instruments, calcSeconds := order.CalcDuration.Count()
+
+	fmt.Fprintf(w, "运行统计: 耗时 %v\n", roundDuration(elapsed.Seconds()))
+	fmt.Fprintf(w, "  读取行数: %.0f\n", order.LinesRead.Value())
+	fmt.Fprintf(w, "  有效订单: %.0f（%d个合约）\n", order.OrdersAccepted.Value(), instruments)
+	order.OrdersRejected.Each(func(labelValues []string, value float64) {
+		fmt.Fprintf(w, "  拒绝[%s]: %.0f\n", labelValues[0], value)
+	})
+	for _, stage := range []string{order.STAGE_INGEST, order.STAGE_PARSE, order.STAGE_AGGREGATE, order.STAGE_CALCULATE} {
+		if count, sum := order.StageDuration.Count(stage); count > 0 {
+			fmt.Fprintf(w, "  阶段[%s]: %v（%d次）\n", stage, roundDuration(sum), count)
+		}
+	}
+
+	_, levels := order.LadderSize.Count()
+	fmt.Fprintf(w, "  分价表档位: %.0f\n", levels)
+	fmt.Fprintf(w, "  合约计算耗时: %v\n", roundDuration(calcSeconds))
+
+	order.InstrumentCalcDuration.Each(func(labelValues []string, count uint64, sum float64) {
+		_, levels := order.InstrumentLadderSize.Count(labelValues...)
+		fmt.Fprintf(w, "  合约[%s]: 订单 %.0f，档位 %.0f，耗时 %v\n", labelValues[0],
+			order.InstrumentOrders.Value(labelValues...), levels, roundDuration(sum))
+	})
+}
+
+func roundDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Microsecond)
+}